@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setAnnotation sets an annotation on a copy of the annotations, the object metadata is shared with the converted object
+func setAnnotation(obj metav1.Object, key string, value string) {
+	annotations := make(map[string]string, len(obj.GetAnnotations())+1)
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+}
+
+// popAnnotation removes an annotation from a copy of the annotations and returns its value
+func popAnnotation(obj metav1.Object, key string) (string, bool) {
+	value, ok := obj.GetAnnotations()[key]
+	if !ok {
+		return "", false
+	}
+	annotations := make(map[string]string, len(obj.GetAnnotations()))
+	for k, v := range obj.GetAnnotations() {
+		if k != key {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	return value, true
+}
+
+func convertInstanceRefTo(src GrafanaInstanceRef) v1beta1.GrafanaInstanceRef {
+	return v1beta1.GrafanaInstanceRef{Name: src.Name, Namespace: src.Namespace}
+}
+
+func convertInstanceRefFrom(src v1beta1.GrafanaInstanceRef) GrafanaInstanceRef {
+	return GrafanaInstanceRef{Name: src.Name, Namespace: src.Namespace}
+}
+
+func convertOrganizationRefTo(src *GrafanaOrganizationRef) *v1beta1.GrafanaOrganizationRef {
+	if src == nil {
+		return nil
+	}
+	return &v1beta1.GrafanaOrganizationRef{Name: src.Name, Namespace: src.Namespace}
+}
+
+func convertOrganizationRefFrom(src *v1beta1.GrafanaOrganizationRef) *GrafanaOrganizationRef {
+	if src == nil {
+		return nil
+	}
+	return &GrafanaOrganizationRef{Name: src.Name, Namespace: src.Namespace}
+}
+
+func convertTeamRefTo(src *GrafanaTeamRef) *v1beta1.GrafanaTeamRef {
+	if src == nil {
+		return nil
+	}
+	return &v1beta1.GrafanaTeamRef{Name: src.Name, Namespace: src.Namespace}
+}
+
+func convertTeamRefFrom(src *v1beta1.GrafanaTeamRef) *GrafanaTeamRef {
+	if src == nil {
+		return nil
+	}
+	return &GrafanaTeamRef{Name: src.Name, Namespace: src.Namespace}
+}
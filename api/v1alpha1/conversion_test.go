@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+const fuzzIterations = 500
+
+// fuzzConversion converts fuzzed objects back and forth between the hub and the spoke version,
+// and checks that no field is lost on the way
+func fuzzConversion(hub conversion.Hub, spoke conversion.Convertible, funcs ...interface{}) {
+	fuzzer := fuzz.New().NilChance(0.3).NumElements(0, 3).Funcs(append(funcs, fuzzTypeMeta)...)
+
+	for i := 0; i < fuzzIterations; i++ {
+		spokeBefore := spoke.DeepCopyObject().(conversion.Convertible)
+		fuzzer.Fuzz(spokeBefore)
+		hubConverted := hub.DeepCopyObject().(conversion.Hub)
+		Expect(spokeBefore.ConvertTo(hubConverted)).To(Succeed(), "failed to convert the spoke to the hub")
+		spokeAfter := spoke.DeepCopyObject().(conversion.Convertible)
+		Expect(spokeAfter.ConvertFrom(hubConverted)).To(Succeed(), "failed to convert the hub to the spoke")
+		Expect(apiequality.Semantic.DeepEqual(spokeBefore, spokeAfter)).To(BeTrue(),
+			"spoke-hub-spoke round trip changed the object:\n%s", diff.ObjectGoPrintSideBySide(spokeBefore, spokeAfter))
+	}
+
+	for i := 0; i < fuzzIterations; i++ {
+		hubBefore := hub.DeepCopyObject().(conversion.Hub)
+		fuzzer.Fuzz(hubBefore)
+		spokeConverted := spoke.DeepCopyObject().(conversion.Convertible)
+		Expect(spokeConverted.ConvertFrom(hubBefore)).To(Succeed(), "failed to convert the hub to the spoke")
+		hubAfter := hub.DeepCopyObject().(conversion.Hub)
+		Expect(spokeConverted.ConvertTo(hubAfter)).To(Succeed(), "failed to convert the spoke to the hub")
+		Expect(apiequality.Semantic.DeepEqual(hubBefore, hubAfter)).To(BeTrue(),
+			"hub-spoke-hub round trip changed the object:\n%s", diff.ObjectGoPrintSideBySide(hubBefore, hubAfter))
+	}
+}
+
+// fuzzTypeMeta leaves the type meta alone, it is set by the conversion webhook
+func fuzzTypeMeta(in *metav1.TypeMeta, c fuzz.Continue) {
+	in.APIVersion = ""
+	in.Kind = ""
+}
+
+// fuzzDashboardModel returns a JSON object with random content
+func fuzzDashboardModel(c fuzz.Continue) []byte {
+	model := map[string]string{}
+	c.Fuzz(&model)
+	raw, _ := json.Marshal(model)
+	return raw
+}
+
+var _ = Describe("Conversion", func() {
+	It("keeps the fields of a GrafanaInstance", func() {
+		fuzzConversion(&v1beta1.GrafanaInstance{}, &GrafanaInstance{},
+			func(in *GrafanaUIStatus, c fuzz.Continue) {
+				c.FuzzNoCustom(in)
+				// The operator reports the replicas as "available/desired"
+				in.AvailableReplicas = ""
+				if c.RandBool() {
+					in.AvailableReplicas = fmt.Sprintf("%d/%d", c.Int31n(5), c.Int31n(5)+1)
+				}
+			},
+		)
+	})
+
+	It("keeps the fields of a GrafanaDashboard", func() {
+		fuzzConversion(&v1beta1.GrafanaDashboard{}, &GrafanaDashboard{},
+			func(in *GrafanaDashboardSpec, c fuzz.Continue) {
+				c.FuzzNoCustom(in)
+				// The model is a JSON document encoded in a string
+				in.Json = apiextensionsv1.JSON{}
+				if c.RandBool() {
+					in.Json.Raw, _ = json.Marshal(string(fuzzDashboardModel(c)))
+				}
+			},
+			func(in *v1beta1.GrafanaDashboardSource, c fuzz.Continue) {
+				c.FuzzNoCustom(in)
+				in.JSON = nil
+				if c.RandBool() {
+					in.JSON = &apiextensionsv1.JSON{Raw: fuzzDashboardModel(c)}
+				}
+			},
+		)
+	})
+
+	It("keeps the fields of a GrafanaOrganization", func() {
+		fuzzConversion(&v1beta1.GrafanaOrganization{}, &GrafanaOrganization{})
+	})
+
+	It("keeps the fields of a GrafanaTeam", func() {
+		fuzzConversion(&v1beta1.GrafanaTeam{}, &GrafanaTeam{})
+	})
+
+	It("keeps the fields of a GrafanaUser", func() {
+		fuzzConversion(&v1beta1.GrafanaUser{}, &GrafanaUser{})
+	})
+})
+
+var _ = Describe("dashboardModelTo", func() {
+	It("keeps a model given as a JSON object as is", func() {
+		model, err := dashboardModelTo(apiextensionsv1.JSON{Raw: []byte(`{"title":"Object"}`)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(model.Raw)).To(Equal(`{"title":"Object"}`))
+	})
+
+	It("refuses a string which is not a JSON document", func() {
+		_, err := dashboardModelTo(apiextensionsv1.JSON{Raw: []byte(`"{\"title\":"`)})
+		Expect(err).To(HaveOccurred())
+	})
+})
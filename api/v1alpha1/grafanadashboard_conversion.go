@@ -0,0 +1,236 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// configMapSourceAnnotation keeps the ConfigMap source of a v1beta1 dashboard, v1alpha1 has no counterpart
+const configMapSourceAnnotation = "grafana.minicali.com/v1beta1-source-configmap-ref"
+
+// uidAnnotation keeps the UID set on the spec of a v1beta1 dashboard, v1alpha1 only knows the uid of the model
+const uidAnnotation = "grafana.minicali.com/v1beta1-uid"
+
+// adoptAnnotation keeps the adoption opt-in of a v1beta1 dashboard
+const adoptAnnotation = "grafana.minicali.com/v1beta1-adopt"
+
+// syncModeAnnotation keeps the sync mode of a v1beta1 dashboard
+const syncModeAnnotation = "grafana.minicali.com/v1beta1-sync-mode"
+
+// variablesAnnotation keeps the variables of a v1beta1 dashboard, encoded in JSON
+const variablesAnnotation = "grafana.minicali.com/v1beta1-variables"
+
+// statusAnnotation keeps the fields of a v1beta1 dashboard status v1alpha1 has no counterpart for, encoded in JSON
+const statusAnnotation = "grafana.minicali.com/v1beta1-status"
+
+// nameAnnotation keeps the name of a v1alpha1 dashboard, it was never used by the operator and has no counterpart
+const nameAnnotation = "grafana.minicali.com/v1alpha1-name"
+
+// ConvertTo converts this GrafanaDashboard to the hub version.
+func (src *GrafanaDashboard) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.GrafanaDashboard)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v1beta1.GrafanaDashboardSpec{
+		Folder:             src.Spec.Folder,
+		SyncPeriod:         src.Spec.SyncPeriod,
+		GrafanaInstanceRef: convertInstanceRefTo(src.Spec.GrafanaInstanceRef),
+		OrgRef:             convertOrganizationRefTo(src.Spec.OrgRef),
+		OrgID:              src.Spec.OrgID,
+		Permissions:        convertPermissionsTo(src.Spec.Permissions),
+		FolderPermissions:  convertPermissionsTo(src.Spec.FolderPermissions),
+		DeletionPolicy:     v1beta1.DeletionPolicy(src.Spec.DeletionPolicy),
+	}
+	model, err := dashboardModelTo(src.Spec.Json)
+	if err != nil {
+		return fmt.Errorf("failed to convert the model of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+	}
+	dst.Spec.Source.JSON = model
+	if src.Spec.Name != "" {
+		setAnnotation(dst, nameAnnotation, src.Spec.Name)
+	}
+	if value, ok := popAnnotation(dst, configMapSourceAnnotation); ok {
+		configMapRef := &corev1.ConfigMapKeySelector{}
+		if err := json.Unmarshal([]byte(value), configMapRef); err != nil {
+			return fmt.Errorf("failed to convert the source of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		dst.Spec.Source.ConfigMapRef = configMapRef
+	}
+	if value, ok := popAnnotation(dst, uidAnnotation); ok {
+		dst.Spec.UID = value
+	}
+	if _, ok := popAnnotation(dst, adoptAnnotation); ok {
+		dst.Spec.Adopt = true
+	}
+	if value, ok := popAnnotation(dst, syncModeAnnotation); ok {
+		dst.Spec.SyncMode = v1beta1.SyncMode(value)
+	}
+	if value, ok := popAnnotation(dst, variablesAnnotation); ok {
+		if err := json.Unmarshal([]byte(value), &dst.Spec.Variables); err != nil {
+			return fmt.Errorf("failed to convert the variables of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+	}
+
+	dst.Status = v1beta1.GrafanaDashboardStatus{}
+	if value, ok := popAnnotation(dst, statusAnnotation); ok {
+		if err := json.Unmarshal([]byte(value), &dst.Status); err != nil {
+			return fmt.Errorf("failed to convert the status of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+	}
+	dst.Status.FolderUID = src.Status.FolderUID
+	dst.Status.DashboardUID = src.Status.DashboardUID
+	dst.Status.Conditions = src.Status.Conditions
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *GrafanaDashboard) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.GrafanaDashboard)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = GrafanaDashboardSpec{
+		Folder:             src.Spec.Folder,
+		SyncPeriod:         src.Spec.SyncPeriod,
+		GrafanaInstanceRef: convertInstanceRefFrom(src.Spec.GrafanaInstanceRef),
+		OrgRef:             convertOrganizationRefFrom(src.Spec.OrgRef),
+		OrgID:              src.Spec.OrgID,
+		Permissions:        convertPermissionsFrom(src.Spec.Permissions),
+		FolderPermissions:  convertPermissionsFrom(src.Spec.FolderPermissions),
+		DeletionPolicy:     DeletionPolicy(src.Spec.DeletionPolicy),
+	}
+	model, err := dashboardModelFrom(src.Spec.Source.JSON)
+	if err != nil {
+		return fmt.Errorf("failed to convert the model of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+	}
+	dst.Spec.Json = model
+	if value, ok := popAnnotation(dst, nameAnnotation); ok {
+		dst.Spec.Name = value
+	}
+	if src.Spec.Source.ConfigMapRef != nil {
+		value, err := json.Marshal(src.Spec.Source.ConfigMapRef)
+		if err != nil {
+			return fmt.Errorf("failed to convert the source of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		setAnnotation(dst, configMapSourceAnnotation, string(value))
+	}
+	if src.Spec.UID != "" {
+		setAnnotation(dst, uidAnnotation, src.Spec.UID)
+	}
+	if src.Spec.Adopt {
+		setAnnotation(dst, adoptAnnotation, "true")
+	}
+	if src.Spec.SyncMode != "" {
+		setAnnotation(dst, syncModeAnnotation, string(src.Spec.SyncMode))
+	}
+	if src.Spec.Variables != nil {
+		value, err := json.Marshal(src.Spec.Variables)
+		if err != nil {
+			return fmt.Errorf("failed to convert the variables of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		setAnnotation(dst, variablesAnnotation, string(value))
+	}
+
+	dst.Status = GrafanaDashboardStatus{
+		FolderUID:    src.Status.FolderUID,
+		DashboardUID: src.Status.DashboardUID,
+		Conditions:   src.Status.Conditions,
+	}
+	// The fields with a counterpart are left out of the annotation
+	hubOnly := src.Status.DeepCopy()
+	hubOnly.FolderUID = ""
+	hubOnly.DashboardUID = ""
+	hubOnly.Conditions = nil
+	if !reflect.DeepEqual(*hubOnly, v1beta1.GrafanaDashboardStatus{}) {
+		value, err := json.Marshal(hubOnly)
+		if err != nil {
+			return fmt.Errorf("failed to convert the status of GrafanaDashboard %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		setAnnotation(dst, statusAnnotation, string(value))
+	}
+	return nil
+}
+
+// dashboardModelTo converts the model, given in v1alpha1 as a JSON document encoded in a string, to a JSON object.
+// A model given as a JSON object is kept as is.
+func dashboardModelTo(src apiextensionsv1.JSON) (*apiextensionsv1.JSON, error) {
+	if src.Raw == nil {
+		return nil, nil
+	}
+	var model string
+	if err := json.Unmarshal(src.Raw, &model); err != nil {
+		if !json.Valid(src.Raw) {
+			return nil, err
+		}
+		return &apiextensionsv1.JSON{Raw: src.Raw}, nil
+	}
+	if !json.Valid([]byte(model)) {
+		return nil, fmt.Errorf("the model is not a JSON document")
+	}
+	return &apiextensionsv1.JSON{Raw: []byte(model)}, nil
+}
+
+// dashboardModelFrom encodes the model in a string, as v1alpha1 expects it
+func dashboardModelFrom(src *apiextensionsv1.JSON) (apiextensionsv1.JSON, error) {
+	if src == nil {
+		return apiextensionsv1.JSON{}, nil
+	}
+	raw, err := json.Marshal(string(src.Raw))
+	if err != nil {
+		return apiextensionsv1.JSON{}, err
+	}
+	return apiextensionsv1.JSON{Raw: raw}, nil
+}
+
+func convertPermissionsTo(src []GrafanaPermission) []v1beta1.GrafanaPermission {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1beta1.GrafanaPermission, 0, len(src))
+	for _, permission := range src {
+		dst = append(dst, v1beta1.GrafanaPermission{
+			Role:       permission.Role,
+			TeamRef:    convertTeamRefTo(permission.TeamRef),
+			User:       permission.User,
+			Permission: permission.Permission,
+		})
+	}
+	return dst
+}
+
+func convertPermissionsFrom(src []v1beta1.GrafanaPermission) []GrafanaPermission {
+	if src == nil {
+		return nil
+	}
+	dst := make([]GrafanaPermission, 0, len(src))
+	for _, permission := range src {
+		dst = append(dst, GrafanaPermission{
+			Role:       permission.Role,
+			TeamRef:    convertTeamRefFrom(permission.TeamRef),
+			User:       permission.User,
+			Permission: permission.Permission,
+		})
+	}
+	return dst
+}
@@ -27,6 +27,20 @@ const (
 	GrafanaGeneralFolder = "General"
 )
 
+// ConditionInstanceGone is set on the resources whose GrafanaInstance does not exist anymore
+const ConditionInstanceGone = "InstanceGone"
+
+// DeletionPolicy defines what happens in Grafana when a resource is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the object from Grafana with the resource
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the object in Grafana, no longer managed
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
@@ -50,6 +64,52 @@ type GrafanaDashboardSpec struct {
 
 	// Reference to the GrafanaInstance that this dashboard should be associated with
 	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+
+	// Reference to the GrafanaOrganization the dashboard and its folder belong to.
+	// Without it, the dashboard lands in the default organization.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the Grafana organization the dashboard and its folder belong to,
+	// as an alternative to orgRef. The organization must be managed by a GrafanaOrganization.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// Permissions of the dashboard, any other permission set on the dashboard is removed.
+	// When unset, the permissions are left to Grafana and inherited from the folder, an empty list removes them.
+	// +optional
+	Permissions []GrafanaPermission `json:"permissions,omitempty"`
+
+	// Permissions of the folder, any other permission set on the folder is removed.
+	// The dashboards sharing a folder must agree on them. The General folder has no permissions.
+	// +optional
+	FolderPermissions []GrafanaPermission `json:"folderPermissions,omitempty"`
+
+	// Whether the dashboard is deleted from Grafana with the resource, or retained and no longer managed.
+	// Defaults to the policy set on the operator.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// GrafanaPermission grants a permission level on a dashboard or a folder
+// to either a role, a team or a user.
+type GrafanaPermission struct {
+	// Basic role granted the permission
+	// +optional
+	// +kubebuilder:validation:Enum=Viewer;Editor
+	Role string `json:"role,omitempty"`
+
+	// Reference to the GrafanaTeam granted the permission, in the organization of the dashboard
+	// +optional
+	TeamRef *GrafanaTeamRef `json:"teamRef,omitempty"`
+
+	// Login or email of the user granted the permission
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// +kubebuilder:validation:Enum=View;Edit;Admin
+	Permission string `json:"permission"`
 }
 
 // Set default values
@@ -61,6 +121,10 @@ func (d *GrafanaDashboard) SetDefaults() {
 	if d.Spec.Folder == "" {
 		d.Spec.Folder = GrafanaGeneralFolder
 	}
+
+	if d.Spec.GrafanaInstanceRef.Namespace == "" {
+		d.Spec.GrafanaInstanceRef.Namespace = d.Namespace
+	}
 }
 
 // GrafanaInstanceRef defines the reference to a GrafanaInstance
@@ -75,6 +139,11 @@ type GrafanaDashboardStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	FolderUID    string `json:"folderUID,omitempty"`
 	DashboardUID string `json:"dashboardUID,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
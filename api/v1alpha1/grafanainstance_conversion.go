@@ -0,0 +1,172 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// portAnnotation keeps the port of a v1alpha1 instance, it was never honored by the operator and has no counterpart
+const portAnnotation = "grafana.minicali.com/v1alpha1-port"
+
+// ConvertTo converts this GrafanaInstance to the hub version.
+func (src *GrafanaInstance) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.GrafanaInstance)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v1beta1.GrafanaInstanceSpec{
+		Image:                 src.Spec.Image,
+		CredentialsSecretName: src.Spec.CredentialsSecretName,
+		UsernameKey:           src.Spec.UsernameKey,
+		PasswordKey:           src.Spec.PasswordKey,
+		OperatorServiceAccount: v1beta1.OperatorServiceAccountSpec{
+			Role:                  src.Spec.OperatorServiceAccount.Role,
+			TokenRotationInterval: src.Spec.OperatorServiceAccount.TokenRotationInterval,
+		},
+		PVCRetentionPolicy: v1beta1.DeletionPolicy(src.Spec.PVCRetentionPolicy),
+		INIConfig:          src.Spec.INIConfig,
+	}
+	if src.Spec.Port != 0 {
+		setAnnotation(dst, portAnnotation, strconv.Itoa(int(src.Spec.Port)))
+	}
+	if src.Spec.CredentialsRotation != nil {
+		dst.Spec.CredentialsRotation = &v1beta1.CredentialsRotationPolicy{Interval: src.Spec.CredentialsRotation.Interval}
+	}
+	if src.Spec.Auth != nil {
+		dst.Spec.Auth = &v1beta1.GrafanaAuth{}
+		if oauth2 := src.Spec.Auth.OAuth2; oauth2 != nil {
+			dst.Spec.Auth.OAuth2 = &v1beta1.OAuth2ClientCredentials{
+				TokenURL:        oauth2.TokenURL,
+				SecretName:      oauth2.SecretName,
+				ClientIDKey:     oauth2.ClientIDKey,
+				ClientSecretKey: oauth2.ClientSecretKey,
+				Scopes:          oauth2.Scopes,
+				Header:          oauth2.Header,
+			}
+		}
+		if mtls := src.Spec.Auth.MTLS; mtls != nil {
+			dst.Spec.Auth.MTLS = &v1beta1.MTLSAuth{SecretName: mtls.SecretName}
+		}
+	}
+
+	dst.Status = v1beta1.GrafanaInstanceStatus{
+		GrafanaUI: v1beta1.GrafanaUIStatus{
+			Conditions: src.Status.GrafanaUI.Conditions,
+			ServiceURL: src.Status.GrafanaUI.ServiceURL,
+		},
+		Credentials: v1beta1.CredentialsStatus{
+			LastRotationTime:    src.Status.Credentials.LastRotationTime,
+			LastRotationTrigger: src.Status.Credentials.LastRotationTrigger,
+		},
+		OperatorServiceAccount: v1beta1.OperatorServiceAccountStatus{
+			ID:                    src.Status.OperatorServiceAccount.ID,
+			TokenSecretName:       src.Status.OperatorServiceAccount.TokenSecretName,
+			LastTokenRotationTime: src.Status.OperatorServiceAccount.LastTokenRotationTime,
+		},
+	}
+	// The replicas were reported as "available/desired", an unexpected value is observed again by the operator
+	if src.Status.GrafanaUI.AvailableReplicas != "" {
+		_, _ = fmt.Sscanf(src.Status.GrafanaUI.AvailableReplicas, "%d/%d",
+			&dst.Status.GrafanaUI.AvailableReplicas, &dst.Status.GrafanaUI.Replicas)
+	}
+	if reprovision := src.Status.Reprovision; reprovision != nil {
+		dst.Status.Reprovision = &v1beta1.ReprovisionStatus{
+			StartedAt:   reprovision.StartedAt,
+			CompletedAt: reprovision.CompletedAt,
+			Phase:       reprovision.Phase,
+			Pending:     reprovision.Pending,
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *GrafanaInstance) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.GrafanaInstance)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = GrafanaInstanceSpec{
+		Image:                 src.Spec.Image,
+		CredentialsSecretName: src.Spec.CredentialsSecretName,
+		UsernameKey:           src.Spec.UsernameKey,
+		PasswordKey:           src.Spec.PasswordKey,
+		OperatorServiceAccount: OperatorServiceAccountSpec{
+			Role:                  src.Spec.OperatorServiceAccount.Role,
+			TokenRotationInterval: src.Spec.OperatorServiceAccount.TokenRotationInterval,
+		},
+		PVCRetentionPolicy: DeletionPolicy(src.Spec.PVCRetentionPolicy),
+		INIConfig:          src.Spec.INIConfig,
+	}
+	if value, ok := popAnnotation(dst, portAnnotation); ok {
+		port, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("failed to convert the port of GrafanaInstance %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		dst.Spec.Port = int32(port)
+	}
+	if src.Spec.CredentialsRotation != nil {
+		dst.Spec.CredentialsRotation = &CredentialsRotationPolicy{Interval: src.Spec.CredentialsRotation.Interval}
+	}
+	if src.Spec.Auth != nil {
+		dst.Spec.Auth = &GrafanaAuth{}
+		if oauth2 := src.Spec.Auth.OAuth2; oauth2 != nil {
+			dst.Spec.Auth.OAuth2 = &OAuth2ClientCredentials{
+				TokenURL:        oauth2.TokenURL,
+				SecretName:      oauth2.SecretName,
+				ClientIDKey:     oauth2.ClientIDKey,
+				ClientSecretKey: oauth2.ClientSecretKey,
+				Scopes:          oauth2.Scopes,
+				Header:          oauth2.Header,
+			}
+		}
+		if mtls := src.Spec.Auth.MTLS; mtls != nil {
+			dst.Spec.Auth.MTLS = &MTLSAuth{SecretName: mtls.SecretName}
+		}
+	}
+
+	dst.Status = GrafanaInstanceStatus{
+		GrafanaUI: GrafanaUIStatus{
+			Conditions: src.Status.GrafanaUI.Conditions,
+			ServiceURL: src.Status.GrafanaUI.ServiceURL,
+		},
+		Credentials: CredentialsStatus{
+			LastRotationTime:    src.Status.Credentials.LastRotationTime,
+			LastRotationTrigger: src.Status.Credentials.LastRotationTrigger,
+		},
+		OperatorServiceAccount: OperatorServiceAccountStatus{
+			ID:                    src.Status.OperatorServiceAccount.ID,
+			TokenSecretName:       src.Status.OperatorServiceAccount.TokenSecretName,
+			LastTokenRotationTime: src.Status.OperatorServiceAccount.LastTokenRotationTime,
+		},
+	}
+	if src.Status.GrafanaUI.Replicas != 0 || src.Status.GrafanaUI.AvailableReplicas != 0 {
+		dst.Status.GrafanaUI.AvailableReplicas = fmt.Sprintf("%d/%d", src.Status.GrafanaUI.AvailableReplicas, src.Status.GrafanaUI.Replicas)
+	}
+	if reprovision := src.Status.Reprovision; reprovision != nil {
+		dst.Status.Reprovision = &ReprovisionStatus{
+			StartedAt:   reprovision.StartedAt,
+			CompletedAt: reprovision.CompletedAt,
+			Phase:       reprovision.Phase,
+			Pending:     reprovision.Pending,
+		}
+	}
+	return nil
+}
@@ -17,11 +17,24 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	DefaultUsernameKey = "admin_username"
+	DefaultPasswordKey = "admin_password"
+
+	DefaultOperatorServiceAccountRole = "Admin"
+	DefaultTokenRotationInterval      = 24 * time.Hour
+
+	// RotateCredentialsAnnotation triggers a rotation of the admin password whenever its value changes
+	RotateCredentialsAnnotation = "grafana.minicali.com/rotate-credentials"
+)
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
@@ -30,10 +43,152 @@ type GrafanaInstanceSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	Image                 string                          `json:"image,omitempty"`
-	Port                  int32                           `json:"port,omitempty"`
-	CredentialsSecretName string                          `json:"credentialsSecretName,omitempty"`
-	INIConfig             map[string]apiextensionsv1.JSON `json:"iniConfig,omitempty"`
+	Image string `json:"image,omitempty"`
+	Port  int32  `json:"port,omitempty"`
+
+	// CredentialsSecretName is the name of the Secret holding the Grafana admin credentials.
+	// The operator generates the Secret if it does not exist. A pre-existing Secret is
+	// adopted as user-managed and never overwritten.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+
+	// UsernameKey is the key of the admin username in the credentials Secret.
+	// Defaults to "admin_username".
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// PasswordKey is the key of the admin password in the credentials Secret.
+	// Defaults to "admin_password".
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+
+	// CredentialsRotation configures the rotation of the admin password.
+	// Only Secrets generated by the operator are rotated.
+	// +optional
+	CredentialsRotation *CredentialsRotationPolicy `json:"credentialsRotation,omitempty"`
+
+	// OperatorServiceAccount configures the Grafana service account the operator authenticates with.
+	// The admin credentials are only used to bootstrap it.
+	// +optional
+	OperatorServiceAccount OperatorServiceAccountSpec `json:"operatorServiceAccount,omitempty"`
+
+	// Auth configures additional authentication applied to every request of the operator,
+	// e.g. to reach a Grafana behind an identity-aware proxy.
+	// +optional
+	Auth *GrafanaAuth `json:"auth,omitempty"`
+
+	// PVCRetentionPolicy defines whether the PVC holding the Grafana data, and the generated credentials
+	// Secret needed to sign in to it, are deleted with the instance. Defaults to Retain, a retained PVC
+	// is reused by a GrafanaInstance of the same name.
+	// +optional
+	PVCRetentionPolicy DeletionPolicy `json:"pvcRetentionPolicy,omitempty"`
+
+	INIConfig map[string]apiextensionsv1.JSON `json:"iniConfig,omitempty"`
+}
+
+// GrafanaAuth defines the authenticators applied to the requests sent to Grafana
+type GrafanaAuth struct {
+	// OAuth2 adds an access token obtained through the OAuth2 client credentials flow
+	// +optional
+	OAuth2 *OAuth2ClientCredentials `json:"oauth2,omitempty"`
+
+	// MTLS presents a client certificate on every connection
+	// +optional
+	MTLS *MTLSAuth `json:"mtls,omitempty"`
+}
+
+// OAuth2ClientCredentials defines the OAuth2 client credentials flow
+type OAuth2ClientCredentials struct {
+	// TokenURL is the URL of the token endpoint
+	TokenURL string `json:"tokenURL"`
+
+	// SecretName is the name of the Secret holding the client id and secret
+	SecretName string `json:"secretName"`
+
+	// ClientIDKey is the key of the client id in the Secret. Defaults to "client_id".
+	// +optional
+	ClientIDKey string `json:"clientIDKey,omitempty"`
+
+	// ClientSecretKey is the key of the client secret in the Secret. Defaults to "client_secret".
+	// +optional
+	ClientSecretKey string `json:"clientSecretKey,omitempty"`
+
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Header carrying the access token. With the default "Authorization" header the token replaces
+	// the Grafana credentials, "Proxy-Authorization" keeps them for proxies accepting it.
+	// +kubebuilder:validation:Enum=Authorization;Proxy-Authorization
+	// +optional
+	Header string `json:"header,omitempty"`
+}
+
+// MTLSAuth defines the client certificate presented to Grafana
+type MTLSAuth struct {
+	// SecretName is the name of a kubernetes.io/tls Secret holding the client certificate and key.
+	// The CA bundle verifying the server certificate is read from the optional ca.crt key.
+	SecretName string `json:"secretName"`
+}
+
+// OperatorServiceAccountSpec defines the Grafana service account used by the operator
+type OperatorServiceAccountSpec struct {
+	// Role is the organization role granted to the service account. Defaults to "Admin".
+	// +kubebuilder:validation:Enum=Viewer;Editor;Admin
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// TokenRotationInterval is the time duration after which the service account token is replaced.
+	// Defaults to 24h.
+	// +optional
+	TokenRotationInterval metav1.Duration `json:"tokenRotationInterval,omitempty"`
+}
+
+// CredentialsRotationPolicy defines when the admin password is rotated
+type CredentialsRotationPolicy struct {
+	// Interval is the time duration between two rotations, e.g. "2160h" for 90 days.
+	// When unset, the password is only rotated on demand by setting the
+	// grafana.minicali.com/rotate-credentials annotation to a new value.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// GetUsernameKey returns the credentials Secret key holding the admin username
+func (i *GrafanaInstance) GetUsernameKey() string {
+	if i.Spec.UsernameKey == "" {
+		return DefaultUsernameKey
+	}
+	return i.Spec.UsernameKey
+}
+
+// GetPasswordKey returns the credentials Secret key holding the admin password
+func (i *GrafanaInstance) GetPasswordKey() string {
+	if i.Spec.PasswordKey == "" {
+		return DefaultPasswordKey
+	}
+	return i.Spec.PasswordKey
+}
+
+// GetOperatorServiceAccountRole returns the organization role of the operator service account
+func (i *GrafanaInstance) GetOperatorServiceAccountRole() string {
+	if i.Spec.OperatorServiceAccount.Role == "" {
+		return DefaultOperatorServiceAccountRole
+	}
+	return i.Spec.OperatorServiceAccount.Role
+}
+
+// GetTokenRotationInterval returns the rotation interval of the operator service account token
+func (i *GrafanaInstance) GetTokenRotationInterval() time.Duration {
+	if i.Spec.OperatorServiceAccount.TokenRotationInterval.Duration <= 0 {
+		return DefaultTokenRotationInterval
+	}
+	return i.Spec.OperatorServiceAccount.TokenRotationInterval.Duration
+}
+
+// GetPVCRetentionPolicy returns whether the data of the instance is deleted with it
+func (i *GrafanaInstance) GetPVCRetentionPolicy() DeletionPolicy {
+	if i.Spec.PVCRetentionPolicy == "" {
+		return DeletionPolicyRetain
+	}
+	return i.Spec.PVCRetentionPolicy
 }
 
 // GrafanaInstanceStatus defines the observed state of GrafanaInstance
@@ -41,6 +196,71 @@ type GrafanaInstanceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	GrafanaUI GrafanaUIStatus `json:"grafanaUI,omitempty"`
+
+	// +optional
+	Credentials CredentialsStatus `json:"credentials,omitempty"`
+
+	// +optional
+	OperatorServiceAccount OperatorServiceAccountStatus `json:"operatorServiceAccount,omitempty"`
+
+	// Reprovision reports the re-provisioning of the resources of the instance after Grafana lost its data
+	// +optional
+	Reprovision *ReprovisionStatus `json:"reprovision,omitempty"`
+}
+
+// Phases of the re-provisioning, the resources are re-provisioned in this order
+const (
+	ReprovisionPhaseOrganizations = "Organizations"
+	ReprovisionPhaseTeams         = "Teams"
+	ReprovisionPhaseUsers         = "Users"
+	ReprovisionPhaseLibraryPanels = "LibraryPanels"
+	ReprovisionPhaseDashboards    = "Dashboards"
+	ReprovisionPhaseCompleted     = "Completed"
+)
+
+// ReprovisionStatus defines the progress of the re-provisioning of the resources of an instance.
+// The loss of the Grafana data is detected when the operator service account is gone.
+type ReprovisionStatus struct {
+	// StartedAt is the time the data loss was detected
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// CompletedAt is the time every resource was provisioned again
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// Phase is the kind of resources being re-provisioned, or Completed. Folders are provisioned with their dashboards.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Pending is the number of resources of the phase not provisioned yet
+	// +optional
+	Pending int `json:"pending,omitempty"`
+}
+
+// OperatorServiceAccountStatus defines the observed state of the operator service account
+type OperatorServiceAccountStatus struct {
+	// ID of the service account in Grafana
+	// +optional
+	ID int64 `json:"id,omitempty"`
+
+	// TokenSecretName is the name of the Secret holding the service account token
+	// +optional
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+
+	// LastTokenRotationTime is the time the current token was created
+	// +optional
+	LastTokenRotationTime *metav1.Time `json:"lastTokenRotationTime,omitempty"`
+}
+
+// CredentialsStatus defines the observed state of the admin credentials
+type CredentialsStatus struct {
+	// LastRotationTime is the time the admin password was last rotated
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+
+	// LastRotationTrigger is the value of the rotate-credentials annotation that triggered the last rotation
+	// +optional
+	LastRotationTrigger string `json:"lastRotationTrigger,omitempty"`
 }
 
 type GrafanaUIStatus struct {
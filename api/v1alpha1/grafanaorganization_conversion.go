@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// orgCreatedAnnotation keeps whether the operator created the organization of a v1beta1 GrafanaOrganization
+const orgCreatedAnnotation = "grafana.minicali.com/v1beta1-org-created"
+
+// orgConditionsAnnotation keeps the conditions of a v1beta1 GrafanaOrganization, encoded in JSON
+const orgConditionsAnnotation = "grafana.minicali.com/v1beta1-org-conditions"
+
+// ConvertTo converts this GrafanaOrganization to the hub version.
+func (src *GrafanaOrganization) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.GrafanaOrganization)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v1beta1.GrafanaOrganizationSpec{
+		Name:               src.Spec.Name,
+		GrafanaInstanceRef: convertInstanceRefTo(src.Spec.GrafanaInstanceRef),
+	}
+	dst.Status = v1beta1.GrafanaOrganizationStatus{
+		OrgID:                 src.Status.OrgID,
+		TokenSecretName:       src.Status.TokenSecretName,
+		LastTokenRotationTime: src.Status.LastTokenRotationTime,
+	}
+	if _, ok := popAnnotation(dst, orgCreatedAnnotation); ok {
+		dst.Status.Created = true
+	}
+	if value, ok := popAnnotation(dst, orgConditionsAnnotation); ok {
+		if err := json.Unmarshal([]byte(value), &dst.Status.Conditions); err != nil {
+			return fmt.Errorf("failed to convert the conditions of GrafanaOrganization %s/%s: %w", src.Namespace, src.Name, err)
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *GrafanaOrganization) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.GrafanaOrganization)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = GrafanaOrganizationSpec{
+		Name:               src.Spec.Name,
+		GrafanaInstanceRef: convertInstanceRefFrom(src.Spec.GrafanaInstanceRef),
+	}
+	dst.Status = GrafanaOrganizationStatus{
+		OrgID:                 src.Status.OrgID,
+		TokenSecretName:       src.Status.TokenSecretName,
+		LastTokenRotationTime: src.Status.LastTokenRotationTime,
+	}
+	if src.Status.Created {
+		setAnnotation(dst, orgCreatedAnnotation, "true")
+	}
+	if src.Status.Conditions != nil {
+		value, err := json.Marshal(src.Status.Conditions)
+		if err != nil {
+			return fmt.Errorf("failed to convert the conditions of GrafanaOrganization %s/%s: %w", src.Namespace, src.Name, err)
+		}
+		setAnnotation(dst, orgConditionsAnnotation, string(value))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// teamCreatedAnnotation keeps whether the operator created the team of a v1beta1 GrafanaTeam
+const teamCreatedAnnotation = "grafana.minicali.com/v1beta1-team-created"
+
+// ConvertTo converts this GrafanaTeam to the hub version.
+func (src *GrafanaTeam) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.GrafanaTeam)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v1beta1.GrafanaTeamSpec{
+		Name:               src.Spec.Name,
+		Email:              src.Spec.Email,
+		ExternalGroups:     src.Spec.ExternalGroups,
+		GrafanaInstanceRef: convertInstanceRefTo(src.Spec.GrafanaInstanceRef),
+		OrgRef:             convertOrganizationRefTo(src.Spec.OrgRef),
+		OrgID:              src.Spec.OrgID,
+	}
+	if src.Spec.Members != nil {
+		dst.Spec.Members = make([]v1beta1.GrafanaTeamMember, 0, len(src.Spec.Members))
+		for _, member := range src.Spec.Members {
+			dst.Spec.Members = append(dst.Spec.Members, v1beta1.GrafanaTeamMember{LoginOrEmail: member.LoginOrEmail, Role: member.Role})
+		}
+	}
+	dst.Status = v1beta1.GrafanaTeamStatus{
+		TeamID:         src.Status.TeamID,
+		OrgID:          src.Status.OrgID,
+		ExternalGroups: src.Status.ExternalGroups,
+	}
+	if _, ok := popAnnotation(dst, teamCreatedAnnotation); ok {
+		dst.Status.Created = true
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *GrafanaTeam) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.GrafanaTeam)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = GrafanaTeamSpec{
+		Name:               src.Spec.Name,
+		Email:              src.Spec.Email,
+		ExternalGroups:     src.Spec.ExternalGroups,
+		GrafanaInstanceRef: convertInstanceRefFrom(src.Spec.GrafanaInstanceRef),
+		OrgRef:             convertOrganizationRefFrom(src.Spec.OrgRef),
+		OrgID:              src.Spec.OrgID,
+	}
+	if src.Spec.Members != nil {
+		dst.Spec.Members = make([]GrafanaTeamMember, 0, len(src.Spec.Members))
+		for _, member := range src.Spec.Members {
+			dst.Spec.Members = append(dst.Spec.Members, GrafanaTeamMember{LoginOrEmail: member.LoginOrEmail, Role: member.Role})
+		}
+	}
+	dst.Status = GrafanaTeamStatus{
+		TeamID:         src.Status.TeamID,
+		OrgID:          src.Status.OrgID,
+		ExternalGroups: src.Status.ExternalGroups,
+	}
+	if src.Status.Created {
+		setAnnotation(dst, teamCreatedAnnotation, "true")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GrafanaTeamSpec defines the desired state of GrafanaTeam
+type GrafanaTeamSpec struct {
+	// Name of the team in Grafana, defaults to the name of the resource
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// Members of the team, any other member is removed
+	// +optional
+	Members []GrafanaTeamMember `json:"members,omitempty"`
+
+	// External groups of the identity provider synced with the team.
+	// Team sync requires Grafana Enterprise or Grafana Cloud.
+	// +optional
+	ExternalGroups []string `json:"externalGroups,omitempty"`
+
+	// Reference to the GrafanaInstance the team is created in
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+
+	// Reference to the GrafanaOrganization the team belongs to.
+	// Without it, the team lands in the default organization.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the Grafana organization the team belongs to, as an alternative to orgRef.
+	// The organization must be managed by a GrafanaOrganization.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+}
+
+// GrafanaTeamMember defines a member of a team
+type GrafanaTeamMember struct {
+	// Login or email of the user
+	LoginOrEmail string `json:"loginOrEmail"`
+
+	// Role of the member in the team
+	// +optional
+	// +kubebuilder:default=Member
+	// +kubebuilder:validation:Enum=Member;Admin
+	Role string `json:"role,omitempty"`
+}
+
+// GetTeamName returns the name of the team in Grafana
+func (t *GrafanaTeam) GetTeamName() string {
+	if t.Spec.Name != "" {
+		return t.Spec.Name
+	}
+	return t.Name
+}
+
+// GrafanaTeamRef defines the reference to a GrafanaTeam
+type GrafanaTeamRef struct {
+	Name string `json:"name"`
+	// Defaults to the namespace of the referencing resource
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GrafanaTeamStatus defines the observed state of GrafanaTeam
+type GrafanaTeamStatus struct {
+	// ID of the team in Grafana
+	TeamID int64 `json:"teamID,omitempty"`
+
+	// ID of the organization the team was created in
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// External groups synced with the team
+	ExternalGroups []string `json:"externalGroups,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Team ID",type=integer,JSONPath=`.status.teamID`
+
+// GrafanaTeam is the Schema for the grafanateams API
+type GrafanaTeam struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaTeamSpec   `json:"spec,omitempty"`
+	Status GrafanaTeamStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaTeamList contains a list of GrafanaTeam
+type GrafanaTeamList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaTeam `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaTeam{}, &GrafanaTeamList{})
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// userCreatedAnnotation keeps whether the operator created the user of a v1beta1 GrafanaUser
+const userCreatedAnnotation = "grafana.minicali.com/v1beta1-user-created"
+
+// ConvertTo converts this GrafanaUser to the hub version.
+func (src *GrafanaUser) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.GrafanaUser)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v1beta1.GrafanaUserSpec{
+		Login:              src.Spec.Login,
+		Email:              src.Spec.Email,
+		Name:               src.Spec.Name,
+		PasswordSecretRef:  src.Spec.PasswordSecretRef,
+		GrafanaAdmin:       src.Spec.GrafanaAdmin,
+		GrafanaInstanceRef: convertInstanceRefTo(src.Spec.GrafanaInstanceRef),
+	}
+	if src.Spec.OrgRoles != nil {
+		dst.Spec.OrgRoles = make([]v1beta1.GrafanaUserOrgRole, 0, len(src.Spec.OrgRoles))
+		for _, orgRole := range src.Spec.OrgRoles {
+			dst.Spec.OrgRoles = append(dst.Spec.OrgRoles, v1beta1.GrafanaUserOrgRole{
+				OrgRef: convertOrganizationRefTo(orgRole.OrgRef),
+				OrgID:  orgRole.OrgID,
+				Role:   orgRole.Role,
+			})
+		}
+	}
+	dst.Status = v1beta1.GrafanaUserStatus{
+		UserID:                src.Status.UserID,
+		PasswordSecretVersion: src.Status.PasswordSecretVersion,
+		OrgIDs:                src.Status.OrgIDs,
+	}
+	if _, ok := popAnnotation(dst, userCreatedAnnotation); ok {
+		dst.Status.Created = true
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *GrafanaUser) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.GrafanaUser)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = GrafanaUserSpec{
+		Login:              src.Spec.Login,
+		Email:              src.Spec.Email,
+		Name:               src.Spec.Name,
+		PasswordSecretRef:  src.Spec.PasswordSecretRef,
+		GrafanaAdmin:       src.Spec.GrafanaAdmin,
+		GrafanaInstanceRef: convertInstanceRefFrom(src.Spec.GrafanaInstanceRef),
+	}
+	if src.Spec.OrgRoles != nil {
+		dst.Spec.OrgRoles = make([]GrafanaUserOrgRole, 0, len(src.Spec.OrgRoles))
+		for _, orgRole := range src.Spec.OrgRoles {
+			dst.Spec.OrgRoles = append(dst.Spec.OrgRoles, GrafanaUserOrgRole{
+				OrgRef: convertOrganizationRefFrom(orgRole.OrgRef),
+				OrgID:  orgRole.OrgID,
+				Role:   orgRole.Role,
+			})
+		}
+	}
+	dst.Status = GrafanaUserStatus{
+		UserID:                src.Status.UserID,
+		PasswordSecretVersion: src.Status.PasswordSecretVersion,
+		OrgIDs:                src.Status.OrgIDs,
+	}
+	if src.Status.Created {
+		setAnnotation(dst, userCreatedAnnotation, "true")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GrafanaUserSpec defines the desired state of GrafanaUser
+type GrafanaUserSpec struct {
+	// +kubebuilder:validation:MinLength=1
+	Login string `json:"login"`
+
+	// +kubebuilder:validation:MinLength=1
+	Email string `json:"email"`
+
+	// Display name of the user, defaults to the login
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Key of the Secret holding the password of the user, in the namespace of the GrafanaUser.
+	// Without it the user gets a random password, for users signing in through an identity provider.
+	// +optional
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	// Roles of the user in the organizations. The user is removed from the organizations no longer listed,
+	// except the default organization which Grafana assigns to every user.
+	// +optional
+	OrgRoles []GrafanaUserOrgRole `json:"orgRoles,omitempty"`
+
+	// Whether the user is a Grafana server admin
+	// +optional
+	GrafanaAdmin bool `json:"grafanaAdmin,omitempty"`
+
+	// Reference to the GrafanaInstance the user is created in
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+}
+
+// GrafanaUserOrgRole defines the role of a user in an organization
+type GrafanaUserOrgRole struct {
+	// Reference to the GrafanaOrganization. Without orgRef and orgID, the default organization.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the organization, as an alternative to orgRef
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// +kubebuilder:validation:Enum=Viewer;Editor;Admin
+	Role string `json:"role"`
+}
+
+// GetDisplayName returns the display name of the user in Grafana
+func (u *GrafanaUser) GetDisplayName() string {
+	if u.Spec.Name != "" {
+		return u.Spec.Name
+	}
+	return u.Spec.Login
+}
+
+// GrafanaUserStatus defines the observed state of GrafanaUser
+type GrafanaUserStatus struct {
+	// ID of the user in Grafana
+	UserID int64 `json:"userID,omitempty"`
+
+	// Resource version of the password Secret last applied
+	PasswordSecretVersion string `json:"passwordSecretVersion,omitempty"`
+
+	// IDs of the organizations the user was given a role in
+	OrgIDs []int64 `json:"orgIDs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Login",type=string,JSONPath=`.spec.login`
+//+kubebuilder:printcolumn:name="User ID",type=integer,JSONPath=`.status.userID`
+
+// GrafanaUser is the Schema for the grafanausers API
+type GrafanaUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaUserSpec   `json:"spec,omitempty"`
+	Status GrafanaUserStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaUserList contains a list of GrafanaUser
+type GrafanaUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaUser{}, &GrafanaUserList{})
+}
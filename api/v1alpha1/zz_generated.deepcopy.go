@@ -23,17 +23,79 @@ package v1alpha1
 
 import (
 	appsv1 "k8s.io/api/apps/v1"
-	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsRotationPolicy) DeepCopyInto(out *CredentialsRotationPolicy) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRotationPolicy.
+func (in *CredentialsRotationPolicy) DeepCopy() *CredentialsRotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsRotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsStatus) DeepCopyInto(out *CredentialsStatus) {
+	*out = *in
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsStatus.
+func (in *CredentialsStatus) DeepCopy() *CredentialsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaAuth) DeepCopyInto(out *GrafanaAuth) {
+	*out = *in
+	if in.OAuth2 != nil {
+		in, out := &in.OAuth2, &out.OAuth2
+		*out = new(OAuth2ClientCredentials)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MTLS != nil {
+		in, out := &in.MTLS, &out.MTLS
+		*out = new(MTLSAuth)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAuth.
+func (in *GrafanaAuth) DeepCopy() *GrafanaAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaDashboard) DeepCopyInto(out *GrafanaDashboard) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaDashboard.
@@ -92,6 +154,25 @@ func (in *GrafanaDashboardSpec) DeepCopyInto(out *GrafanaDashboardSpec) {
 	in.Json.DeepCopyInto(&out.Json)
 	out.SyncPeriod = in.SyncPeriod
 	out.GrafanaInstanceRef = in.GrafanaInstanceRef
+	if in.OrgRef != nil {
+		in, out := &in.OrgRef, &out.OrgRef
+		*out = new(GrafanaOrganizationRef)
+		**out = **in
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]GrafanaPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FolderPermissions != nil {
+		in, out := &in.FolderPermissions, &out.FolderPermissions
+		*out = make([]GrafanaPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaDashboardSpec.
@@ -107,6 +188,13 @@ func (in *GrafanaDashboardSpec) DeepCopy() *GrafanaDashboardSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaDashboardStatus) DeepCopyInto(out *GrafanaDashboardStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaDashboardStatus.
@@ -196,9 +284,20 @@ func (in *GrafanaInstanceRef) DeepCopy() *GrafanaInstanceRef {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaInstanceSpec) DeepCopyInto(out *GrafanaInstanceSpec) {
 	*out = *in
+	if in.CredentialsRotation != nil {
+		in, out := &in.CredentialsRotation, &out.CredentialsRotation
+		*out = new(CredentialsRotationPolicy)
+		**out = **in
+	}
+	out.OperatorServiceAccount = in.OperatorServiceAccount
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(GrafanaAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.INIConfig != nil {
 		in, out := &in.INIConfig, &out.INIConfig
-		*out = make(map[string]v1.JSON, len(*in))
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
 		for key, val := range *in {
 			(*out)[key] = *val.DeepCopy()
 		}
@@ -219,6 +318,13 @@ func (in *GrafanaInstanceSpec) DeepCopy() *GrafanaInstanceSpec {
 func (in *GrafanaInstanceStatus) DeepCopyInto(out *GrafanaInstanceStatus) {
 	*out = *in
 	in.GrafanaUI.DeepCopyInto(&out.GrafanaUI)
+	in.Credentials.DeepCopyInto(&out.Credentials)
+	in.OperatorServiceAccount.DeepCopyInto(&out.OperatorServiceAccount)
+	if in.Reprovision != nil {
+		in, out := &in.Reprovision, &out.Reprovision
+		*out = new(ReprovisionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaInstanceStatus.
@@ -231,6 +337,275 @@ func (in *GrafanaInstanceStatus) DeepCopy() *GrafanaInstanceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaOrganization) DeepCopyInto(out *GrafanaOrganization) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaOrganization.
+func (in *GrafanaOrganization) DeepCopy() *GrafanaOrganization {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaOrganization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaOrganization) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaOrganizationList) DeepCopyInto(out *GrafanaOrganizationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GrafanaOrganization, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaOrganizationList.
+func (in *GrafanaOrganizationList) DeepCopy() *GrafanaOrganizationList {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaOrganizationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaOrganizationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaOrganizationRef) DeepCopyInto(out *GrafanaOrganizationRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaOrganizationRef.
+func (in *GrafanaOrganizationRef) DeepCopy() *GrafanaOrganizationRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaOrganizationRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaOrganizationSpec) DeepCopyInto(out *GrafanaOrganizationSpec) {
+	*out = *in
+	out.GrafanaInstanceRef = in.GrafanaInstanceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaOrganizationSpec.
+func (in *GrafanaOrganizationSpec) DeepCopy() *GrafanaOrganizationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaOrganizationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaOrganizationStatus) DeepCopyInto(out *GrafanaOrganizationStatus) {
+	*out = *in
+	if in.LastTokenRotationTime != nil {
+		in, out := &in.LastTokenRotationTime, &out.LastTokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaOrganizationStatus.
+func (in *GrafanaOrganizationStatus) DeepCopy() *GrafanaOrganizationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaOrganizationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaPermission) DeepCopyInto(out *GrafanaPermission) {
+	*out = *in
+	if in.TeamRef != nil {
+		in, out := &in.TeamRef, &out.TeamRef
+		*out = new(GrafanaTeamRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaPermission.
+func (in *GrafanaPermission) DeepCopy() *GrafanaPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeam) DeepCopyInto(out *GrafanaTeam) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeam.
+func (in *GrafanaTeam) DeepCopy() *GrafanaTeam {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeam)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaTeam) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeamList) DeepCopyInto(out *GrafanaTeamList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GrafanaTeam, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeamList.
+func (in *GrafanaTeamList) DeepCopy() *GrafanaTeamList {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeamList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaTeamList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeamMember) DeepCopyInto(out *GrafanaTeamMember) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeamMember.
+func (in *GrafanaTeamMember) DeepCopy() *GrafanaTeamMember {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeamMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeamRef) DeepCopyInto(out *GrafanaTeamRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeamRef.
+func (in *GrafanaTeamRef) DeepCopy() *GrafanaTeamRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeamRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeamSpec) DeepCopyInto(out *GrafanaTeamSpec) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]GrafanaTeamMember, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExternalGroups != nil {
+		in, out := &in.ExternalGroups, &out.ExternalGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.GrafanaInstanceRef = in.GrafanaInstanceRef
+	if in.OrgRef != nil {
+		in, out := &in.OrgRef, &out.OrgRef
+		*out = new(GrafanaOrganizationRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeamSpec.
+func (in *GrafanaTeamSpec) DeepCopy() *GrafanaTeamSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeamSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaTeamStatus) DeepCopyInto(out *GrafanaTeamStatus) {
+	*out = *in
+	if in.ExternalGroups != nil {
+		in, out := &in.ExternalGroups, &out.ExternalGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaTeamStatus.
+func (in *GrafanaTeamStatus) DeepCopy() *GrafanaTeamStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaTeamStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaUIStatus) DeepCopyInto(out *GrafanaUIStatus) {
 	*out = *in
@@ -252,3 +627,220 @@ func (in *GrafanaUIStatus) DeepCopy() *GrafanaUIStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaUser) DeepCopyInto(out *GrafanaUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaUser.
+func (in *GrafanaUser) DeepCopy() *GrafanaUser {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaUserList) DeepCopyInto(out *GrafanaUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GrafanaUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaUserList.
+func (in *GrafanaUserList) DeepCopy() *GrafanaUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GrafanaUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaUserOrgRole) DeepCopyInto(out *GrafanaUserOrgRole) {
+	*out = *in
+	if in.OrgRef != nil {
+		in, out := &in.OrgRef, &out.OrgRef
+		*out = new(GrafanaOrganizationRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaUserOrgRole.
+func (in *GrafanaUserOrgRole) DeepCopy() *GrafanaUserOrgRole {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaUserOrgRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaUserSpec) DeepCopyInto(out *GrafanaUserSpec) {
+	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OrgRoles != nil {
+		in, out := &in.OrgRoles, &out.OrgRoles
+		*out = make([]GrafanaUserOrgRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.GrafanaInstanceRef = in.GrafanaInstanceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaUserSpec.
+func (in *GrafanaUserSpec) DeepCopy() *GrafanaUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaUserStatus) DeepCopyInto(out *GrafanaUserStatus) {
+	*out = *in
+	if in.OrgIDs != nil {
+		in, out := &in.OrgIDs, &out.OrgIDs
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaUserStatus.
+func (in *GrafanaUserStatus) DeepCopy() *GrafanaUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTLSAuth) DeepCopyInto(out *MTLSAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTLSAuth.
+func (in *MTLSAuth) DeepCopy() *MTLSAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(MTLSAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OAuth2ClientCredentials) DeepCopyInto(out *OAuth2ClientCredentials) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAuth2ClientCredentials.
+func (in *OAuth2ClientCredentials) DeepCopy() *OAuth2ClientCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(OAuth2ClientCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorServiceAccountSpec) DeepCopyInto(out *OperatorServiceAccountSpec) {
+	*out = *in
+	out.TokenRotationInterval = in.TokenRotationInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorServiceAccountSpec.
+func (in *OperatorServiceAccountSpec) DeepCopy() *OperatorServiceAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorServiceAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorServiceAccountStatus) DeepCopyInto(out *OperatorServiceAccountStatus) {
+	*out = *in
+	if in.LastTokenRotationTime != nil {
+		in, out := &in.LastTokenRotationTime, &out.LastTokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorServiceAccountStatus.
+func (in *OperatorServiceAccountStatus) DeepCopy() *OperatorServiceAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorServiceAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReprovisionStatus) DeepCopyInto(out *ReprovisionStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReprovisionStatus.
+func (in *ReprovisionStatus) DeepCopy() *ReprovisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReprovisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
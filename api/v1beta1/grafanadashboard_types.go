@@ -0,0 +1,352 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	GrafanaGeneralFolder = "General"
+)
+
+// ConditionInstanceGone is set on the resources whose GrafanaInstance does not exist anymore
+const ConditionInstanceGone = "InstanceGone"
+
+// ConditionConflict is set on the dashboards whose UID, or title in the folder, is already taken in Grafana
+// by another dashboard of the same organization
+const ConditionConflict = "Conflict"
+
+// ConditionEditConflict is set on the bidirectionally synced dashboards changed both in Grafana and in the resource
+const ConditionEditConflict = "EditConflict"
+
+// ConditionExportDisabled is set on the bidirectionally synced dashboards whose changes made in Grafana cannot
+// be written back into their source, they are overwritten as in the Push mode
+const ConditionExportDisabled = "ExportDisabled"
+
+// RestoreVersionAnnotation restores the version of the dashboard it is set to, the model of the version
+// is then written back into the source of the dashboard and the annotation removed. The versions of a dashboard
+// with variables are not restored, their model holds the values of the variables.
+const RestoreVersionAnnotation = "grafana.minicali.com/restore-version"
+
+// GitCommitAnnotation holds the git commit the dashboard was applied from, it is recorded in the message of the versions
+const GitCommitAnnotation = "grafana.minicali.com/git-commit"
+
+// SyncMode defines in which direction a dashboard is synced
+// +kubebuilder:validation:Enum=Push;Bidirectional
+type SyncMode string
+
+const (
+	// SyncModePush overwrites the changes made in Grafana with the model of the resource
+	SyncModePush SyncMode = "Push"
+	// SyncModeBidirectional also writes the changes made in Grafana back into the source of the resource
+	SyncModeBidirectional SyncMode = "Bidirectional"
+)
+
+// DeletionPolicy defines what happens in Grafana when a resource is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the object from Grafana with the resource
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the object in Grafana, no longer managed
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// GrafanaDashboardSpec defines the desired state of GrafanaDashboard
+type GrafanaDashboardSpec struct {
+	// Source of the dashboard model
+	Source GrafanaDashboardSource `json:"source"`
+
+	// UID of the dashboard in Grafana, it cannot change once the dashboard is synced.
+	// Defaults to the uid of the dashboard model, or to a UID derived from the namespace and the name of the resource,
+	// so the dashboard keeps its URL when the resource is recreated.
+	// +optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]+$`
+	UID string `json:"uid,omitempty"`
+
+	// Whether the dashboard takes over the one already in Grafana with the same UID, or the same title in the folder,
+	// when no other resource of the cluster manages it. Without it, such a dashboard is reported with the Conflict condition,
+	// unless it was synced by a resource with the same namespace and name.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// Title of the folder the dashboard is created in, defaults to the General folder
+	// +optional
+	Folder string `json:"folder,omitempty"`
+
+	// SyncPeriod is the time duration to wait between each sync operation.
+	// The operator will check the actual state in Grafana and reconcile it with the desired state defined in the custom resource.
+	// +optional
+	SyncPeriod metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// Reference to the GrafanaInstance that this dashboard should be associated with
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+
+	// Reference to the GrafanaOrganization the dashboard and its folder belong to.
+	// Without it, the dashboard lands in the default organization.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the Grafana organization the dashboard and its folder belong to,
+	// as an alternative to orgRef. The organization must be managed by a GrafanaOrganization.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// Permissions of the dashboard, any other permission set on the dashboard is removed.
+	// When unset, the permissions are left to Grafana and inherited from the folder, an empty list removes them.
+	// +optional
+	Permissions []GrafanaPermission `json:"permissions,omitempty"`
+
+	// Permissions of the folder, any other permission set on the folder is removed.
+	// The dashboards sharing a folder must agree on them. The General folder has no permissions.
+	// +optional
+	FolderPermissions []GrafanaPermission `json:"folderPermissions,omitempty"`
+
+	// Whether the changes made in Grafana are overwritten, or written back into the inline model or the ConfigMap.
+	// When both Grafana and the resource changed, nothing is synced until one side matches the other
+	// or the mode is switched to Push. A model with variables cannot be written back, its dashboard is only pushed
+	// and the ExportDisabled condition reports it. Defaults to Push.
+	// +optional
+	SyncMode SyncMode `json:"syncMode,omitempty"`
+
+	// Whether the dashboard is deleted from Grafana with the resource, or retained and no longer managed.
+	// Defaults to the policy set on the operator.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Variables substituted into the strings of the dashboard model before it is pushed, where they are referenced
+	// as $(name). $$(name) is kept as the literal $(name). A string only made of a reference to a number or a boolean
+	// takes the value with its type. Referencing a variable that is not defined fails the sync.
+	// Dashboards with variables cannot be synced bidirectionally.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Variables []DashboardVariable `json:"variables,omitempty"`
+}
+
+// DashboardVariable is a value substituted into the dashboard model, exactly one of value or valueFrom must be set
+type DashboardVariable struct {
+	// Name the variable is referenced by
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Name string `json:"name"`
+
+	// Value of the variable
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// Key of a ConfigMap or a Secret of the namespace holding the value of the variable
+	// +optional
+	ValueFrom *DashboardVariableSource `json:"valueFrom,omitempty"`
+
+	// Whether the value is also set as the current value of the Grafana template variable of the same name,
+	// in templating.list of the model
+	// +optional
+	SetCurrent bool `json:"setCurrent,omitempty"`
+}
+
+// DashboardVariableSource defines where the value of a variable is read from, exactly one source must be set
+type DashboardVariableSource struct {
+	// Key of a ConfigMap
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Key of a Secret
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// GrafanaDashboardSource defines where the dashboard model is read from, exactly one source must be set
+type GrafanaDashboardSource struct {
+	// JSON is the dashboard model, inline
+	// +optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	JSON *apiextensionsv1.JSON `json:"json,omitempty"`
+
+	// ConfigMapRef selects the key of a ConfigMap holding the dashboard model, in the namespace of the dashboard
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// GrafanaPermission grants a permission level on a dashboard or a folder
+// to either a role, a team or a user.
+type GrafanaPermission struct {
+	// Basic role granted the permission
+	// +optional
+	// +kubebuilder:validation:Enum=Viewer;Editor
+	Role string `json:"role,omitempty"`
+
+	// Reference to the GrafanaTeam granted the permission, in the organization of the dashboard
+	// +optional
+	TeamRef *GrafanaTeamRef `json:"teamRef,omitempty"`
+
+	// Login or email of the user granted the permission
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// +kubebuilder:validation:Enum=View;Edit;Admin
+	Permission string `json:"permission"`
+}
+
+// SetDefaults sets the default values of the spec
+func (d *GrafanaDashboard) SetDefaults() {
+	if d.Spec.SyncPeriod.Duration == 0 {
+		d.Spec.SyncPeriod.Duration = 5 * time.Minute
+	}
+
+	if d.Spec.Folder == "" {
+		d.Spec.Folder = GrafanaGeneralFolder
+	}
+
+	if d.Spec.GrafanaInstanceRef.Namespace == "" {
+		d.Spec.GrafanaInstanceRef.Namespace = d.Namespace
+	}
+}
+
+// GrafanaInstanceRef defines the reference to a GrafanaInstance
+type GrafanaInstanceRef struct {
+	Name string `json:"name"`
+	// Namespace of the GrafanaInstance, the dashboard admission webhook defaults it to the namespace of the dashboard
+	Namespace string `json:"namespace"`
+}
+
+// GrafanaDashboardStatus defines the observed state of GrafanaDashboard
+type GrafanaDashboardStatus struct {
+	// UID of the folder the dashboard was synced to
+	// +optional
+	FolderUID string `json:"folderUID,omitempty"`
+
+	// Whether the operator created the folder for the dashboard, only such a folder is renamed with the dashboard
+	// +optional
+	FolderCreated bool `json:"folderCreated,omitempty"`
+
+	// UID of the dashboard in Grafana
+	// +optional
+	DashboardUID string `json:"dashboardUID,omitempty"`
+
+	// Title of the dashboard in Grafana, no other dashboard of the folder can take it
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// Version of the dashboard in Grafana when it was last synced
+	// +optional
+	Version int64 `json:"version,omitempty"`
+
+	// Hash of the model of the source when the dashboard was last synced, telling the changes of the resource apart
+	// +optional
+	SourceHash string `json:"sourceHash,omitempty"`
+
+	// Latest change made in Grafana written back into the source of the dashboard
+	// +optional
+	LastExport *GrafanaDashboardExport `json:"lastExport,omitempty"`
+
+	// Latest versions of the dashboard in Grafana, newest first
+	// +optional
+	Versions []GrafanaDashboardVersion `json:"versions,omitempty"`
+
+	// Rules of the lint policies of the namespace the dashboard breaks
+	// +optional
+	LintViolations []GrafanaLintViolation `json:"lintViolations,omitempty"`
+
+	// Names of the GrafanaLibraryPanels the model of the dashboard uses, they cannot be deleted meanwhile
+	// +optional
+	LibraryPanels []string `json:"libraryPanels,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GrafanaDashboardExport records a change made in Grafana and written back into the source of the dashboard
+type GrafanaDashboardExport struct {
+	// Version of the dashboard in Grafana
+	Version int64 `json:"version"`
+
+	// User who saved the version in Grafana
+	// +optional
+	UpdatedBy string `json:"updatedBy,omitempty"`
+
+	// When the version was saved in Grafana
+	// +optional
+	Updated metav1.Time `json:"updated,omitempty"`
+}
+
+// GrafanaDashboardVersion is a version of the dashboard saved in Grafana
+type GrafanaDashboardVersion struct {
+	Version int64 `json:"version"`
+
+	// User who saved the version
+	// +optional
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	// Message the version was saved with
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the version was saved
+	// +optional
+	Created metav1.Time `json:"created,omitempty"`
+}
+
+// GrafanaLintViolation is a rule of a lint policy the dashboard breaks
+type GrafanaLintViolation struct {
+	// Name of the GrafanaLintPolicy
+	Policy string `json:"policy"`
+
+	// Name of the rule
+	Rule string `json:"rule"`
+
+	// What breaks the rule
+	Message string `json:"message"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Folder",type=string,JSONPath=`.spec.folder`
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.dashboardUID`
+
+// GrafanaDashboard is the Schema for the grafanadashboards API
+type GrafanaDashboard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaDashboardSpec   `json:"spec,omitempty"`
+	Status GrafanaDashboardStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaDashboardList contains a list of GrafanaDashboard
+type GrafanaDashboardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaDashboard `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaDashboard{}, &GrafanaDashboardList{})
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/minicali/grafana-operator/internal/helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var grafanadashboardlog = logf.Log.WithName("grafanadashboard-resource")
+
+func (r *GrafanaDashboard) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-grafana-minicali-com-v1beta1-grafanadashboard,mutating=true,failurePolicy=fail,sideEffects=None,groups=grafana.minicali.com,resources=grafanadashboards,verbs=create;update,versions=v1beta1,name=mgrafanadashboard.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &GrafanaDashboard{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *GrafanaDashboard) Default() {
+	grafanadashboardlog.Info("default", "name", r.Name)
+
+	r.SetDefaults()
+}
+
+//+kubebuilder:webhook:path=/validate-grafana-minicali-com-v1beta1-grafanadashboard,mutating=false,failurePolicy=fail,sideEffects=None,groups=grafana.minicali.com,resources=grafanadashboards,verbs=create;update,versions=v1beta1,name=vgrafanadashboard.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &GrafanaDashboard{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaDashboard) ValidateCreate() error {
+	grafanadashboardlog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateDashboard()
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaDashboard) ValidateUpdate(old runtime.Object) error {
+	grafanadashboardlog.Info("validate update", "name", r.Name)
+
+	allErrs := r.validateDashboard()
+	if oldDashboard, ok := old.(*GrafanaDashboard); ok {
+		allErrs = append(allErrs, r.validateUIDUnchanged(oldDashboard)...)
+	}
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaDashboard) ValidateDelete() error {
+	return nil
+}
+
+// validateDashboard checks the dashboard model and the references of the spec
+func (r *GrafanaDashboard) validateDashboard() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.GrafanaInstanceRef.Name == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("grafanaInstanceRef", "name"), "the GrafanaInstance of the dashboard must be set"))
+	}
+	for i, permission := range r.Spec.Permissions {
+		allErrs = append(allErrs, validatePermission(specPath.Child("permissions").Index(i), permission)...)
+	}
+	for i, permission := range r.Spec.FolderPermissions {
+		allErrs = append(allErrs, validatePermission(specPath.Child("folderPermissions").Index(i), permission)...)
+	}
+	if value, ok := r.Annotations[RestoreVersionAnnotation]; ok {
+		if version, err := strconv.ParseInt(value, 10, 64); err != nil || version < 1 {
+			annotationPath := field.NewPath("metadata", "annotations").Key(RestoreVersionAnnotation)
+			allErrs = append(allErrs, field.Invalid(annotationPath, value, "the version to restore must be a positive number"))
+		}
+	}
+
+	variablesPath := specPath.Child("variables")
+	for i, variable := range r.Spec.Variables {
+		allErrs = append(allErrs, validateVariable(variablesPath.Index(i), variable)...)
+	}
+	if len(r.Spec.Variables) > 0 && r.Spec.SyncMode == SyncModeBidirectional {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("syncMode"), r.Spec.SyncMode, "the changes made in Grafana cannot be written back into a model with variables"))
+	}
+	if _, ok := r.Annotations[RestoreVersionAnnotation]; ok && len(r.Spec.Variables) > 0 {
+		annotationPath := field.NewPath("metadata", "annotations").Key(RestoreVersionAnnotation)
+		allErrs = append(allErrs, field.Forbidden(annotationPath, "the restored version cannot be written back into a model with variables"))
+	}
+
+	sourcePath := specPath.Child("source")
+	source := r.Spec.Source
+	switch {
+	case source.JSON != nil && source.ConfigMapRef != nil:
+		return append(allErrs, field.Invalid(sourcePath, "", "exactly one of json or configMapRef must be set"))
+	case source.ConfigMapRef != nil:
+		// The model is read from the ConfigMap when the dashboard is synced
+		if source.ConfigMapRef.Name == "" {
+			allErrs = append(allErrs, field.Required(sourcePath.Child("configMapRef", "name"), "the name of the ConfigMap must be set"))
+		}
+		return allErrs
+	case source.JSON == nil:
+		return append(allErrs, field.Required(sourcePath, "one of json or configMapRef must be set"))
+	}
+
+	jsonPath := sourcePath.Child("json")
+	var model map[string]interface{}
+	if err := json.Unmarshal(source.JSON.Raw, &model); err != nil || model == nil {
+		return append(allErrs, field.Invalid(jsonPath, string(source.JSON.Raw), "the dashboard model must be a JSON object"))
+	}
+	if title, _ := model["title"].(string); title == "" {
+		allErrs = append(allErrs, field.Required(jsonPath.Child("title"), "the dashboard must have a title"))
+	}
+	if uid, _ := model["uid"].(string); uid != "" && r.Spec.UID != "" && uid != r.Spec.UID {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("uid"), r.Spec.UID, "the uid must match the one of the dashboard model, "+uid))
+	}
+	allErrs = append(allErrs, r.validateVariableReferences(jsonPath, variablesPath, model)...)
+	return allErrs
+}
+
+// validateVariable checks the value of a variable is set once
+func validateVariable(variablePath *field.Path, variable DashboardVariable) field.ErrorList {
+	if variable.ValueFrom == nil {
+		return nil
+	}
+	valueFromPath := variablePath.Child("valueFrom")
+	switch {
+	case variable.Value != "":
+		return field.ErrorList{field.Invalid(variablePath, variable.Name, "exactly one of value or valueFrom must be set")}
+	case variable.ValueFrom.ConfigMapKeyRef != nil && variable.ValueFrom.SecretKeyRef != nil:
+		return field.ErrorList{field.Invalid(valueFromPath, "", "exactly one of configMapKeyRef or secretKeyRef must be set")}
+	case variable.ValueFrom.ConfigMapKeyRef == nil && variable.ValueFrom.SecretKeyRef == nil:
+		return field.ErrorList{field.Required(valueFromPath, "one of configMapKeyRef or secretKeyRef must be set")}
+	}
+	return nil
+}
+
+// validateVariableReferences checks every variable referenced by the inline model is defined, and the Grafana
+// template variables the variables set the current value of exist
+func (r *GrafanaDashboard) validateVariableReferences(jsonPath *field.Path, variablesPath *field.Path, model map[string]interface{}) field.ErrorList {
+	var allErrs field.ErrorList
+	defined := map[string]bool{}
+	for _, variable := range r.Spec.Variables {
+		defined[variable.Name] = true
+	}
+	for _, name := range helpers.VariableReferences(model) {
+		if !defined[name] {
+			allErrs = append(allErrs, field.Invalid(jsonPath, fmt.Sprintf("$(%s)", name), "the variable "+name+" is not defined in spec.variables"))
+		}
+	}
+
+	templated := map[string]bool{}
+	templating, _ := model["templating"].(map[string]interface{})
+	list, _ := templating["list"].([]interface{})
+	for _, item := range list {
+		if templateVariable, ok := item.(map[string]interface{}); ok {
+			name, _ := templateVariable["name"].(string)
+			templated[name] = true
+		}
+	}
+	for i, variable := range r.Spec.Variables {
+		if variable.SetCurrent && !templated[variable.Name] {
+			allErrs = append(allErrs, field.Invalid(variablesPath.Index(i).Child("setCurrent"), true, "the dashboard has no template variable "+variable.Name))
+		}
+	}
+	return allErrs
+}
+
+// validateUIDUnchanged rejects a change of the uid of the dashboard, Grafana would create
+// a new dashboard and leave the one previously synced behind.
+func (r *GrafanaDashboard) validateUIDUnchanged(old *GrafanaDashboard) field.ErrorList {
+	uidPath, uid := r.declaredUID()
+	_, oldUID := old.declaredUID()
+
+	if oldUID == "" {
+		// Without a declared uid, the operator derived the one of the dashboard
+		if uid == "" || old.Status.DashboardUID == "" || uid == old.Status.DashboardUID {
+			return nil
+		}
+		return field.ErrorList{field.Forbidden(uidPath, "the uid must match the one of the synced dashboard, "+old.Status.DashboardUID)}
+	}
+	if uid != oldUID {
+		return field.ErrorList{field.Forbidden(uidPath, "the uid of the dashboard cannot change, it was "+oldUID)}
+	}
+	return nil
+}
+
+// declaredUID returns the uid set on the spec, or else in the inline dashboard model, with its path
+func (r *GrafanaDashboard) declaredUID() (*field.Path, string) {
+	if r.Spec.UID != "" {
+		return field.NewPath("spec", "uid"), r.Spec.UID
+	}
+	uidPath := field.NewPath("spec", "source", "json", "uid")
+	if r.Spec.Source.JSON == nil {
+		return uidPath, ""
+	}
+	var model map[string]interface{}
+	if err := json.Unmarshal(r.Spec.Source.JSON.Raw, &model); err != nil {
+		return uidPath, ""
+	}
+	uid, _ := model["uid"].(string)
+	return uidPath, uid
+}
+
+// validatePermission checks that a permission is granted to exactly one of a role, a team or a user
+func validatePermission(path *field.Path, permission GrafanaPermission) field.ErrorList {
+	grantees := 0
+	if permission.Role != "" {
+		grantees++
+	}
+	if permission.TeamRef != nil {
+		grantees++
+	}
+	if permission.User != "" {
+		grantees++
+	}
+	if grantees != 1 {
+		return field.ErrorList{field.Invalid(path, permission, "exactly one of role, teamRef or user must be set")}
+	}
+	return nil
+}
+
+func (r *GrafanaDashboard) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("GrafanaDashboard").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func dashboardSource(model string) GrafanaDashboardSource {
+	return GrafanaDashboardSource{JSON: &apiextensionsv1.JSON{Raw: []byte(model)}}
+}
+
+func newDashboard(name string, model string) *GrafanaDashboard {
+	return &GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: GrafanaDashboardSpec{
+			Source:             dashboardSource(model),
+			GrafanaInstanceRef: GrafanaInstanceRef{Name: "grafana"},
+		},
+	}
+}
+
+var _ = Describe("GrafanaDashboard Webhook", func() {
+
+	Context("When creating a GrafanaDashboard", func() {
+		It("Should default the sync period, the folder and the namespace of the instance", func() {
+			dashboard := newDashboard("defaulted", `{"title": "Defaulted"}`)
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+
+			Expect(dashboard.Spec.SyncPeriod.Duration).To(Equal(5 * time.Minute))
+			Expect(dashboard.Spec.Folder).To(Equal(GrafanaGeneralFolder))
+			Expect(dashboard.Spec.GrafanaInstanceRef.Namespace).To(Equal("default"))
+		})
+
+		It("Should keep the values set in the spec", func() {
+			dashboard := newDashboard("explicit", `{"title": "Explicit"}`)
+			dashboard.Spec.SyncPeriod = metav1.Duration{Duration: time.Minute}
+			dashboard.Spec.Folder = "Team"
+			dashboard.Spec.GrafanaInstanceRef.Namespace = "grafana"
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+
+			Expect(dashboard.Spec.SyncPeriod.Duration).To(Equal(time.Minute))
+			Expect(dashboard.Spec.Folder).To(Equal("Team"))
+			Expect(dashboard.Spec.GrafanaInstanceRef.Namespace).To(Equal("grafana"))
+		})
+
+		It("Should reject a dashboard with two sources", func() {
+			dashboard := newDashboard("two-sources", `{"title": "Two sources"}`)
+			dashboard.Spec.Source.ConfigMapRef = &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "dashboards"},
+				Key:                  "dashboard.json",
+			}
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should accept a dashboard read from a ConfigMap", func() {
+			dashboard := newDashboard("configmap", `{}`)
+			dashboard.Spec.Source = GrafanaDashboardSource{ConfigMapRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "dashboards"},
+				Key:                  "dashboard.json",
+			}}
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+		})
+
+		It("Should reject a dashboard without a title", func() {
+			dashboard := newDashboard("no-title", `{"panels": []}`)
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should reject a permission granted to more than one grantee", func() {
+			dashboard := newDashboard("ambiguous-permission", `{"title": "Permissions"}`)
+			dashboard.Spec.Permissions = []GrafanaPermission{{Role: "Viewer", User: "jane", Permission: "View"}}
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should reject a uid not matching the one of the model", func() {
+			dashboard := newDashboard("mismatched-uid", `{"title": "Mismatched", "uid": "model"}`)
+			dashboard.Spec.UID = "spec"
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should reject a reference to an undefined variable", func() {
+			dashboard := newDashboard("undefined-variable", `{"title": "Nodes $(cluster)"}`)
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+
+			dashboard.Spec.Variables = []DashboardVariable{{Name: "cluster", Value: "prod-eu"}}
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+		})
+
+		It("Should reject the current value of a missing template variable", func() {
+			dashboard := newDashboard("missing-template-variable", `{"title": "Nodes"}`)
+			dashboard.Spec.Variables = []DashboardVariable{{Name: "env", Value: "prod", SetCurrent: true}}
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should reject a version to restore that is not a positive number", func() {
+			dashboard := newDashboard("invalid-restore", `{"title": "Restore"}`)
+			dashboard.Annotations = map[string]string{RestoreVersionAnnotation: "latest"}
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+
+			dashboard.Annotations[RestoreVersionAnnotation] = "0"
+			err = k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should reject restoring a version of a model with variables", func() {
+			dashboard := newDashboard("restore-variables", `{"title": "$(env) nodes"}`)
+			dashboard.Annotations = map[string]string{RestoreVersionAnnotation: "2"}
+			dashboard.Spec.Variables = []DashboardVariable{{Name: "env", Value: "prod"}}
+			err := k8sClient.Create(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+	})
+
+	Context("When updating a GrafanaDashboard", func() {
+		It("Should reject a change of the uid", func() {
+			dashboard := newDashboard("stable-uid", `{"title": "Stable", "uid": "stable"}`)
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+
+			dashboard.Spec.Source = dashboardSource(`{"title": "Renamed", "uid": "stable"}`)
+			Expect(k8sClient.Update(ctx, dashboard)).To(Succeed())
+
+			dashboard.Spec.Source = dashboardSource(`{"title": "Renamed", "uid": "changed"}`)
+			err := k8sClient.Update(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dashboard), dashboard)).To(Succeed())
+			dashboard.Spec.Source = dashboardSource(`{"title": "Renamed"}`)
+			err = k8sClient.Update(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+
+		It("Should only accept the uid of the synced dashboard", func() {
+			dashboard := newDashboard("generated-uid", `{"title": "Generated"}`)
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+
+			dashboard.Status.DashboardUID = "generated"
+			Expect(k8sClient.Status().Update(ctx, dashboard)).To(Succeed())
+
+			dashboard.Spec.Source = dashboardSource(`{"title": "Generated", "uid": "other"}`)
+			err := k8sClient.Update(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dashboard), dashboard)).To(Succeed())
+			dashboard.Spec.Source = dashboardSource(`{"title": "Generated", "uid": "generated"}`)
+			Expect(k8sClient.Update(ctx, dashboard)).To(Succeed())
+		})
+
+		It("Should reject a change of the uid of the spec", func() {
+			dashboard := newDashboard("spec-uid", `{"title": "Spec", "uid": "spec"}`)
+			Expect(k8sClient.Create(ctx, dashboard)).To(Succeed())
+
+			// Moving the uid from the model to the spec keeps the dashboard
+			dashboard.Spec.Source = dashboardSource(`{"title": "Spec"}`)
+			dashboard.Spec.UID = "spec"
+			Expect(k8sClient.Update(ctx, dashboard)).To(Succeed())
+
+			dashboard.Spec.UID = "changed"
+			err := k8sClient.Update(ctx, dashboard)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+	})
+
+})
@@ -0,0 +1,118 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionImported is set on the imports once the dashboards of Grafana were imported
+const ConditionImported = "Imported"
+
+// ImportedByLabel is set on the GrafanaDashboards generated by an import, with the name of the import
+const ImportedByLabel = "grafana.minicali.com/imported-by"
+
+// GrafanaDashboardImportSpec defines the desired state of GrafanaDashboardImport
+type GrafanaDashboardImportSpec struct {
+	// Reference to the GrafanaInstance the dashboards are imported from
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+
+	// Reference to the GrafanaOrganization the dashboards are imported from.
+	// Without it, the dashboards of the default organization are imported.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the Grafana organization the dashboards are imported from, as an alternative to orgRef.
+	// The organization must be managed by a GrafanaOrganization.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// Titles of the folders the dashboards are imported from, General for the dashboards out of any folder.
+	// Defaults to all the folders.
+	// +optional
+	Folders []string `json:"folders,omitempty"`
+
+	// Tags the imported dashboards must all carry
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// Name of a ConfigMap the GrafanaDashboard manifests are written to, one key per dashboard,
+	// instead of creating the GrafanaDashboards
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+}
+
+// GrafanaDashboardImportStatus defines the observed state of GrafanaDashboardImport
+type GrafanaDashboardImportStatus struct {
+	// Generation of the import the dashboards were imported for, the import runs again when the spec changes
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Dashboards imported from Grafana
+	// +optional
+	Dashboards []ImportedDashboard `json:"dashboards,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ImportedDashboard is a dashboard imported from Grafana
+type ImportedDashboard struct {
+	// UID of the dashboard in Grafana
+	UID string `json:"uid"`
+
+	// Title of the dashboard in Grafana
+	Title string `json:"title"`
+
+	// Name of the GrafanaDashboard generated for the dashboard
+	Name string `json:"name"`
+
+	// Why the GrafanaDashboard was not created, when it was skipped
+	// +optional
+	Skipped string `json:"skipped,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Imported",type=string,JSONPath=`.status.conditions[?(@.type=="Imported")].status`
+
+// GrafanaDashboardImport is the Schema for the grafanadashboardimports API.
+// It generates a GrafanaDashboard for each dashboard of Grafana matching the filters, the GrafanaDashboards adopt
+// the dashboards so the operator takes them over without recreating them.
+type GrafanaDashboardImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaDashboardImportSpec   `json:"spec,omitempty"`
+	Status GrafanaDashboardImportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaDashboardImportList contains a list of GrafanaDashboardImport
+type GrafanaDashboardImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaDashboardImport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaDashboardImport{}, &GrafanaDashboardImportList{})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var grafanainstancelog = logf.Log.WithName("grafanainstance-resource")
+
+func (r *GrafanaInstance) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-grafana-minicali-com-v1beta1-grafanainstance,mutating=true,failurePolicy=fail,sideEffects=None,groups=grafana.minicali.com,resources=grafanainstances,verbs=create;update,versions=v1beta1,name=mgrafanainstance.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &GrafanaInstance{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *GrafanaInstance) Default() {
+	grafanainstancelog.Info("default", "name", r.Name)
+
+	r.Spec.UsernameKey = r.GetUsernameKey()
+	r.Spec.PasswordKey = r.GetPasswordKey()
+	r.Spec.PVCRetentionPolicy = r.GetPVCRetentionPolicy()
+}
+
+//+kubebuilder:webhook:path=/validate-grafana-minicali-com-v1beta1-grafanainstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=grafana.minicali.com,resources=grafanainstances,verbs=create;update,versions=v1beta1,name=vgrafanainstance.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &GrafanaInstance{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaInstance) ValidateCreate() error {
+	grafanainstancelog.Info("validate create", "name", r.Name)
+
+	return r.validateInstance()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaInstance) ValidateUpdate(old runtime.Object) error {
+	grafanainstancelog.Info("validate update", "name", r.Name)
+
+	return r.validateInstance()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *GrafanaInstance) ValidateDelete() error {
+	return nil
+}
+
+// validateInstance checks that every section of the INI configuration is a map of settings
+func (r *GrafanaInstance) validateInstance() error {
+	var allErrs field.ErrorList
+	iniPath := field.NewPath("spec", "iniConfig")
+
+	for section, value := range r.Spec.INIConfig {
+		var settings map[string]interface{}
+		if err := json.Unmarshal(value.Raw, &settings); err != nil || settings == nil {
+			allErrs = append(allErrs, field.Invalid(iniPath.Key(section), string(value.Raw), "an INI section must be a map of settings"))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("GrafanaInstance").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newInstance(name string, iniConfig map[string]apiextensionsv1.JSON) *GrafanaInstance {
+	return &GrafanaInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: GrafanaInstanceSpec{
+			Image:                 "grafana/grafana:10.0.0",
+			CredentialsSecretName: name + "-admin",
+			INIConfig:             iniConfig,
+		},
+	}
+}
+
+var _ = Describe("GrafanaInstance Webhook", func() {
+
+	Context("When creating a GrafanaInstance", func() {
+		It("Should default the credentials keys and the PVC retention policy", func() {
+			instance := newInstance("defaulted", nil)
+			Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+			Expect(instance.Spec.UsernameKey).To(Equal(DefaultUsernameKey))
+			Expect(instance.Spec.PasswordKey).To(Equal(DefaultPasswordKey))
+			Expect(instance.Spec.PVCRetentionPolicy).To(Equal(DeletionPolicyRetain))
+		})
+
+		It("Should accept INI sections given as maps", func() {
+			instance := newInstance("ini-maps", map[string]apiextensionsv1.JSON{
+				"server": {Raw: []byte(`{"root_url": "https://grafana.example.com"}`)},
+			})
+			Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+		})
+
+		It("Should reject an INI section that is not a map", func() {
+			instance := newInstance("ini-scalar", map[string]apiextensionsv1.JSON{
+				"server": {Raw: []byte(`"https://grafana.example.com"`)},
+			})
+			err := k8sClient.Create(ctx, instance)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+	})
+
+	Context("When updating a GrafanaInstance", func() {
+		It("Should reject an INI section that is not a map", func() {
+			instance := newInstance("ini-update", nil)
+			Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+			instance.Spec.INIConfig = map[string]apiextensionsv1.JSON{
+				"security": {Raw: []byte(`["admin"]`)},
+			}
+			err := k8sClient.Update(ctx, instance)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error %v", err)
+		})
+	})
+
+})
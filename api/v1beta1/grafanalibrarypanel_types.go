@@ -0,0 +1,134 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionInUse is set on the library panels whose deletion waits for the dashboards still using them
+const ConditionInUse = "InUse"
+
+// LibraryPanelRefKey is set on a panel of a dashboard model to the name of a GrafanaLibraryPanel of the namespace,
+// the operator replaces it with the libraryPanel reference to the library panel in Grafana
+const LibraryPanelRefKey = "libraryPanelRef"
+
+// GrafanaLibraryPanelSpec defines the desired state of GrafanaLibraryPanel
+type GrafanaLibraryPanelSpec struct {
+	// Model of the panel, its title is the name of the library panel
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Model apiextensionsv1.JSON `json:"model"`
+
+	// UID of the library panel in Grafana, it cannot change once the library panel is synced.
+	// Defaults to a UID derived from the namespace and the name of the resource.
+	// +optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]+$`
+	UID string `json:"uid,omitempty"`
+
+	// Title of the folder the library panel is created in, defaults to the General folder
+	// +optional
+	Folder string `json:"folder,omitempty"`
+
+	// Reference to the GrafanaInstance that this library panel should be associated with
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+
+	// Reference to the GrafanaOrganization the library panel and its folder belong to.
+	// Without it, the library panel lands in the default organization.
+	// +optional
+	OrgRef *GrafanaOrganizationRef `json:"orgRef,omitempty"`
+
+	// ID of the Grafana organization the library panel and its folder belong to,
+	// as an alternative to orgRef. The organization must be managed by a GrafanaOrganization.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	OrgID int64 `json:"orgID,omitempty"`
+}
+
+// GrafanaLibraryPanelStatus defines the observed state of GrafanaLibraryPanel
+type GrafanaLibraryPanelStatus struct {
+	// Generation of the library panel last synced to Grafana
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// UID of the library panel in Grafana, set once it is synced
+	// +optional
+	UID string `json:"uid,omitempty"`
+
+	// Name of the library panel in Grafana
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// UID of the folder of the library panel, none for the General folder
+	// +optional
+	FolderUID string `json:"folderUID,omitempty"`
+
+	// Whether the operator created the folder for the library panel, only such a folder is renamed with it
+	// +optional
+	FolderCreated bool `json:"folderCreated,omitempty"`
+
+	// Version of the library panel last synced
+	// +optional
+	Version int64 `json:"version,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.uid`
+//+kubebuilder:printcolumn:name="In Use",type=string,JSONPath=`.status.conditions[?(@.type=="InUse")].status`
+
+// GrafanaLibraryPanel is the Schema for the grafanalibrarypanels API.
+// The GrafanaDashboards of its namespace use it by setting libraryPanelRef to its name on a panel of their model.
+// It cannot be deleted while dashboards use it.
+type GrafanaLibraryPanel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaLibraryPanelSpec   `json:"spec,omitempty"`
+	Status GrafanaLibraryPanelStatus `json:"status,omitempty"`
+}
+
+// SetDefaults sets the default values of the spec
+func (p *GrafanaLibraryPanel) SetDefaults() {
+	if p.Spec.Folder == "" {
+		p.Spec.Folder = GrafanaGeneralFolder
+	}
+
+	if p.Spec.GrafanaInstanceRef.Namespace == "" {
+		p.Spec.GrafanaInstanceRef.Namespace = p.Namespace
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaLibraryPanelList contains a list of GrafanaLibraryPanel
+type GrafanaLibraryPanelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaLibraryPanel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaLibraryPanel{}, &GrafanaLibraryPanelList{})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionLintFailed is set on the dashboards breaking rules of the lint policies of their namespace
+const ConditionLintFailed = "LintFailed"
+
+// LintMode defines what happens to the dashboards breaking the rules of a lint policy
+// +kubebuilder:validation:Enum=Warn;Enforce
+type LintMode string
+
+const (
+	// LintModeWarn reports the broken rules in the status of the dashboards, which are synced anyway
+	LintModeWarn LintMode = "Warn"
+	// LintModeEnforce also stops syncing the dashboards until they follow the rules
+	LintModeEnforce LintMode = "Enforce"
+)
+
+// GrafanaLintPolicySpec defines the rules the dashboards of the namespace are checked against before they are synced
+type GrafanaLintPolicySpec struct {
+	// Whether the dashboards breaking the rules are only reported, or not synced. Defaults to Warn.
+	// +optional
+	Mode LintMode `json:"mode,omitempty"`
+
+	// Rules the dashboards are checked against
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Rules []GrafanaLintRule `json:"rules"`
+}
+
+// GrafanaLintRule enables a rule of the operator. The built-in rules are:
+// datasource-uid, panels and queries must reference their datasource by UID rather than by name;
+// deprecated-panels, panels must not use a deprecated type, the comma-separated types param defaults to graph,singlestat,table-old;
+// required-tags, the dashboard must carry the comma-separated tags param;
+// min-refresh, the dashboard must not refresh faster than the interval param, 30s by default.
+type GrafanaLintRule struct {
+	// Name of the rule
+	Name string `json:"name"`
+
+	// Parameters of the rule
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+
+// GrafanaLintPolicy is the Schema for the grafanalintpolicies API.
+// The GrafanaDashboards of its namespace are checked against its rules before they are synced.
+type GrafanaLintPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GrafanaLintPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaLintPolicyList contains a list of GrafanaLintPolicy
+type GrafanaLintPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaLintPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaLintPolicy{}, &GrafanaLintPolicyList{})
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionReferenced is set on the organizations whose deletion waits for the resources still referencing them
+const ConditionReferenced = "Referenced"
+
+// GrafanaOrganizationSpec defines the desired state of GrafanaOrganization
+type GrafanaOrganizationSpec struct {
+	// Name of the organization in Grafana, defaults to the name of the resource
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Reference to the GrafanaInstance the organization is created in
+	GrafanaInstanceRef GrafanaInstanceRef `json:"grafanaInstanceRef"`
+}
+
+// GetOrganizationName returns the name of the organization in Grafana
+func (o *GrafanaOrganization) GetOrganizationName() string {
+	if o.Spec.Name != "" {
+		return o.Spec.Name
+	}
+	return o.Name
+}
+
+// GrafanaOrganizationRef defines the reference to a GrafanaOrganization
+type GrafanaOrganizationRef struct {
+	Name string `json:"name"`
+	// Defaults to the namespace of the referencing resource
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GrafanaOrganizationStatus defines the observed state of GrafanaOrganization
+type GrafanaOrganizationStatus struct {
+	// ID of the organization in Grafana
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// Whether the operator created the organization, only those are deleted with the resource
+	Created bool `json:"created,omitempty"`
+
+	// Name of the Secret holding the token of the operator service account of the organization
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+
+	LastTokenRotationTime *metav1.Time `json:"lastTokenRotationTime,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Org ID",type=integer,JSONPath=`.status.orgID`
+
+// GrafanaOrganization is the Schema for the grafanaorganizations API
+type GrafanaOrganization struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaOrganizationSpec   `json:"spec,omitempty"`
+	Status GrafanaOrganizationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaOrganizationList contains a list of GrafanaOrganization
+type GrafanaOrganizationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaOrganization `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaOrganization{}, &GrafanaOrganizationList{})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	"github.com/minicali/grafana-operator/internal/reconcilers"
+)
+
+// errOrganizationNotReady is returned while a referenced GrafanaOrganization is not created in Grafana yet
+var errOrganizationNotReady = errors.New("organization not created in Grafana yet")
+
+// errOrganizationGone is returned when no GrafanaOrganization manages an organization anymore
+var errOrganizationGone = errors.New("no GrafanaOrganization manages the organization")
+
+// errInstanceGone is returned when the GrafanaInstance referenced by a resource does not exist
+var errInstanceGone = errors.New("GrafanaInstance not found")
+
+// isInstanceGone reports whether the GrafanaInstance referenced by a resource does not exist
+func isInstanceGone(err error) bool {
+	return errors.Is(err, errInstanceGone)
+}
+
+// isGrafanaNotReady reports whether the error is expected to go away once Grafana, the operator
+// service accounts or the referenced resources are bootstrapped, so the reconcile should simply be retried later.
+func isGrafanaNotReady(err error) bool {
+	return errors.Is(err, grafana.ErrServiceAccountNotReady) ||
+		errors.Is(err, grafana.ErrCredentialsRotationInProgress) ||
+		errors.Is(err, reconcilers.ErrGrafanaNotReady) ||
+		errors.Is(err, errOrganizationNotReady) ||
+		errors.Is(err, errTeamNotReady) ||
+		errors.Is(err, errLibraryPanelNotReady)
+}
+
+// getAdminClient returns a client authenticated with the admin credentials of the instance, and the admin login
+func getAdminClient(ctx context.Context, c client.Client, clients *grafana.ClientRegistry, instance *grafanav1beta1.GrafanaInstance) (*grafana.GrafanaClient, string, error) {
+	if instance.Status.GrafanaUI.ServiceURL == "" {
+		return nil, "", fmt.Errorf("%w: service URL not known yet", reconcilers.ErrGrafanaNotReady)
+	}
+
+	adminSecret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Name: instance.Spec.CredentialsSecretName, Namespace: instance.Namespace}, adminSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	adminLogin, _, err := helpers.GetCredentialsFromSecret(adminSecret, instance.GetUsernameKey(), instance.GetPasswordKey())
+	if err != nil {
+		return nil, "", err
+	}
+
+	auths, err := grafana.GetInstanceAuthenticators(ctx, c, instance)
+	if err != nil {
+		return nil, "", err
+	}
+
+	adminClient, err := grafana.CreateGrafanaClientFromSecret(ctx, instance, adminSecret, instance.Status.GrafanaUI.ServiceURL, clients.TransportOptions(instance), auths...)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := adminClient.IsGrafanaHealthy(ctx); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", reconcilers.ErrGrafanaNotReady, err)
+	}
+
+	return adminClient, adminLogin, nil
+}
+
+// getOrganization returns the GrafanaOrganization selected by orgRef or orgID, or nil for the default organization.
+// The namespace is the one of the referencing resource.
+func getOrganization(ctx context.Context, c client.Client, namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgRef *grafanav1beta1.GrafanaOrganizationRef, orgID int64) (*grafanav1beta1.GrafanaOrganization, error) {
+	switch {
+	case orgRef != nil:
+		if orgRef.Namespace != "" {
+			namespace = orgRef.Namespace
+		}
+		org := &grafanav1beta1.GrafanaOrganization{}
+		if err := c.Get(ctx, client.ObjectKey{Name: orgRef.Name, Namespace: namespace}, org); err != nil {
+			return nil, err
+		}
+		if org.Spec.GrafanaInstanceRef != instanceRef {
+			return nil, fmt.Errorf("GrafanaOrganization %s/%s belongs to another GrafanaInstance", namespace, org.Name)
+		}
+		return org, nil
+
+	case orgID != 0 && orgID != grafana.DefaultOrgID:
+		orgs := &grafanav1beta1.GrafanaOrganizationList{}
+		if err := c.List(ctx, orgs); err != nil {
+			return nil, err
+		}
+		for i := range orgs.Items {
+			if orgs.Items[i].Spec.GrafanaInstanceRef == instanceRef && orgs.Items[i].Status.OrgID == orgID {
+				return &orgs.Items[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w %d", errOrganizationGone, orgID)
+
+	default:
+		return nil, nil
+	}
+}
+
+// getOrganizationClient returns the client of the organization selected by orgRef or orgID,
+// authenticated with the operator service account of the organization.
+func getOrganizationClient(ctx context.Context, c client.Client, clients *grafana.ClientRegistry, instance *grafanav1beta1.GrafanaInstance, namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgRef *grafanav1beta1.GrafanaOrganizationRef, orgID int64) (*grafana.GrafanaClient, error) {
+	org, err := getOrganization(ctx, c, namespace, instanceRef, orgRef, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return clients.GetClient(ctx, c, instance)
+	}
+	return clients.GetOrganizationClient(ctx, c, instance, org)
+}
+
+// getOrganizationID returns the ID of the organization selected by orgRef or orgID
+func getOrganizationID(ctx context.Context, c client.Client, namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgRef *grafanav1beta1.GrafanaOrganizationRef, orgID int64) (int64, error) {
+	if orgRef == nil {
+		if orgID == 0 {
+			return grafana.DefaultOrgID, nil
+		}
+		return orgID, nil
+	}
+
+	org, err := getOrganization(ctx, c, namespace, instanceRef, orgRef, orgID)
+	if err != nil {
+		return -1, err
+	}
+	if org.Status.OrgID == 0 {
+		return -1, fmt.Errorf("%w: %s/%s", errOrganizationNotReady, org.Namespace, org.Name)
+	}
+	return org.Status.OrgID, nil
+}
+
+// organizationIndexKeys returns the keys under which a resource referencing an organization is indexed
+func organizationIndexKeys(namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgRef *grafanav1beta1.GrafanaOrganizationRef, orgID int64) []string {
+	switch {
+	case orgRef != nil:
+		if orgRef.Namespace != "" {
+			namespace = orgRef.Namespace
+		}
+		return []string{organizationRefIndexKey(namespace, orgRef.Name)}
+	case orgID != 0:
+		return []string{organizationIDIndexKey(instanceRef, orgID)}
+	default:
+		return nil
+	}
+}
+
+func organizationRefIndexKey(namespace string, name string) string {
+	return "ref:" + namespace + "/" + name
+}
+
+func organizationIDIndexKey(instanceRef grafanav1beta1.GrafanaInstanceRef, orgID int64) string {
+	return "id:" + instanceRef.Namespace + "/" + instanceRef.Name + "/" + strconv.FormatInt(orgID, 10)
+}
+
+// instanceIndexKey returns the key under which a resource referencing a GrafanaInstance is indexed
+func instanceIndexKey(instanceRef grafanav1beta1.GrafanaInstanceRef) string {
+	return instanceRef.Namespace + "/" + instanceRef.Name
+}
+
+// instanceSecretNames returns the names of the Secrets the clients of the instance are created from
+func instanceSecretNames(instance *grafanav1beta1.GrafanaInstance) []string {
+	names := []string{instance.Spec.CredentialsSecretName}
+	if instance.Status.OperatorServiceAccount.TokenSecretName != "" {
+		names = append(names, instance.Status.OperatorServiceAccount.TokenSecretName)
+	}
+	if instance.Spec.Auth != nil && instance.Spec.Auth.OAuth2 != nil {
+		names = append(names, instance.Spec.Auth.OAuth2.SecretName)
+	}
+	if instance.Spec.Auth != nil && instance.Spec.Auth.MTLS != nil {
+		names = append(names, instance.Spec.Auth.MTLS.SecretName)
+	}
+	return names
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// errTeamNotReady is returned while a GrafanaTeam referenced by a permission is not created in Grafana yet
+var errTeamNotReady = errors.New("team not created in Grafana yet")
+
+// resolvePermissions converts the permissions of a resource to the ones of Grafana.
+// Teams are resolved through their GrafanaTeam and must belong to the organization orgID,
+// users are looked up by login or email.
+func resolvePermissions(ctx context.Context, log logr.Logger, c client.Client, namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgID int64, permissions []grafanav1beta1.GrafanaPermission) ([]grafana.Permission, error) {
+	resolved := make([]grafana.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		level, err := grafana.ParsePermissionLevel(permission.Permission)
+		if err != nil {
+			return nil, err
+		}
+		item := grafana.Permission{Permission: level}
+
+		switch {
+		case permission.Role != "" && permission.TeamRef == nil && permission.User == "":
+			item.Role = permission.Role
+
+		case permission.TeamRef != nil && permission.Role == "" && permission.User == "":
+			teamID, err := getTeamID(ctx, c, namespace, instanceRef, orgID, permission.TeamRef)
+			if err != nil {
+				return nil, err
+			}
+			item.TeamID = teamID
+
+		case permission.User != "" && permission.Role == "" && permission.TeamRef == nil:
+			userID, err := grafana.FromContext(ctx).GetOrgUserID(ctx, log, permission.User)
+			if err != nil {
+				return nil, err
+			}
+			item.UserID = userID
+
+		default:
+			return nil, errors.New("a permission must grant exactly one of role, teamRef or user")
+		}
+		resolved = append(resolved, item)
+	}
+	return resolved, nil
+}
+
+// getTeamID returns the ID of the team managed by the referenced GrafanaTeam
+func getTeamID(ctx context.Context, c client.Client, namespace string, instanceRef grafanav1beta1.GrafanaInstanceRef, orgID int64, teamRef *grafanav1beta1.GrafanaTeamRef) (int64, error) {
+	if teamRef.Namespace != "" {
+		namespace = teamRef.Namespace
+	}
+	team := &grafanav1beta1.GrafanaTeam{}
+	if err := c.Get(ctx, client.ObjectKey{Name: teamRef.Name, Namespace: namespace}, team); err != nil {
+		return -1, err
+	}
+	if team.Spec.GrafanaInstanceRef != instanceRef {
+		return -1, fmt.Errorf("GrafanaTeam %s/%s belongs to another GrafanaInstance", namespace, team.Name)
+	}
+	if team.Status.TeamID == 0 {
+		return -1, fmt.Errorf("%w: %s/%s", errTeamNotReady, namespace, team.Name)
+	}
+	if team.Status.OrgID != orgID {
+		return -1, fmt.Errorf("GrafanaTeam %s/%s belongs to the organization %d, not %d", namespace, team.Name, team.Status.OrgID, orgID)
+	}
+	return team.Status.TeamID, nil
+}
@@ -18,32 +18,76 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/reconcilers"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
 )
 
 // GrafanaDashboardReconciler reconciles a GrafanaDashboard object
 type GrafanaDashboardReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Clients  *grafana.ClientRegistry
+	Recorder record.EventRecorder
+	// DefaultDeletionPolicy applies to the dashboards without a deletion policy
+	DefaultDeletionPolicy grafanav1beta1.DeletionPolicy
+	// DeletionTimeout bounds how long the deletion of a dashboard waits for Grafana to be reachable
+	DeletionTimeout time.Duration
 }
 
-const grafanaDashboardFinalizer = "finalizer.grafana.minicali.com"
+const (
+	grafanaDashboardFinalizer = "finalizer.grafana.minicali.com"
+
+	// serviceAccountRequeueDelay is the delay before retrying while the operator service account is bootstrapped
+	serviceAccountRequeueDelay = 10 * time.Second
+
+	// dashboardInstanceIndex indexes the dashboards by the GrafanaInstance they reference
+	dashboardInstanceIndex = "spec.grafanaInstanceRef"
+
+	// dashboardConfigMapIndex indexes the dashboards by the ConfigMaps holding their model or the value of a variable
+	dashboardConfigMapIndex = "spec.source.configMapRef.name"
+
+	// dashboardSecretIndex indexes the dashboards by the Secrets holding the value of a variable
+	dashboardSecretIndex = "spec.variables.valueFrom.secretKeyRef.name"
+
+	// DefaultDeletionTimeout is how long the deletion of a dashboard, organization, team or user waits for Grafana by default
+	DefaultDeletionTimeout = 5 * time.Minute
+)
 
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards/finalizers,verbs=update
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanateams,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanalintpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanalibrarypanels,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -56,10 +100,12 @@ const grafanaDashboardFinalizer = "finalizer.grafana.minicali.com"
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *GrafanaDashboardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx).WithValues("grafanadashboard", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
 	log.Info("Starting reconciliation")
 
 	// your code to get the GrafanaDashboard resource
-	grafanaDashboard := &grafanav1alpha1.GrafanaDashboard{}
+	grafanaDashboard := &grafanav1beta1.GrafanaDashboard{}
 	if err := r.Get(ctx, req.NamespacedName, grafanaDashboard); err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("GrafanaDashboard resource not found. Ignoring since object must be deleted.")
@@ -71,113 +117,532 @@ func (r *GrafanaDashboardReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	grafanaDashboard.SetDefaults()
 
-	// Get the GrafanaInstance resource
-	grafanaInstanceName := grafanaDashboard.Spec.GrafanaInstanceRef.Name
-	grafanaInstanceNamespace := grafanaDashboard.Spec.GrafanaInstanceRef.Namespace
-
-	grafanaInstance := &grafanav1alpha1.GrafanaInstance{}
-	err := r.Get(ctx, client.ObjectKey{Name: grafanaInstanceName, Namespace: grafanaInstanceNamespace}, grafanaInstance)
-	if err != nil {
-		log.Error(err, "unable to fetch GrafanaInstance")
-		return ctrl.Result{}, err
+	// Deletion is handled first, it must not be held by an instance that is gone or unreachable
+	if grafanaDashboard.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, log, grafanaDashboard)
 	}
 
-	// Get the service URL
-	grafanaInstanceServiceURL := grafanaInstance.Status.GrafanaUI.ServiceURL
-	if grafanaInstanceServiceURL == "" {
-		log.Error(err, "failed to retrieve url")
+	grafanaClient, err := r.getGrafanaClient(ctx, grafanaDashboard)
+	if err := r.setInstanceGone(ctx, grafanaDashboard, isInstanceGone(err)); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
 		return ctrl.Result{}, err
 	}
-	// Get the secret
-	grafanaInstanceSecret := &corev1.Secret{}
-	err = r.Get(ctx, client.ObjectKey{Name: grafanaInstance.Spec.CredentialsSecretName, Namespace: grafanaInstance.Namespace}, grafanaInstanceSecret)
 	if err != nil {
-		log.Error(err, "unable to fetch Secret")
+		if isInstanceGone(err) {
+			log.Info("GrafanaInstance is gone, waiting for it to be recreated", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: grafanaDashboard.Spec.SyncPeriod.Duration}, nil
+		}
+		if isGrafanaNotReady(err) {
+			log.Info("Operator service account not bootstrapped yet, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "unable to get a healthy Grafana Client")
 		return ctrl.Result{}, err
 	}
+	ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
 
-	// Create Grafana client
-	grafanaClient, err := grafana.CreateGrafanaClientFromSecret(ctx, grafanaInstanceSecret, grafanaInstanceServiceURL)
-	if err != nil {
-		log.Error(err, "unable to create Grafana Client")
+	if err := r.syncDashboard(ctx, log, grafanaDashboard); err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Referenced resources not created in Grafana yet, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
-	// Check Grafana health
-	_, err = grafanaClient.IsGrafanaHealthy()
+	// Requeue for periodic sync
+	syncPeriod := grafanaDashboard.Spec.SyncPeriod.Duration
+	return ctrl.Result{RequeueAfter: syncPeriod}, nil
+}
+
+// getGrafanaClient returns the client of the organization the dashboard belongs to
+func (r *GrafanaDashboardReconciler) getGrafanaClient(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard) (*grafana.GrafanaClient, error) {
+	instanceRef := grafanaDashboard.Spec.GrafanaInstanceRef
+	grafanaInstance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: instanceRef.Name, Namespace: instanceRef.Namespace}, grafanaInstance)
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s/%s", errInstanceGone, instanceRef.Namespace, instanceRef.Name)
+	}
 	if err != nil {
-		log.Error(err, "Failed to check Grafana health")
-		return ctrl.Result{}, err
+		return nil, err
+	}
+	if grafanaInstance.Status.GrafanaUI.ServiceURL == "" {
+		return nil, fmt.Errorf("%w: service URL not known yet", reconcilers.ErrGrafanaNotReady)
 	}
-	log.Info("Grafana connection healthy")
 
-	// Check if the GrafanaDashboard resource is being deleted
-	if grafanaDashboard.DeletionTimestamp != nil {
-		// The object is being deleted
-		if containsString(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer) {
+	// The operator service account is bootstrapped by the GrafanaInstance controller
+	return getOrganizationClient(ctx, r.Client, r.Clients, grafanaInstance, grafanaDashboard.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef, grafanaDashboard.Spec.OrgRef, grafanaDashboard.Spec.OrgID)
+}
 
-			// Delete the dashboard from Grafana
-			if err = grafanaClient.DeleteDashboard(log, grafanaDashboard.Status.DashboardUID); err != nil {
-				return ctrl.Result{}, err
-			}
-			// Remove the finalizer from the list and update it.
-			grafanaDashboard.ObjectMeta.Finalizers = removeString(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer)
-			if err := r.Update(context.Background(), grafanaDashboard); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
+// setInstanceGone updates the InstanceGone condition of the dashboard
+func (r *GrafanaDashboardReconciler) setInstanceGone(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, gone bool) error {
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionInstanceGone,
+		Status:             metav1.ConditionFalse,
+		Reason:             "InstanceFound",
+		ObservedGeneration: grafanaDashboard.Generation,
+	}
+	if gone {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "InstanceNotFound"
+		condition.Message = fmt.Sprintf("GrafanaInstance %s/%s does not exist", grafanaDashboard.Spec.GrafanaInstanceRef.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef.Name)
+	}
+
+	current := meta.FindStatusCondition(grafanaDashboard.Status.Conditions, grafanav1beta1.ConditionInstanceGone)
+	// The condition only shows up once the instance went missing
+	if current == nil && !gone {
+		return nil
+	}
+	if current != nil && current.Status == condition.Status && current.Message == condition.Message {
+		return nil
+	}
+	meta.SetStatusCondition(&grafanaDashboard.Status.Conditions, condition)
+	return r.Status().Update(ctx, grafanaDashboard)
+}
+
+// reconcileDeletion cleans up Grafana and releases the finalizer. While Grafana cannot be reached,
+// the deletion is retried until the deletion timeout, then the finalizer is released anyway.
+func (r *GrafanaDashboardReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard) (ctrl.Result, error) {
+	if !containsString(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer) {
 		return ctrl.Result{}, nil
 	}
+	retain := r.getDeletionPolicy(grafanaDashboard) == grafanav1beta1.DeletionPolicyRetain
+
+	grafanaClient, err := r.getGrafanaClient(ctx, grafanaDashboard)
+	if err == nil {
+		ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+		err = r.deleteDashboard(ctx, log, grafanaDashboard)
+	}
+	switch {
+	case err == nil:
+	case retain:
+		// Nothing to clean up, the dashboard keeps the tags of the operator and goes without the annotation
+		log.Info("Retaining Grafana dashboard without releasing it", "reason", err.Error())
+		r.Recorder.Eventf(grafanaDashboard, corev1.EventTypeNormal, "Retained", "Dashboard retained in Grafana, it could not be annotated and untagged: %v", err)
+	case time.Since(grafanaDashboard.DeletionTimestamp.Time) >= r.DeletionTimeout:
+		log.Error(err, "Giving up deleting the Grafana dashboard", "timeout", r.DeletionTimeout)
+		r.Recorder.Eventf(grafanaDashboard, corev1.EventTypeWarning, "DeletionAbandoned", "Dashboard left in Grafana, it could not be deleted within %s: %v", r.DeletionTimeout, err)
+	default:
+		log.Info("Failed to delete the Grafana dashboard, retrying later", "reason", err.Error())
+		r.Recorder.Eventf(grafanaDashboard, corev1.EventTypeWarning, "DeletionFailed", "Dashboard could not be deleted from Grafana, retrying: %v", err)
+		return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+	}
+
+	// Remove the finalizer from the list and update it.
+	grafanaDashboard.ObjectMeta.Finalizers = removeString(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer)
+	return ctrl.Result{}, r.Update(ctx, grafanaDashboard)
+}
+
+// deleteDashboard removes the dashboard from Grafana, or annotates it when it is retained
+func (r *GrafanaDashboardReconciler) deleteDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard) error {
+	dashboardUID := grafanaDashboard.Status.DashboardUID
+	// The dashboard never made it to Grafana
+	if dashboardUID == "" {
+		return nil
+	}
+
+	grafanaClient := grafana.FromContext(ctx)
+	if r.getDeletionPolicy(grafanaDashboard) == grafanav1beta1.DeletionPolicyRetain {
+		text := fmt.Sprintf("No longer managed by the grafana-operator, GrafanaDashboard %s/%s was deleted with the Retain policy", grafanaDashboard.Namespace, grafanaDashboard.Name)
+		if err := grafanaClient.AnnotateDashboard(ctx, log, dashboardUID, text); err != nil {
+			return err
+		}
+		// Untagged, the dashboard is no longer taken over by a resource with the same name
+		if err := grafanaClient.ReleaseDashboard(ctx, log, dashboardUID, text); err != nil && !grafana.IsNotFound(err) {
+			return err
+		}
+		log.Info("Retaining Grafana dashboard", "dashboardUID", dashboardUID)
+		r.Recorder.Event(grafanaDashboard, corev1.EventTypeNormal, "Retained", "Dashboard retained in Grafana, no longer managed")
+		return nil
+	}
+
+	if err := grafanaClient.DeleteDashboard(ctx, log, dashboardUID); err != nil && !grafana.IsNotFound(err) {
+		return err
+	}
+	r.Recorder.Event(grafanaDashboard, corev1.EventTypeNormal, "Deleted", "Dashboard deleted from Grafana")
+	return nil
+}
+
+// getDeletionPolicy returns the deletion policy of the dashboard, or the default one of the operator
+func (r *GrafanaDashboardReconciler) getDeletionPolicy(grafanaDashboard *grafanav1beta1.GrafanaDashboard) grafanav1beta1.DeletionPolicy {
+	if grafanaDashboard.Spec.DeletionPolicy != "" {
+		return grafanaDashboard.Spec.DeletionPolicy
+	}
+	if r.DefaultDeletionPolicy != "" {
+		return r.DefaultDeletionPolicy
+	}
+	return grafanav1beta1.DeletionPolicyDelete
+}
+
+// syncDashboard upserts the dashboard and its folder in Grafana
+func (r *GrafanaDashboardReconciler) syncDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard) error {
+	grafanaClient := grafana.FromContext(ctx)
 
 	// Add finalizer for this CR, if it doesn't exist
 	if !containsString(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer) {
 		grafanaDashboard.ObjectMeta.Finalizers = append(grafanaDashboard.ObjectMeta.Finalizers, grafanaDashboardFinalizer)
-		if err := r.Update(context.Background(), grafanaDashboard); err != nil {
-			return ctrl.Result{}, err
+		if err := r.Update(ctx, grafanaDashboard); err != nil {
+			return err
 		}
 	}
 
-	folderUID := ""
-	if !grafana.IsGeneralFolder(grafanaDashboard.Spec.Folder) {
+	model, err := r.getDashboardModel(ctx, grafanaDashboard)
+	if err != nil {
+		return err
+	}
+	model, err = r.renderDashboardModel(ctx, grafanaDashboard, model)
+	if err != nil {
+		return err
+	}
+	if err := r.setLibraryPanels(ctx, log, grafanaDashboard, libraryPanelRefs(model)); err != nil {
+		return err
+	}
+	if err := r.injectLibraryPanels(ctx, grafanaDashboard, model); err != nil {
+		return err
+	}
+	sourceHash := hashModel(model)
+
+	blocked, err := r.lintDashboard(ctx, log, grafanaDashboard, model)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		log.Info("Dashboard breaks enforced lint rules, not syncing")
+		return nil
+	}
 
-		// Ensure the folder exists and get its UID
-		folderUID, err = grafanaClient.EnsureFolder(log, grafanaDashboard)
+	// Two dashboards sharing a UID, or a title in a folder, would overwrite each other in Grafana. The conflicts are
+	// found before anything is written to Grafana, in the folder the dashboard is synced to. A folder not created
+	// yet holds no dashboard, the title cannot be claimed in it.
+	dashboardUID := getDashboardUID(grafanaDashboard, model)
+	title, _ := model["title"].(string)
+	folderUID, folderExists, err := grafanaClient.FindFolder(ctx, log, grafanaDashboard.Spec.Folder, grafanaDashboard.Status.FolderUID, grafanaDashboard.Status.FolderCreated)
+	if err != nil {
+		return err
+	}
+	claimedTitle := ""
+	if folderExists {
+		claimedTitle = title
+	}
+	conflict, err := r.findClaimingDashboard(ctx, grafanaDashboard, dashboardUID, claimedTitle, folderUID)
+	if err != nil {
+		return err
+	}
+	adopted := false
+	if conflict == nil {
+		conflict, adopted, err = r.findUnmanagedDashboard(ctx, log, grafanaDashboard, dashboardUID, claimedTitle, folderUID)
 		if err != nil {
-			return ctrl.Result{}, err
+			return err
 		}
+	}
+	if err := r.setConflict(ctx, grafanaDashboard, conflict); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
+		return err
+	}
+	if conflict != nil {
+		log.Info("Dashboard conflicts with another one, not syncing", "dashboardUID", dashboardUID, "reason", conflict.message)
+		return nil
+	}
 
-		// Update the status with the folder UID if it's not already set
-		if grafanaDashboard.Status.FolderUID != folderUID {
-			grafanaDashboard.Status.FolderUID = folderUID
-			if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
-				log.Error(err, "Failed to update GrafanaDashboard status")
-				return ctrl.Result{}, err
-			}
+	// Ensure the folder exists and get its UID, the General folder has none
+	folderUID, folderCreated, err := grafanaClient.EnsureFolder(ctx, log, grafanaDashboard)
+	if err != nil {
+		return err
+	}
+	if _, ok := grafanaDashboard.Annotations[grafanav1beta1.RestoreVersionAnnotation]; ok {
+		return r.restoreDashboard(ctx, log, grafanaDashboard, dashboardUID)
+	}
+
+	// Every push saves a new version, the dashboard is only pushed when it changed on either side
+	var live *grafana.LiveDashboard
+	if grafanaDashboard.Status.Version != 0 && grafanaDashboard.Status.DashboardUID == dashboardUID {
+		live, err = grafanaClient.GetLiveDashboard(ctx, log, dashboardUID)
+		if err != nil && !grafana.IsNotFound(err) {
+			return err
 		}
 	}
-	dashboardUID, err := grafanaClient.UpsertDashboard(log, grafanaDashboard, folderUID)
-	if err != nil {
-		return ctrl.Result{}, err
+	// Some models cannot be written back into their source, their dashboard is only pushed and this is reported
+	bidirectional := grafanaDashboard.Spec.SyncMode == grafanav1beta1.SyncModeBidirectional
+	exportDisabled := ""
+	if bidirectional {
+		exportDisabled = exportDisabledReason(grafanaDashboard)
+	}
+	if live != nil && live.Version > grafanaDashboard.Status.Version && bidirectional && exportDisabled == "" {
+		exported, err := r.exportDashboard(ctx, log, grafanaDashboard, model, sourceHash, dashboardUID, live)
+		if err != nil || exported {
+			return err
+		}
+	}
+
+	version := grafanaDashboard.Status.Version
+	upToDate := live != nil && live.Version == grafanaDashboard.Status.Version &&
+		grafanaDashboard.Status.SourceHash == sourceHash && grafanaDashboard.Status.FolderUID == folderUID
+	if !upToDate {
+		model["uid"] = dashboardUID
+		dashboardUID, version, err = grafanaClient.UpsertDashboard(ctx, log, model, folderUID, grafana.OwnerTag(grafanaDashboard.Namespace, grafanaDashboard.Name), upsertMessage(grafanaDashboard))
+		if err != nil {
+			return err
+		}
+	}
+	if adopted {
+		r.Recorder.Event(grafanaDashboard, corev1.EventTypeNormal, "Adopted", "Dashboard already in Grafana taken over")
 	}
 
-	// Update the status with the dashboard UID if it's not already set
-	if grafanaDashboard.Status.DashboardUID != dashboardUID {
+	// The synced dashboard claims its UID and its title in the folder, the version and the hash of the source
+	// tell the later changes of Grafana and of the resource apart, the latest versions are listed when a new one was saved
+	changed := setEditConflict(grafanaDashboard, "")
+	if setExportDisabled(grafanaDashboard, exportDisabled) {
+		if exportDisabled != "" {
+			r.Recorder.Event(grafanaDashboard, corev1.EventTypeWarning, "ExportDisabled", exportDisabled)
+		}
+		changed = true
+	}
+	if grafanaDashboard.Status.Versions == nil || grafanaDashboard.Status.Version != version {
+		changed = r.refreshVersions(ctx, log, grafanaDashboard, dashboardUID) || changed
+	}
+	if changed || grafanaDashboard.Status.FolderUID != folderUID || grafanaDashboard.Status.FolderCreated != folderCreated || grafanaDashboard.Status.DashboardUID != dashboardUID || grafanaDashboard.Status.Title != title ||
+		grafanaDashboard.Status.Version != version || grafanaDashboard.Status.SourceHash != sourceHash {
+		grafanaDashboard.Status.FolderUID = folderUID
+		grafanaDashboard.Status.FolderCreated = folderCreated
 		grafanaDashboard.Status.DashboardUID = dashboardUID
+		grafanaDashboard.Status.Title = title
+		grafanaDashboard.Status.Version = version
+		grafanaDashboard.Status.SourceHash = sourceHash
 		if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
 			log.Error(err, "Failed to update GrafanaDashboard status")
-			return ctrl.Result{}, err
+			return err
 		}
 	}
 
-	// Requeue for periodic sync
-	syncPeriod := grafanaDashboard.Spec.SyncPeriod.Duration
-	return ctrl.Result{RequeueAfter: syncPeriod}, nil
+	return r.syncPermissions(ctx, log, grafanaDashboard, folderUID, dashboardUID)
+}
+
+// getDashboardModel returns the dashboard model, inline or read from the ConfigMap of the source
+func (r *GrafanaDashboardReconciler) getDashboardModel(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard) (map[string]interface{}, error) {
+	source := grafanaDashboard.Spec.Source
+
+	var raw []byte
+	switch {
+	case source.JSON != nil:
+		raw = source.JSON.Raw
+	case source.ConfigMapRef != nil:
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Name: source.ConfigMapRef.Name, Namespace: grafanaDashboard.Namespace}, configMap); err != nil {
+			return nil, fmt.Errorf("failed to get the ConfigMap of the dashboard model: %w", err)
+		}
+		data, ok := configMap.Data[source.ConfigMapRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %s has no key %s", source.ConfigMapRef.Name, source.ConfigMapRef.Key)
+		}
+		raw = []byte(data)
+	default:
+		return nil, fmt.Errorf("no dashboard model found")
+	}
+
+	var model map[string]interface{}
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse the dashboard model: %w", err)
+	}
+	return model, nil
+}
+
+// syncPermissions restores the permissions of the dashboard and its folder set on the resource
+func (r *GrafanaDashboardReconciler) syncPermissions(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, folderUID string, dashboardUID string) error {
+	if grafanaDashboard.Spec.Permissions == nil && grafanaDashboard.Spec.FolderPermissions == nil {
+		return nil
+	}
+	grafanaClient := grafana.FromContext(ctx)
+
+	// Teams must belong to the organization of the dashboard
+	orgID, err := getOrganizationID(ctx, r.Client, grafanaDashboard.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef, grafanaDashboard.Spec.OrgRef, grafanaDashboard.Spec.OrgID)
+	if err != nil {
+		return err
+	}
+
+	if grafanaDashboard.Spec.FolderPermissions != nil {
+		if folderUID == "" {
+			log.Info("Ignoring the folder permissions, the General folder has none")
+		} else {
+			permissions, err := resolvePermissions(ctx, log, r.Client, grafanaDashboard.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef, orgID, grafanaDashboard.Spec.FolderPermissions)
+			if err != nil {
+				return err
+			}
+			if err := grafanaClient.SyncFolderPermissions(ctx, log, folderUID, permissions); err != nil {
+				return err
+			}
+		}
+	}
+
+	if grafanaDashboard.Spec.Permissions != nil {
+		permissions, err := resolvePermissions(ctx, log, r.Client, grafanaDashboard.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef, orgID, grafanaDashboard.Spec.Permissions)
+		if err != nil {
+			return err
+		}
+		if err := grafanaClient.SyncDashboardPermissions(ctx, log, dashboardUID, permissions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexDashboardInstance returns the index key of the GrafanaInstance of a dashboard
+func indexDashboardInstance(obj client.Object) []string {
+	dashboard := obj.(*grafanav1beta1.GrafanaDashboard)
+	return []string{instanceIndexKey(dashboard.Spec.GrafanaInstanceRef)}
+}
+
+// indexDashboardConfigMap returns the index keys of the ConfigMaps holding the model or the variables of a dashboard
+func indexDashboardConfigMap(obj client.Object) []string {
+	dashboard := obj.(*grafanav1beta1.GrafanaDashboard)
+	var names []string
+	if dashboard.Spec.Source.ConfigMapRef != nil {
+		names = append(names, dashboard.Spec.Source.ConfigMapRef.Name)
+	}
+	for _, variable := range dashboard.Spec.Variables {
+		if variable.ValueFrom != nil && variable.ValueFrom.ConfigMapKeyRef != nil && !containsString(names, variable.ValueFrom.ConfigMapKeyRef.Name) {
+			names = append(names, variable.ValueFrom.ConfigMapKeyRef.Name)
+		}
+	}
+	return names
+}
+
+// indexDashboardSecret returns the index keys of the Secrets holding the variables of a dashboard
+func indexDashboardSecret(obj client.Object) []string {
+	dashboard := obj.(*grafanav1beta1.GrafanaDashboard)
+	var names []string
+	for _, variable := range dashboard.Spec.Variables {
+		if variable.ValueFrom != nil && variable.ValueFrom.SecretKeyRef != nil && !containsString(names, variable.ValueFrom.SecretKeyRef.Name) {
+			names = append(names, variable.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	return names
+}
+
+// requestsForInstance enqueues the dashboards of a GrafanaInstance
+func (r *GrafanaDashboardReconciler) requestsForInstance(obj client.Object) []reconcile.Request {
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	instanceRef := grafanav1beta1.GrafanaInstanceRef{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+	if err := r.List(context.Background(), dashboards, client.MatchingFields{dashboardInstanceIndex: instanceIndexKey(instanceRef)}); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a GrafanaInstance", "GrafanaInstance", instanceIndexKey(instanceRef))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dashboards.Items))
+	for _, dashboard := range dashboards.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+	}
+	return requests
+}
+
+// requestsForSecret enqueues the dashboards of the GrafanaInstances whose clients are created from the Secret,
+// and the dashboards whose variables are read from it
+func (r *GrafanaDashboardReconciler) requestsForSecret(obj client.Object) []reconcile.Request {
+	instances := &grafanav1beta1.GrafanaInstanceList{}
+	if err := r.List(context.Background(), instances, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list the GrafanaInstances of a Secret", "Secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(context.Background(), dashboards, client.InNamespace(obj.GetNamespace()), client.MatchingFields{dashboardSecretIndex: obj.GetName()}); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a Secret", "Secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, dashboard := range dashboards.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+	}
+	for i := range instances.Items {
+		if containsString(instanceSecretNames(&instances.Items[i]), obj.GetName()) {
+			requests = append(requests, r.requestsForInstance(&instances.Items[i])...)
+		}
+	}
+	return requests
+}
+
+// requestsForConfigMap enqueues the dashboards whose model or variables are held by the ConfigMap
+func (r *GrafanaDashboardReconciler) requestsForConfigMap(obj client.Object) []reconcile.Request {
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(context.Background(), dashboards, client.InNamespace(obj.GetNamespace()), client.MatchingFields{dashboardConfigMapIndex: obj.GetName()}); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a ConfigMap", "ConfigMap", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dashboards.Items))
+	for _, dashboard := range dashboards.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+	}
+	return requests
+}
+
+// instanceChanged filters the updates of a GrafanaInstance changing how its dashboards reach Grafana
+func instanceChanged(e event.UpdateEvent) bool {
+	oldInstance, okOld := e.ObjectOld.(*grafanav1beta1.GrafanaInstance)
+	newInstance, okNew := e.ObjectNew.(*grafanav1beta1.GrafanaInstance)
+	if !okOld || !okNew {
+		return false
+	}
+	return oldInstance.Generation != newInstance.Generation ||
+		oldInstance.Status.GrafanaUI.ServiceURL != newInstance.Status.GrafanaUI.ServiceURL ||
+		oldInstance.Status.GrafanaUI.AvailableReplicas != newInstance.Status.GrafanaUI.AvailableReplicas ||
+		oldInstance.Status.GrafanaUI.Replicas != newInstance.Status.GrafanaUI.Replicas ||
+		oldInstance.Status.OperatorServiceAccount.TokenSecretName != newInstance.Status.OperatorServiceAccount.TokenSecretName
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GrafanaDashboardReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &grafanav1beta1.GrafanaDashboard{}, dashboardInstanceIndex, indexDashboardInstance); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &grafanav1beta1.GrafanaDashboard{}, dashboardConfigMapIndex, indexDashboardConfigMap); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &grafanav1beta1.GrafanaDashboard{}, dashboardSecretIndex, indexDashboardSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &grafanav1beta1.GrafanaDashboard{}, dashboardLibraryPanelIndex, indexDashboardLibraryPanels); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&grafanav1alpha1.GrafanaDashboard{}).
+		For(&grafanav1beta1.GrafanaDashboard{}).
+		// Dashboards are synced as soon as their instance is ready or reachable differently
+		Watches(
+			&source.Kind{Type: &grafanav1beta1.GrafanaInstance{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForInstance),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: instanceChanged}),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForSecret),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForConfigMap),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		// The dashboards are synced as soon as the library panels they use are created in Grafana
+		Watches(
+			&source.Kind{Type: &grafanav1beta1.GrafanaLibraryPanel{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForLibraryPanel),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: libraryPanelSynced}),
+		).
+		// The dashboards are checked again as soon as the lint policies of their namespace change
+		Watches(
+			&source.Kind{Type: &grafanav1beta1.GrafanaLintPolicy{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForLintPolicy),
+		).
+		// The dashboards in conflict are synced as soon as the dashboard claiming their UID or title is deleted
+		Watches(
+			&source.Kind{Type: &grafanav1beta1.GrafanaDashboard{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForConflicts),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return false },
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
 		Complete(r)
 }
 
@@ -0,0 +1,600 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+var _ = Describe("GrafanaDashboardReconciler", func() {
+	var (
+		ctx       context.Context
+		dashboard *grafanav1beta1.GrafanaDashboard
+		objects   []client.Object
+		recorder  *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dashboard = &grafanav1beta1.GrafanaDashboard{
+			ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"},
+			Spec: grafanav1beta1.GrafanaDashboardSpec{
+				GrafanaInstanceRef: grafanav1beta1.GrafanaInstanceRef{Name: "gone", Namespace: "default"},
+			},
+			Status: grafanav1beta1.GrafanaDashboardStatus{DashboardUID: "uid"},
+		}
+		objects = nil
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	// newReconciler returns a reconciler whose client holds the dashboard and the objects
+	newReconciler := func() *GrafanaDashboardReconciler {
+		return &GrafanaDashboardReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(append(objects, dashboard)...).
+				WithIndex(&grafanav1beta1.GrafanaDashboard{}, dashboardInstanceIndex, indexDashboardInstance).
+				WithIndex(&grafanav1beta1.GrafanaDashboard{}, dashboardConfigMapIndex, indexDashboardConfigMap).
+				WithIndex(&grafanav1beta1.GrafanaDashboard{}, dashboardSecretIndex, indexDashboardSecret).
+				WithIndex(&grafanav1beta1.GrafanaDashboard{}, dashboardLibraryPanelIndex, indexDashboardLibraryPanels).
+				Build(),
+			Scheme:          scheme.Scheme,
+			Clients:         grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0),
+			Recorder:        recorder,
+			DeletionTimeout: 10 * time.Minute,
+		}
+	}
+
+	// withGrafana sends the requests of the Grafana client in the context to the handler
+	withGrafana := func(handler http.HandlerFunc) {
+		grafanaServer := httptest.NewServer(handler)
+		DeferCleanup(grafanaServer.Close)
+		grafanaClient, err := grafana.NewClient(grafanaServer.URL, grafana.DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+		ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+	}
+
+	getDashboard := func(r *GrafanaDashboardReconciler) *grafanav1beta1.GrafanaDashboard {
+		updated := &grafanav1beta1.GrafanaDashboard{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(dashboard), updated)).To(Succeed())
+		return updated
+	}
+
+	Context("when the instance is gone", func() {
+		DescribeTable("handles the deletion of the dashboard",
+			func(deletedSince time.Duration, policy grafanav1beta1.DeletionPolicy, defaultPolicy grafanav1beta1.DeletionPolicy, released bool, event string) {
+				dashboard.Finalizers = []string{grafanaDashboardFinalizer}
+				dashboard.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedSince)}
+				dashboard.Spec.DeletionPolicy = policy
+				r := newReconciler()
+				r.DefaultDeletionPolicy = defaultPolicy
+
+				result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(dashboard)})
+				Expect(err).NotTo(HaveOccurred())
+
+				// Without its finalizer, the deleted dashboard is gone
+				err = r.Get(ctx, client.ObjectKeyFromObject(dashboard), &grafanav1beta1.GrafanaDashboard{})
+				if released {
+					Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).NotTo(BeZero(), "the deletion is retried")
+				}
+				Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			},
+			Entry("retried within the timeout", time.Minute, grafanav1beta1.DeletionPolicyDelete, grafanav1beta1.DeletionPolicy(""), false, "DeletionFailed"),
+			Entry("released after the timeout", time.Hour, grafanav1beta1.DeletionPolicyDelete, grafanav1beta1.DeletionPolicy(""), true, "DeletionAbandoned"),
+			Entry("released when retained", time.Minute, grafanav1beta1.DeletionPolicyRetain, grafanav1beta1.DeletionPolicy(""), true, "Retained"),
+			Entry("released when retained by default", time.Minute, grafanav1beta1.DeletionPolicy(""), grafanav1beta1.DeletionPolicyRetain, true, "Retained"),
+			Entry("retried when deleted despite the default", time.Minute, grafanav1beta1.DeletionPolicyDelete, grafanav1beta1.DeletionPolicyRetain, false, "DeletionFailed"),
+		)
+
+		It("waits for the instance with the InstanceGone condition", func() {
+			r := newReconciler()
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(dashboard)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			Expect(meta.IsStatusConditionTrue(getDashboard(r).Status.Conditions, grafanav1beta1.ConditionInstanceGone)).To(BeTrue())
+		})
+	})
+
+	Describe("requestsForSecret", func() {
+		var r *GrafanaDashboardReconciler
+
+		BeforeEach(func() {
+			dashboard.Spec.GrafanaInstanceRef = grafanav1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "monitoring"}
+			other := dashboard.DeepCopy()
+			other.Name = "other"
+			other.Namespace = "monitoring"
+			other.Spec.Variables = []grafanav1beta1.DashboardVariable{{
+				Name: "datasource",
+				ValueFrom: &grafanav1beta1.DashboardVariableSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "datasources"},
+					Key:                  "prometheus",
+				}},
+			}}
+			objects = []client.Object{
+				&grafanav1beta1.GrafanaInstance{
+					ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"},
+					Spec:       grafanav1beta1.GrafanaInstanceSpec{CredentialsSecretName: "grafana-admin"},
+				},
+				other,
+			}
+			r = newReconciler()
+		})
+
+		It("enqueues the dashboards of the instance for its credentials", func() {
+			credentials := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "grafana-admin", Namespace: "monitoring"}}
+			Expect(r.requestsForSecret(credentials)).To(HaveLen(2))
+		})
+
+		It("enqueues the dashboard reading a variable from the Secret", func() {
+			variables := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "datasources", Namespace: "monitoring"}}
+			requests := r.requestsForSecret(variables)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal("other"))
+		})
+
+		It("enqueues nothing for an unrelated Secret", func() {
+			unrelated := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "monitoring"}}
+			Expect(r.requestsForSecret(unrelated)).To(BeEmpty())
+		})
+	})
+
+	Describe("conflicts", func() {
+		var (
+			newcomer *grafanav1beta1.GrafanaDashboard
+			otherOrg *grafanav1beta1.GrafanaDashboard
+			r        *GrafanaDashboardReconciler
+		)
+
+		BeforeEach(func() {
+			dashboard.Name = "synced"
+			dashboard.CreationTimestamp = metav1.NewTime(time.Now())
+			dashboard.Status.DashboardUID = "shared"
+			dashboard.Status.Title = "Shared"
+			// The newcomer is older but the UID already belongs to the synced dashboard
+			newcomer = dashboard.DeepCopy()
+			newcomer.Name = "newcomer"
+			newcomer.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+			newcomer.Status = grafanav1beta1.GrafanaDashboardStatus{}
+			newcomer.Spec.UID = "shared"
+			otherOrg = newcomer.DeepCopy()
+			otherOrg.Name = "other-org"
+			otherOrg.Spec.OrgID = 2
+			objects = []client.Object{newcomer, otherOrg}
+			r = newReconciler()
+		})
+
+		It("finds the dashboard claiming the UID or the title", func() {
+			conflict, err := r.findClaimingDashboard(ctx, newcomer, "shared", "Newcomer", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflict).NotTo(BeNil())
+			Expect(conflict.claimedBy.Name).To(Equal(dashboard.Name))
+
+			conflict, err = r.findClaimingDashboard(ctx, newcomer, "other", "shared", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflict).NotTo(BeNil(), "the title is claimed by the synced dashboard")
+		})
+
+		DescribeTable("finds no conflict",
+			func(name string, uid string, title string, folderUID string) {
+				claiming := map[string]*grafanav1beta1.GrafanaDashboard{"newcomer": newcomer, "synced": dashboard, "other-org": otherOrg}[name]
+				conflict, err := r.findClaimingDashboard(ctx, claiming, uid, title, folderUID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conflict).To(BeNil())
+			},
+			Entry("for the same title in another folder", "newcomer", "other", "Shared", "folder"),
+			Entry("for the synced dashboard keeping its UID", "synced", "shared", "Shared", ""),
+			Entry("for the same UID in another organization", "other-org", "shared", "Shared", ""),
+		)
+
+		It("records the conflict until it is resolved", func() {
+			conflict, err := r.findClaimingDashboard(ctx, newcomer, "shared", "Newcomer", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.setConflict(ctx, newcomer, conflict)).To(Succeed())
+			updated := &grafanav1beta1.GrafanaDashboard{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(newcomer), updated)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionConflict)).To(BeTrue())
+
+			// The newcomer is enqueued once the synced dashboard is deleted
+			requests := r.requestsForConflicts(dashboard)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal(newcomer.Name))
+
+			Expect(r.setConflict(ctx, updated, nil)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionConflict)).To(BeFalse())
+		})
+
+		It("writes nothing to Grafana for a dashboard in conflict", func() {
+			var writes []string
+			withGrafana(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					writes = append(writes, r.Method+" "+r.URL.Path)
+				}
+				_, _ = w.Write([]byte(`[]`))
+			})
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(newcomer), newcomer)).To(Succeed())
+			newcomer.Spec.Folder = "Team"
+			newcomer.Spec.Source.JSON = &apiextensionsv1.JSON{Raw: []byte(`{"title": "Newcomer"}`)}
+
+			Expect(r.syncDashboard(ctx, logr.Discard(), newcomer)).To(Succeed())
+			Expect(writes).To(BeEmpty(), "the folder is not created")
+			updated := &grafanav1beta1.GrafanaDashboard{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(newcomer), updated)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionConflict)).To(BeTrue())
+		})
+	})
+
+	Describe("findUnmanagedDashboard", func() {
+		BeforeEach(func() {
+			dashboard.Name = "overview"
+			withGrafana(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/dashboards/uid/left-behind":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": map[string]interface{}{"uid": "left-behind", "title": "Overview", "tags": []string{grafana.ManagedTag, grafana.OwnerTag("default", "overview")}},
+					})
+				case "/api/dashboards/uid/other-owner":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": map[string]interface{}{"uid": "other-owner", "title": "Overview", "tags": []string{grafana.ManagedTag, grafana.OwnerTag("other", "overview")}},
+					})
+				case "/api/dashboards/uid/hand-built":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": map[string]interface{}{"uid": "hand-built", "title": "Overview"},
+					})
+				case "/api/search":
+					_ = json.NewEncoder(w).Encode([]map[string]interface{}{})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+		})
+
+		DescribeTable("refuses or adopts the dashboards it does not manage",
+			func(uid string, adopt bool, conflicting bool, wantAdopted bool) {
+				dashboard.Spec.Adopt = adopt
+				conflict, adopted, err := newReconciler().findUnmanagedDashboard(ctx, logr.Discard(), dashboard, uid, "Overview", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conflict != nil).To(Equal(conflicting))
+				Expect(adopted).To(Equal(wantAdopted))
+			},
+			Entry("new dashboard", "new", false, false, false),
+			Entry("left behind by the resource", "left-behind", false, false, false),
+			Entry("managed by another resource", "other-owner", false, true, false),
+			Entry("adopted from another resource", "other-owner", true, false, true),
+			Entry("not managed", "hand-built", false, true, false),
+			Entry("adopted", "hand-built", true, false, true),
+		)
+	})
+
+	Describe("exportDashboard", func() {
+		var (
+			synced map[string]interface{}
+			live   *grafana.LiveDashboard
+		)
+
+		BeforeEach(func() {
+			withGrafana(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"version": 4, "createdBy": "jane", "message": "", "created": "2023-05-01T12:00:00Z"},
+				})
+			})
+			synced = map[string]interface{}{"title": "Overview", "uid": "overview"}
+			live = &grafana.LiveDashboard{
+				Model:     map[string]interface{}{"title": "Overview", "uid": "overview", "panels": []interface{}{}},
+				Version:   4,
+				UpdatedBy: "jane",
+				Updated:   time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+			}
+			dashboard.Spec.SyncMode = grafanav1beta1.SyncModeBidirectional
+			dashboard.Status = grafanav1beta1.GrafanaDashboardStatus{DashboardUID: "overview", Version: 3, SourceHash: hashModel(synced)}
+		})
+
+		// export runs the export with the source of the dashboard
+		export := func(source map[string]interface{}) (*GrafanaDashboardReconciler, bool) {
+			raw, err := json.Marshal(source)
+			Expect(err).NotTo(HaveOccurred())
+			dashboard.Spec.Source.JSON = &apiextensionsv1.JSON{Raw: raw}
+			r := newReconciler()
+			stopped, err := r.exportDashboard(ctx, logr.Discard(), dashboard, source, hashModel(source), "overview", live)
+			Expect(err).NotTo(HaveOccurred())
+			return r, stopped
+		}
+
+		It("writes back the dashboard changed in Grafana", func() {
+			r, stopped := export(synced)
+			Expect(stopped).To(BeTrue())
+
+			updated := getDashboard(r)
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionEditConflict)).To(BeFalse())
+			var model map[string]interface{}
+			Expect(json.Unmarshal(updated.Spec.Source.JSON.Raw, &model)).To(Succeed())
+			Expect(sameModel(model, live.Model, "overview")).To(BeTrue(), "the live model is written back, got %v", model)
+			Expect(updated.Status.LastExport).NotTo(BeNil())
+			Expect(updated.Status.LastExport.Version).To(BeEquivalentTo(4))
+			Expect(updated.Status.LastExport.UpdatedBy).To(Equal("jane"))
+			Expect(updated.Status.Version).To(BeEquivalentTo(4))
+			Expect(updated.Status.Versions).To(HaveLen(1))
+			Expect(updated.Status.Versions[0].Version).To(BeEquivalentTo(4))
+		})
+
+		It("stops with the EditConflict condition when changed on both sides", func() {
+			r, stopped := export(map[string]interface{}{"title": "Renamed", "uid": "overview"})
+			Expect(stopped).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(getDashboard(r).Status.Conditions, grafanav1beta1.ConditionEditConflict)).To(BeTrue())
+		})
+
+		It("goes on with the push once written back", func() {
+			r, stopped := export(live.Model)
+			Expect(stopped).To(BeFalse())
+			Expect(meta.IsStatusConditionTrue(getDashboard(r).Status.Conditions, grafanav1beta1.ConditionEditConflict)).To(BeFalse())
+		})
+	})
+
+	Describe("restoreDashboard", func() {
+		var (
+			restored map[string]interface{}
+			raw      []byte
+		)
+
+		BeforeEach(func() {
+			restored = map[string]interface{}{"title": "Overview", "uid": "overview", "panels": []interface{}{}}
+			withGrafana(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/dashboards/uid/overview/restore":
+					var body struct {
+						Version int64 `json:"version"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					if body.Version != 2 {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"uid": "overview", "version": 6})
+				case "/api/dashboards/uid/overview":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": restored,
+						"meta":      map[string]interface{}{"version": 6, "updatedBy": "admin"},
+					})
+				case "/api/dashboards/uid/overview/versions":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"versions": []map[string]interface{}{
+						{"version": 6, "createdBy": "admin", "message": "Restored from version 2", "created": "2023-05-02T12:00:00Z"},
+						{"version": 5, "createdBy": "admin", "message": "Synced", "created": "2023-05-01T12:00:00Z"},
+					}})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			synced := map[string]interface{}{"title": "Overview", "uid": "overview"}
+			var err error
+			raw, err = json.Marshal(synced)
+			Expect(err).NotTo(HaveOccurred())
+			dashboard.Spec.Source.JSON = &apiextensionsv1.JSON{Raw: raw}
+			dashboard.Status = grafanav1beta1.GrafanaDashboardStatus{DashboardUID: "overview", Version: 5, SourceHash: hashModel(synced)}
+		})
+
+		// restore restores the version of the annotation, returning the updated dashboard
+		restore := func(version string) *grafanav1beta1.GrafanaDashboard {
+			dashboard.Annotations = map[string]string{grafanav1beta1.RestoreVersionAnnotation: version}
+			r := newReconciler()
+			Expect(r.restoreDashboard(ctx, logr.Discard(), dashboard, "overview")).To(Succeed())
+			updated := getDashboard(r)
+			Expect(updated.Annotations).NotTo(HaveKey(grafanav1beta1.RestoreVersionAnnotation))
+			return updated
+		}
+
+		It("writes back the restored model", func() {
+			updated := restore("2")
+			var model map[string]interface{}
+			Expect(json.Unmarshal(updated.Spec.Source.JSON.Raw, &model)).To(Succeed())
+			Expect(model).To(Equal(restored))
+			Expect(updated.Status.Version).To(BeEquivalentTo(6))
+			Expect(updated.Status.SourceHash).To(Equal(hashModel(restored)))
+			Expect(updated.Status.Versions).To(HaveLen(2))
+		})
+
+		DescribeTable("leaves the source alone when the restore fails",
+			func(version string, variables []grafanav1beta1.DashboardVariable) {
+				dashboard.Spec.Variables = variables
+				updated := restore(version)
+				Expect(recorder.Events).To(Receive(ContainSubstring("RestoreFailed")))
+				Expect(updated.Spec.Source.JSON.Raw).To(Equal(raw))
+			},
+			Entry("missing version", "3", nil),
+			Entry("not a version", "latest", nil),
+			Entry("model with variables", "2", []grafanav1beta1.DashboardVariable{{Name: "cluster", Value: "prod"}}),
+		)
+	})
+
+	Describe("renderDashboardModel", func() {
+		var variables []grafanav1beta1.DashboardVariable
+
+		BeforeEach(func() {
+			objects = []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+					Data:       map[string]string{"cluster": "prod-eu", "threshold": "90"},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "datasources", Namespace: "default"},
+					Data:       map[string][]byte{"prometheus": []byte("P1809F7CD0C75ACF3")},
+				},
+			}
+			variables = []grafanav1beta1.DashboardVariable{
+				{Name: "env", Value: "prod", SetCurrent: true},
+				{Name: "cluster", ValueFrom: &grafanav1beta1.DashboardVariableSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "cluster",
+				}}},
+				{Name: "threshold", ValueFrom: &grafanav1beta1.DashboardVariableSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "threshold",
+				}}},
+				{Name: "datasource", ValueFrom: &grafanav1beta1.DashboardVariableSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "datasources"}, Key: "prometheus",
+				}}},
+			}
+		})
+
+		// render renders the model with the named variables
+		render := func(raw string, names []string) (map[string]interface{}, error) {
+			dashboard.Spec.Variables = nil
+			for _, variable := range variables {
+				for _, name := range names {
+					if variable.Name == name {
+						dashboard.Spec.Variables = append(dashboard.Spec.Variables, variable)
+					}
+				}
+			}
+			var model map[string]interface{}
+			Expect(json.Unmarshal([]byte(raw), &model)).To(Succeed())
+			return newReconciler().renderDashboardModel(ctx, dashboard, model)
+		}
+
+		DescribeTable("renders the model",
+			func(raw string, names []string, expected string) {
+				rendered, err := render(raw, names)
+				Expect(err).NotTo(HaveOccurred())
+				var expectedModel map[string]interface{}
+				Expect(json.Unmarshal([]byte(expected), &expectedModel)).To(Succeed())
+				Expect(rendered).To(Equal(expectedModel))
+			},
+			Entry("substituted",
+				`{"title": "Nodes $(cluster)", "panels": [{"datasource": {"uid": "$(datasource)"}, "thresholds": [{"value": "$(threshold)"}]}]}`,
+				[]string{"cluster", "threshold", "datasource"},
+				`{"title": "Nodes prod-eu", "panels": [{"datasource": {"uid": "P1809F7CD0C75ACF3"}, "thresholds": [{"value": 90}]}]}`,
+			),
+			Entry("escaped", `{"title": "Nodes $$(cluster) ${cluster}"}`, nil, `{"title": "Nodes $(cluster) ${cluster}"}`),
+			Entry("current value of a template variable",
+				`{"title": "Nodes", "templating": {"list": [{"name": "env", "type": "custom"}]}}`,
+				[]string{"env"},
+				`{"title": "Nodes", "templating": {"list": [{"name": "env", "type": "custom", "current": {"selected": true, "text": "prod", "value": "prod"}}]}}`,
+			),
+		)
+
+		DescribeTable("refuses the model",
+			func(raw string, names []string) {
+				_, err := render(raw, names)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("undefined variable", `{"title": "Nodes $(region)"}`, []string{"env", "cluster", "threshold", "datasource"}),
+			Entry("missing template variable", `{"title": "Nodes"}`, []string{"env"}),
+		)
+	})
+
+	Describe("lintDashboard", func() {
+		DescribeTable("records the violations of the lint policy of the namespace",
+			func(mode grafanav1beta1.LintMode, rules []grafanav1beta1.GrafanaLintRule, violations int, blocked bool) {
+				policy := &grafanav1beta1.GrafanaLintPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "team-rules", Namespace: dashboard.Namespace},
+					Spec:       grafanav1beta1.GrafanaLintPolicySpec{Mode: mode, Rules: rules},
+				}
+				other := policy.DeepCopy()
+				other.Namespace = "other"
+				other.Spec = grafanav1beta1.GrafanaLintPolicySpec{Mode: grafanav1beta1.LintModeEnforce, Rules: []grafanav1beta1.GrafanaLintRule{{Name: "unknown"}}}
+				objects = []client.Object{policy, other}
+				r := newReconciler()
+
+				model := map[string]interface{}{
+					"title":  "Nodes",
+					"panels": []interface{}{map[string]interface{}{"title": "CPU Usage", "type": "graph"}},
+				}
+				isBlocked, err := r.lintDashboard(ctx, logr.Discard(), dashboard, model)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isBlocked).To(Equal(blocked))
+
+				updated := getDashboard(r)
+				Expect(updated.Status.LintViolations).To(HaveLen(violations))
+				Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionLintFailed)).To(Equal(violations > 0))
+			},
+			Entry("passed", grafanav1beta1.LintMode(""), []grafanav1beta1.GrafanaLintRule{{Name: "datasource-uid"}}, 0, false),
+			Entry("warned", grafanav1beta1.LintMode(""), []grafanav1beta1.GrafanaLintRule{{Name: "deprecated-panels"}}, 1, false),
+			Entry("enforced", grafanav1beta1.LintModeEnforce, []grafanav1beta1.GrafanaLintRule{{Name: "deprecated-panels"}}, 1, true),
+			Entry("misconfigured", grafanav1beta1.LintModeEnforce, []grafanav1beta1.GrafanaLintRule{{Name: "required-tags"}}, 1, true),
+		)
+	})
+})
+
+var _ = Describe("getDashboardUID", func() {
+	var dashboard *grafanav1beta1.GrafanaDashboard
+
+	BeforeEach(func() {
+		dashboard = &grafanav1beta1.GrafanaDashboard{ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"}}
+	})
+
+	It("derives the UID from the resource", func() {
+		derived := getDashboardUID(dashboard, map[string]interface{}{})
+		Expect(derived).To(Equal(derivedDashboardUID("default", "dashboard")))
+		Expect(len(derived)).To(BeNumerically("<=", 40))
+		Expect(derivedDashboardUID("default", "other")).NotTo(Equal(derived))
+	})
+
+	It("prefers the UID of the spec, then of the model, then of the synced dashboard", func() {
+		dashboard.Status.DashboardUID = "synced"
+		Expect(getDashboardUID(dashboard, map[string]interface{}{})).To(Equal("synced"))
+		Expect(getDashboardUID(dashboard, map[string]interface{}{"uid": "model"})).To(Equal("model"))
+		dashboard.Spec.UID = "spec"
+		Expect(getDashboardUID(dashboard, map[string]interface{}{"uid": "model"})).To(Equal("spec"))
+	})
+})
+
+var _ = Describe("setExportDisabled", func() {
+	It("disables the export of a dashboard with variables", func() {
+		dashboard := &grafanav1beta1.GrafanaDashboard{}
+		Expect(exportDisabledReason(dashboard)).To(BeEmpty())
+		Expect(setExportDisabled(dashboard, "")).To(BeFalse(), "no condition until the export is disabled")
+
+		dashboard.Spec.Variables = []grafanav1beta1.DashboardVariable{{Name: "cluster", Value: "prod"}}
+		reason := exportDisabledReason(dashboard)
+		Expect(reason).NotTo(BeEmpty())
+		Expect(setExportDisabled(dashboard, reason)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(dashboard.Status.Conditions, grafanav1beta1.ConditionExportDisabled)).To(BeTrue())
+		Expect(setExportDisabled(dashboard, reason)).To(BeFalse(), "the condition is set once")
+		Expect(setExportDisabled(dashboard, "")).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(dashboard.Status.Conditions, grafanav1beta1.ConditionExportDisabled)).To(BeFalse())
+	})
+})
+
+var _ = Describe("upsertMessage", func() {
+	It("names the resource and the git commit", func() {
+		dashboard := &grafanav1beta1.GrafanaDashboard{}
+		dashboard.Namespace, dashboard.Name, dashboard.Generation = "monitoring", "overview", 3
+		Expect(upsertMessage(dashboard)).To(Equal("Synced from GrafanaDashboard monitoring/overview generation 3"))
+		dashboard.Annotations = map[string]string{grafanav1beta1.GitCommitAnnotation: "4f2a9c1"}
+		Expect(upsertMessage(dashboard)).To(HaveSuffix(", git commit 4f2a9c1"))
+	})
+})
+
+var _ = DescribeTable("getDeletionPolicy",
+	func(policy grafanav1beta1.DeletionPolicy, defaultPolicy grafanav1beta1.DeletionPolicy, expected grafanav1beta1.DeletionPolicy) {
+		dashboard := &grafanav1beta1.GrafanaDashboard{
+			ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"},
+			Spec:       grafanav1beta1.GrafanaDashboardSpec{DeletionPolicy: policy},
+		}
+		r := &GrafanaDashboardReconciler{DefaultDeletionPolicy: defaultPolicy}
+		Expect(r.getDeletionPolicy(dashboard)).To(Equal(expected))
+	},
+	Entry("deleted without any policy", grafanav1beta1.DeletionPolicy(""), grafanav1beta1.DeletionPolicy(""), grafanav1beta1.DeletionPolicyDelete),
+	Entry("default of the operator", grafanav1beta1.DeletionPolicy(""), grafanav1beta1.DeletionPolicyRetain, grafanav1beta1.DeletionPolicyRetain),
+	Entry("policy of the dashboard", grafanav1beta1.DeletionPolicyRetain, grafanav1beta1.DeletionPolicy(""), grafanav1beta1.DeletionPolicyRetain),
+	Entry("policy of the dashboard over the default", grafanav1beta1.DeletionPolicyDelete, grafanav1beta1.DeletionPolicyRetain, grafanav1beta1.DeletionPolicyDelete),
+)
@@ -0,0 +1,191 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// exportDashboard writes the changes made in Grafana since the last sync back into the source of the dashboard.
+// It reports whether the dashboard must not be pushed: its source was just updated, or both sides changed.
+func (r *GrafanaDashboardReconciler) exportDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}, sourceHash string, uid string, live *grafana.LiveDashboard) (bool, error) {
+	// The resource already holds the changes made in Grafana
+	if sameModel(model, live.Model, uid) {
+		return false, nil
+	}
+
+	if sourceHash != grafanaDashboard.Status.SourceHash {
+		message := fmt.Sprintf("Dashboard changed both in Grafana, version %d saved by %s, and in the resource", live.Version, live.UpdatedBy)
+		log.Info("Dashboard changed on both sides, not syncing", "version", live.Version, "updatedBy", live.UpdatedBy)
+		if !setEditConflict(grafanaDashboard, message) {
+			return true, nil
+		}
+		r.Recorder.Event(grafanaDashboard, corev1.EventTypeWarning, "EditConflict", message)
+		return true, r.Status().Update(ctx, grafanaDashboard)
+	}
+
+	// The source references the library panels by the names of their resources
+	source, err := r.extractLibraryPanels(ctx, grafanaDashboard, live.Model)
+	if err != nil {
+		return true, err
+	}
+	if err := r.writeSource(ctx, grafanaDashboard, source); err != nil {
+		return true, err
+	}
+	grafanaDashboard.Status.Version = live.Version
+	grafanaDashboard.Status.SourceHash = hashModel(live.Model)
+	grafanaDashboard.Status.LastExport = &grafanav1beta1.GrafanaDashboardExport{
+		Version:   live.Version,
+		UpdatedBy: live.UpdatedBy,
+		Updated:   metav1.NewTime(live.Updated),
+	}
+	setEditConflict(grafanaDashboard, "")
+	r.refreshVersions(ctx, log, grafanaDashboard, uid)
+	if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
+		return true, err
+	}
+	log.Info("Exported the changes made in Grafana", "version", live.Version, "updatedBy", live.UpdatedBy)
+	r.Recorder.Eventf(grafanaDashboard, corev1.EventTypeNormal, "Exported", "Dashboard version %d saved by %s in Grafana written back into the source", live.Version, live.UpdatedBy)
+	return true, nil
+}
+
+// writeSource replaces the model of the source of the dashboard, inline or in the ConfigMap
+func (r *GrafanaDashboardReconciler) writeSource(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) error {
+	source := grafanaDashboard.Spec.Source
+	if source.ConfigMapRef == nil {
+		raw, err := json.Marshal(model)
+		if err != nil {
+			return err
+		}
+		grafanaDashboard.Spec.Source.JSON = &apiextensionsv1.JSON{Raw: raw}
+		return r.updatePreservingStatus(ctx, grafanaDashboard)
+	}
+
+	raw, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Name: source.ConfigMapRef.Name, Namespace: grafanaDashboard.Namespace}, configMap); err != nil {
+		return fmt.Errorf("failed to get the ConfigMap of the dashboard model: %w", err)
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[source.ConfigMapRef.Key] = string(raw)
+	return r.Update(ctx, configMap)
+}
+
+// setEditConflict sets the EditConflict condition when a message is given, or resolves it.
+// It reports whether the condition changed, the caller updates the status.
+func setEditConflict(grafanaDashboard *grafanav1beta1.GrafanaDashboard, message string) bool {
+	current := meta.FindStatusCondition(grafanaDashboard.Status.Conditions, grafanav1beta1.ConditionEditConflict)
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionEditConflict,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ChangedOnBothSides",
+		Message:            message,
+		ObservedGeneration: grafanaDashboard.Generation,
+	}
+	if message == "" {
+		// The condition only shows up once both sides changed
+		if current == nil || current.Status == metav1.ConditionFalse {
+			return false
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Synced"
+	} else if current != nil && current.Status == metav1.ConditionTrue && current.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&grafanaDashboard.Status.Conditions, condition)
+	return true
+}
+
+// exportDisabledReason returns why the changes made in Grafana cannot be written back into the source of the dashboard, or nothing
+func exportDisabledReason(grafanaDashboard *grafanav1beta1.GrafanaDashboard) string {
+	if len(grafanaDashboard.Spec.Variables) > 0 {
+		return "Dashboard has variables, writing the model of Grafana back would replace their references with their values"
+	}
+	return ""
+}
+
+// setExportDisabled sets the ExportDisabled condition when a message is given, or resolves it.
+// It reports whether the condition changed, the caller updates the status.
+func setExportDisabled(grafanaDashboard *grafanav1beta1.GrafanaDashboard, message string) bool {
+	current := meta.FindStatusCondition(grafanaDashboard.Status.Conditions, grafanav1beta1.ConditionExportDisabled)
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionExportDisabled,
+		Status:             metav1.ConditionTrue,
+		Reason:             "SourceNotWritable",
+		Message:            message,
+		ObservedGeneration: grafanaDashboard.Generation,
+	}
+	if message == "" {
+		// The condition only shows up once the export was disabled
+		if current == nil || current.Status == metav1.ConditionFalse {
+			return false
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SourceWritable"
+	} else if current != nil && current.Status == metav1.ConditionTrue && current.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&grafanaDashboard.Status.Conditions, condition)
+	return true
+}
+
+// hashModel returns the hash of a dashboard model, the keys are encoded in order
+func hashModel(model map[string]interface{}) string {
+	raw, _ := json.Marshal(model)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// sameModel reports whether the model of the source matches the live model of Grafana,
+// leaving out what the operator adds when pushing it
+func sameModel(source map[string]interface{}, live map[string]interface{}, uid string) bool {
+	normalize := func(model map[string]interface{}) map[string]interface{} {
+		normalized := make(map[string]interface{}, len(model))
+		for key, value := range model {
+			normalized[key] = value
+		}
+		delete(normalized, "id")
+		delete(normalized, "version")
+		normalized["uid"] = uid
+		if tags, ok := normalized["tags"].([]interface{}); ok && len(tags) == 0 {
+			delete(normalized, "tags")
+		}
+		return normalized
+	}
+	return reflect.DeepEqual(normalize(source), normalize(live))
+}
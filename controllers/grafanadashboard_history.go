@@ -0,0 +1,140 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// dashboardVersionsLimit is the number of versions of the dashboard listed in its status
+const dashboardVersionsLimit = 10
+
+// upsertMessage returns the message the dashboard is saved with in Grafana, it tells which
+// generation of the resource, and which git commit when annotated, the version comes from
+func upsertMessage(grafanaDashboard *grafanav1beta1.GrafanaDashboard) string {
+	message := fmt.Sprintf("Synced from GrafanaDashboard %s/%s generation %d", grafanaDashboard.Namespace, grafanaDashboard.Name, grafanaDashboard.Generation)
+	if commit := grafanaDashboard.Annotations[grafanav1beta1.GitCommitAnnotation]; commit != "" {
+		message += ", git commit " + commit
+	}
+	return message
+}
+
+// restoreDashboard restores the version of the dashboard set in the restore annotation, then writes
+// the restored model back into the source of the dashboard so the next sync keeps it
+func (r *GrafanaDashboardReconciler) restoreDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, dashboardUID string) error {
+	grafanaClient := grafana.FromContext(ctx)
+	value := grafanaDashboard.Annotations[grafanav1beta1.RestoreVersionAnnotation]
+
+	version, err := strconv.ParseInt(value, 10, 64)
+	switch {
+	case err != nil || version < 1:
+		return r.failRestore(ctx, grafanaDashboard, fmt.Sprintf("Version to restore %q is not a positive number", value))
+	case grafanaDashboard.Status.DashboardUID != dashboardUID:
+		return r.failRestore(ctx, grafanaDashboard, "Dashboard was never synced to Grafana, there is no version to restore")
+	case exportDisabledReason(grafanaDashboard) != "":
+		// The restored model would leak the values of the variables, Secret ones included, into the source
+		return r.failRestore(ctx, grafanaDashboard, fmt.Sprintf("Version %d not restored: %s, update the source by hand", version, exportDisabledReason(grafanaDashboard)))
+	}
+
+	if err := grafanaClient.RestoreDashboardVersion(ctx, log, dashboardUID, version); err != nil {
+		if grafana.IsNotFound(err) {
+			return r.failRestore(ctx, grafanaDashboard, fmt.Sprintf("Version %d of the dashboard does not exist in Grafana", version))
+		}
+		return err
+	}
+	live, err := grafanaClient.GetLiveDashboard(ctx, log, dashboardUID)
+	if err != nil {
+		return err
+	}
+
+	delete(grafanaDashboard.Annotations, grafanav1beta1.RestoreVersionAnnotation)
+	// The source references the library panels by the names of their resources
+	source, err := r.extractLibraryPanels(ctx, grafanaDashboard, live.Model)
+	if err != nil {
+		return err
+	}
+	if err := r.writeSource(ctx, grafanaDashboard, source); err != nil {
+		return err
+	}
+	if grafanaDashboard.Spec.Source.ConfigMapRef != nil {
+		if err := r.updatePreservingStatus(ctx, grafanaDashboard); err != nil {
+			return err
+		}
+	}
+
+	grafanaDashboard.Status.Version = live.Version
+	grafanaDashboard.Status.SourceHash = hashModel(live.Model)
+	setEditConflict(grafanaDashboard, "")
+	r.refreshVersions(ctx, log, grafanaDashboard, dashboardUID)
+	if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
+		return err
+	}
+	log.Info("Restored dashboard version", "restoredVersion", version, "version", live.Version)
+	r.Recorder.Eventf(grafanaDashboard, corev1.EventTypeNormal, "Restored", "Dashboard version %d restored in Grafana as version %d", version, live.Version)
+	return nil
+}
+
+// failRestore reports a version that cannot be restored and removes the restore annotation,
+// the dashboard is synced as usual afterwards
+func (r *GrafanaDashboardReconciler) failRestore(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, message string) error {
+	r.Recorder.Event(grafanaDashboard, corev1.EventTypeWarning, "RestoreFailed", message)
+	delete(grafanaDashboard.Annotations, grafanav1beta1.RestoreVersionAnnotation)
+	return r.updatePreservingStatus(ctx, grafanaDashboard)
+}
+
+// updatePreservingStatus updates the dashboard, keeping the status the caller may still update afterwards
+func (r *GrafanaDashboardReconciler) updatePreservingStatus(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard) error {
+	status := grafanaDashboard.Status
+	err := r.Update(ctx, grafanaDashboard)
+	grafanaDashboard.Status = status
+	return err
+}
+
+// refreshVersions lists the latest versions of the dashboard into its status and reports whether they changed.
+// The versions are only informative, failing to list them does not fail the sync.
+func (r *GrafanaDashboardReconciler) refreshVersions(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, dashboardUID string) bool {
+	dashboardVersions, err := grafana.FromContext(ctx).ListDashboardVersions(ctx, log, dashboardUID, dashboardVersionsLimit)
+	if err != nil {
+		return false
+	}
+
+	var versions []grafanav1beta1.GrafanaDashboardVersion
+	for _, version := range dashboardVersions {
+		versions = append(versions, grafanav1beta1.GrafanaDashboardVersion{
+			Version:   version.Version,
+			CreatedBy: version.CreatedBy,
+			Message:   version.Message,
+			Created:   metav1.NewTime(version.Created),
+		})
+	}
+	if reflect.DeepEqual(versions, grafanaDashboard.Status.Versions) {
+		return false
+	}
+	grafanaDashboard.Status.Versions = versions
+	return true
+}
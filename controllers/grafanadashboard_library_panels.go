@@ -0,0 +1,172 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+)
+
+// dashboardLibraryPanelIndex indexes the dashboards by the GrafanaLibraryPanels their model uses
+const dashboardLibraryPanelIndex = "status.libraryPanels"
+
+// errLibraryPanelNotReady is returned while a GrafanaLibraryPanel used by a dashboard is not created in Grafana yet
+var errLibraryPanelNotReady = errors.New("library panel not created in Grafana yet")
+
+// libraryPanelRefs returns the names of the GrafanaLibraryPanels referenced by the panels of a model, sorted
+func libraryPanelRefs(model map[string]interface{}) []string {
+	var names []string
+	walkPanels(model, func(panel map[string]interface{}) {
+		if name, _ := panel[grafanav1beta1.LibraryPanelRefKey].(string); name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// setLibraryPanels records the GrafanaLibraryPanels the dashboard uses, before they are resolved
+// so none of them can be deleted meanwhile
+func (r *GrafanaDashboardReconciler) setLibraryPanels(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, names []string) error {
+	if reflect.DeepEqual(names, grafanaDashboard.Status.LibraryPanels) {
+		return nil
+	}
+	grafanaDashboard.Status.LibraryPanels = names
+	if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
+		return err
+	}
+	return nil
+}
+
+// injectLibraryPanels replaces the references to the GrafanaLibraryPanels of the namespace in the panels
+// of the model with references to their library panels in Grafana
+func (r *GrafanaDashboardReconciler) injectLibraryPanels(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) error {
+	libraryPanels := map[string]*grafanav1beta1.GrafanaLibraryPanel{}
+	for _, name := range libraryPanelRefs(model) {
+		libraryPanel := &grafanav1beta1.GrafanaLibraryPanel{}
+		err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: grafanaDashboard.Namespace}, libraryPanel)
+		if apierrors.IsNotFound(err) || err == nil && libraryPanel.Status.UID == "" {
+			return fmt.Errorf("%w: %s/%s", errLibraryPanelNotReady, grafanaDashboard.Namespace, name)
+		}
+		if err != nil {
+			return err
+		}
+		libraryPanel.SetDefaults()
+		if libraryPanel.Spec.GrafanaInstanceRef != grafanaDashboard.Spec.GrafanaInstanceRef {
+			return fmt.Errorf("GrafanaLibraryPanel %s/%s belongs to another GrafanaInstance than the dashboard", grafanaDashboard.Namespace, name)
+		}
+		libraryPanels[name] = libraryPanel
+	}
+
+	walkPanels(model, func(panel map[string]interface{}) {
+		name, _ := panel[grafanav1beta1.LibraryPanelRefKey].(string)
+		libraryPanel, ok := libraryPanels[name]
+		if !ok {
+			return
+		}
+		delete(panel, grafanav1beta1.LibraryPanelRefKey)
+		panel["libraryPanel"] = map[string]interface{}{"uid": libraryPanel.Status.UID, "name": libraryPanel.Status.Name}
+	})
+	return nil
+}
+
+// extractLibraryPanels returns a copy of a model of Grafana where the references to the library panels of the
+// GrafanaLibraryPanels of the namespace are replaced by their names again, so it can be written back into the source
+func (r *GrafanaDashboardReconciler) extractLibraryPanels(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) (map[string]interface{}, error) {
+	libraryPanels := &grafanav1beta1.GrafanaLibraryPanelList{}
+	if err := r.List(ctx, libraryPanels, client.InNamespace(grafanaDashboard.Namespace)); err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for i := range libraryPanels.Items {
+		libraryPanel := &libraryPanels.Items[i]
+		libraryPanel.SetDefaults()
+		if libraryPanel.Status.UID != "" && libraryPanel.Spec.GrafanaInstanceRef == grafanaDashboard.Spec.GrafanaInstanceRef {
+			names[libraryPanel.Status.UID] = libraryPanel.Name
+		}
+	}
+
+	extracted := runtime.DeepCopyJSON(model)
+	walkPanels(extracted, func(panel map[string]interface{}) {
+		reference, _ := panel["libraryPanel"].(map[string]interface{})
+		uid, _ := reference["uid"].(string)
+		// The library panels made in Grafana are kept as they are
+		name, ok := names[uid]
+		if !ok {
+			return
+		}
+		delete(panel, "libraryPanel")
+		panel[grafanav1beta1.LibraryPanelRefKey] = name
+	})
+	return extracted, nil
+}
+
+// walkPanels calls visit with every panel of a model, the ones nested in collapsed rows included
+func walkPanels(model map[string]interface{}, visit func(map[string]interface{})) {
+	panels, _ := model["panels"].([]interface{})
+	for _, item := range panels {
+		if panel, ok := item.(map[string]interface{}); ok {
+			visit(panel)
+			walkPanels(panel, visit)
+		}
+	}
+}
+
+// indexDashboardLibraryPanels returns the index keys of the GrafanaLibraryPanels a dashboard uses
+func indexDashboardLibraryPanels(obj client.Object) []string {
+	dashboard := obj.(*grafanav1beta1.GrafanaDashboard)
+	return dashboard.Status.LibraryPanels
+}
+
+// requestsForLibraryPanel enqueues the dashboards using the library panel
+func (r *GrafanaDashboardReconciler) requestsForLibraryPanel(obj client.Object) []reconcile.Request {
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(context.Background(), dashboards, client.InNamespace(obj.GetNamespace()), client.MatchingFields{dashboardLibraryPanelIndex: obj.GetName()}); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a GrafanaLibraryPanel", "GrafanaLibraryPanel", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dashboards.Items))
+	for _, dashboard := range dashboards.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+	}
+	return requests
+}
+
+// libraryPanelSynced filters the updates of a GrafanaLibraryPanel changing how dashboards reference it
+func libraryPanelSynced(e event.UpdateEvent) bool {
+	oldPanel, okOld := e.ObjectOld.(*grafanav1beta1.GrafanaLibraryPanel)
+	newPanel, okNew := e.ObjectNew.(*grafanav1beta1.GrafanaLibraryPanel)
+	if !okOld || !okNew {
+		return false
+	}
+	return oldPanel.Status.UID != newPanel.Status.UID || oldPanel.Status.Name != newPanel.Status.Name
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// lintDashboard checks the model against the rules of the lint policies of the namespace and records the broken
+// rules in the status. It reports whether an enforcing policy blocks the sync.
+func (r *GrafanaDashboardReconciler) lintDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) (bool, error) {
+	policies := &grafanav1beta1.GrafanaLintPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(grafanaDashboard.Namespace)); err != nil {
+		return false, err
+	}
+	sort.Slice(policies.Items, func(i, j int) bool { return policies.Items[i].Name < policies.Items[j].Name })
+
+	var violations []grafanav1beta1.GrafanaLintViolation
+	blocked := false
+	for _, policy := range policies.Items {
+		for _, ruleSpec := range policy.Spec.Rules {
+			var messages []string
+			// A misconfigured rule is reported like a broken one, so an enforcing policy never lets a dashboard through unchecked
+			rule, err := grafana.NewLintRule(ruleSpec.Name, ruleSpec.Params)
+			if err != nil {
+				messages = []string{err.Error()}
+			} else {
+				messages = rule.Check(model)
+			}
+			for _, message := range messages {
+				violations = append(violations, grafanav1beta1.GrafanaLintViolation{Policy: policy.Name, Rule: ruleSpec.Name, Message: message})
+			}
+			if len(messages) > 0 && policy.Spec.Mode == grafanav1beta1.LintModeEnforce {
+				blocked = true
+			}
+		}
+	}
+
+	if !setLintFailed(grafanaDashboard, violations, blocked) && reflect.DeepEqual(violations, grafanaDashboard.Status.LintViolations) {
+		return blocked, nil
+	}
+	grafanaDashboard.Status.LintViolations = violations
+	if err := r.Status().Update(ctx, grafanaDashboard); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboard status")
+		return blocked, err
+	}
+	if len(violations) > 0 {
+		log.Info("Dashboard breaks lint rules", "violations", len(violations), "blocked", blocked)
+		r.Recorder.Event(grafanaDashboard, corev1.EventTypeWarning, "LintFailed", lintMessage(violations, blocked))
+	}
+	return blocked, nil
+}
+
+// setLintFailed sets the LintFailed condition when rules are broken, or resolves it.
+// It reports whether the condition changed, the caller updates the status.
+func setLintFailed(grafanaDashboard *grafanav1beta1.GrafanaDashboard, violations []grafanav1beta1.GrafanaLintViolation, blocked bool) bool {
+	current := meta.FindStatusCondition(grafanaDashboard.Status.Conditions, grafanav1beta1.ConditionLintFailed)
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionLintFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Warned",
+		Message:            lintMessage(violations, blocked),
+		ObservedGeneration: grafanaDashboard.Generation,
+	}
+	switch {
+	case len(violations) == 0:
+		// The condition only shows up once a rule is broken
+		if current == nil || current.Status == metav1.ConditionFalse {
+			return false
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Passed"
+	case blocked:
+		condition.Reason = "Enforced"
+	}
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return false
+	}
+	meta.SetStatusCondition(&grafanaDashboard.Status.Conditions, condition)
+	return true
+}
+
+// lintMessage summarizes the broken rules, the details are in the status
+func lintMessage(violations []grafanav1beta1.GrafanaLintViolation, blocked bool) string {
+	switch {
+	case len(violations) == 0:
+		return "Dashboard follows the lint rules"
+	case blocked:
+		return fmt.Sprintf("Dashboard breaks %d lint rules and is not synced until it follows the enforced ones", len(violations))
+	}
+	return fmt.Sprintf("Dashboard breaks %d lint rules", len(violations))
+}
+
+// requestsForLintPolicy enqueues the dashboards of the namespace of the lint policy
+func (r *GrafanaDashboardReconciler) requestsForLintPolicy(obj client.Object) []reconcile.Request {
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(context.Background(), dashboards, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a GrafanaLintPolicy", "GrafanaLintPolicy", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dashboards.Items))
+	for _, dashboard := range dashboards.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+	}
+	return requests
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// dashboardConflict describes why a dashboard cannot be synced without overwriting another one
+type dashboardConflict struct {
+	reason  string
+	message string
+	// claimedBy is the resource managing the other dashboard, nil when no resource manages it
+	claimedBy *grafanav1beta1.GrafanaDashboard
+}
+
+// getDashboardUID returns the UID the dashboard is synced with: the one of the spec, else the one of the model.
+// Without any, the dashboard is given a UID derived from the resource, so it keeps its URL when the resource is recreated.
+func getDashboardUID(grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) string {
+	if grafanaDashboard.Spec.UID != "" {
+		return grafanaDashboard.Spec.UID
+	}
+	if uid, _ := model["uid"].(string); uid != "" {
+		return uid
+	}
+	// The dashboards synced before the UIDs were derived keep the one Grafana generated
+	if grafanaDashboard.Status.DashboardUID != "" {
+		return grafanaDashboard.Status.DashboardUID
+	}
+	return derivedDashboardUID(grafanaDashboard.Namespace, grafanaDashboard.Name)
+}
+
+// derivedDashboardUID returns a UID derived from the namespace and the name of a dashboard, Grafana accepts up to 40 characters
+func derivedDashboardUID(namespace string, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	return hex.EncodeToString(sum[:12])
+}
+
+// findClaimingDashboard returns the conflict with the dashboard of the same organization claiming the UID,
+// or the title in the folder, first. The resources of the instance in the cache are the registry of the claims:
+// a dashboard synced with the UID or the title claims it before the others, then the oldest one does.
+func (r *GrafanaDashboardReconciler) findClaimingDashboard(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, uid string, title string, folderUID string) (*dashboardConflict, error) {
+	orgID, err := getOrganizationID(ctx, r.Client, grafanaDashboard.Namespace, grafanaDashboard.Spec.GrafanaInstanceRef, grafanaDashboard.Spec.OrgRef, grafanaDashboard.Spec.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(ctx, dashboards, client.MatchingFields{dashboardInstanceIndex: instanceIndexKey(grafanaDashboard.Spec.GrafanaInstanceRef)}); err != nil {
+		return nil, err
+	}
+	for i := range dashboards.Items {
+		other := &dashboards.Items[i]
+		if other.Namespace == grafanaDashboard.Namespace && other.Name == grafanaDashboard.Name {
+			continue
+		}
+
+		var message string
+		switch {
+		case (other.Status.DashboardUID == uid || other.Spec.UID == uid) &&
+			claimsBefore(other, grafanaDashboard, other.Status.DashboardUID == uid, grafanaDashboard.Status.DashboardUID == uid):
+			message = fmt.Sprintf("UID %s is already claimed by GrafanaDashboard %s/%s", uid, other.Namespace, other.Name)
+		case claimsTitle(other, title, folderUID) &&
+			claimsBefore(other, grafanaDashboard, true, claimsTitle(grafanaDashboard, title, folderUID)):
+			message = fmt.Sprintf("Title %q is already claimed in the folder by GrafanaDashboard %s/%s", title, other.Namespace, other.Name)
+		default:
+			continue
+		}
+
+		// UIDs and titles are unique per organization, the organizations not created yet hold no dashboard
+		other.SetDefaults()
+		otherOrgID, err := getOrganizationID(ctx, r.Client, other.Namespace, other.Spec.GrafanaInstanceRef, other.Spec.OrgRef, other.Spec.OrgID)
+		if err != nil || otherOrgID != orgID {
+			continue
+		}
+		return &dashboardConflict{reason: "ClaimedByResource", message: message, claimedBy: other}, nil
+	}
+	return nil, nil
+}
+
+// claimsTitle reports whether the dashboard was synced with the title in the folder
+func claimsTitle(grafanaDashboard *grafanav1beta1.GrafanaDashboard, title string, folderUID string) bool {
+	return grafanaDashboard.Status.Title != "" && strings.EqualFold(grafanaDashboard.Status.Title, title) && grafanaDashboard.Status.FolderUID == folderUID
+}
+
+// claimsBefore reports whether the other dashboard claims before the dashboard, given which of them is already synced with the claim
+func claimsBefore(other *grafanav1beta1.GrafanaDashboard, grafanaDashboard *grafanav1beta1.GrafanaDashboard, otherSynced bool, synced bool) bool {
+	if otherSynced != synced {
+		return otherSynced
+	}
+	if !other.CreationTimestamp.Equal(&grafanaDashboard.CreationTimestamp) {
+		return other.CreationTimestamp.Before(&grafanaDashboard.CreationTimestamp)
+	}
+	return other.Namespace+"/"+other.Name < grafanaDashboard.Namespace+"/"+grafanaDashboard.Name
+}
+
+// findUnmanagedDashboard returns the conflict with a dashboard of Grafana the upsert would overwrite while this resource does not manage it.
+// A dashboard with the UID tagged with the owner tag of the resource was left behind when it was recreated and is taken over.
+// The other ones, including the ones managed by another operator or cluster, are only taken over when the dashboard opts in
+// for adoption, which is reported.
+func (r *GrafanaDashboardReconciler) findUnmanagedDashboard(ctx context.Context, log logr.Logger, grafanaDashboard *grafanav1beta1.GrafanaDashboard, uid string, title string, folderUID string) (*dashboardConflict, bool, error) {
+	existing, err := grafana.FromContext(ctx).FindDashboards(ctx, log, uid, title, folderUID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	owner := grafana.OwnerTag(grafanaDashboard.Namespace, grafanaDashboard.Name)
+	adopted := false
+	for _, dashboard := range existing {
+		if dashboard.UID == grafanaDashboard.Status.DashboardUID || (dashboard.UID == uid && dashboard.Owner == owner) {
+			continue
+		}
+		if grafanaDashboard.Spec.Adopt {
+			adopted = true
+			continue
+		}
+		if dashboard.Managed {
+			return &dashboardConflict{
+				reason:  "ManagedElsewhere",
+				message: fmt.Sprintf("Dashboard %q with UID %s already exists in Grafana and is managed by another resource, set adopt to take it over", dashboard.Title, dashboard.UID),
+			}, false, nil
+		}
+		return &dashboardConflict{
+			reason:  "Unmanaged",
+			message: fmt.Sprintf("Dashboard %q with UID %s already exists in Grafana and is not managed, set adopt to take it over", dashboard.Title, dashboard.UID),
+		}, false, nil
+	}
+	return nil, adopted, nil
+}
+
+// setConflict updates the Conflict condition of the dashboard. A dashboard losing to another resource forgets
+// the Grafana dashboard they were both synced to, it must not be deleted with this one.
+func (r *GrafanaDashboardReconciler) setConflict(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, conflict *dashboardConflict) error {
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionConflict,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoConflict",
+		ObservedGeneration: grafanaDashboard.Generation,
+	}
+	if conflict != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = conflict.reason
+		condition.Message = conflict.message
+	}
+
+	current := meta.FindStatusCondition(grafanaDashboard.Status.Conditions, grafanav1beta1.ConditionConflict)
+	// The condition only shows up once a conflict was found
+	if current == nil && conflict == nil {
+		return nil
+	}
+	released := conflict != nil && conflict.claimedBy != nil && grafanaDashboard.Status.DashboardUID != "" &&
+		grafanaDashboard.Status.DashboardUID == conflict.claimedBy.Status.DashboardUID
+	if !released && current != nil && current.Status == condition.Status && current.Message == condition.Message {
+		return nil
+	}
+	if released {
+		grafanaDashboard.Status.DashboardUID = ""
+		grafanaDashboard.Status.Title = ""
+	}
+	if conflict != nil {
+		r.Recorder.Event(grafanaDashboard, corev1.EventTypeWarning, "Conflict", conflict.message)
+	}
+	meta.SetStatusCondition(&grafanaDashboard.Status.Conditions, condition)
+	return r.Status().Update(ctx, grafanaDashboard)
+}
+
+// requestsForConflicts enqueues the dashboards of the instance of a deleted dashboard which are in conflict
+func (r *GrafanaDashboardReconciler) requestsForConflicts(obj client.Object) []reconcile.Request {
+	deleted, ok := obj.(*grafanav1beta1.GrafanaDashboard)
+	if !ok {
+		return nil
+	}
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(context.Background(), dashboards, client.MatchingFields{dashboardInstanceIndex: instanceIndexKey(deleted.Spec.GrafanaInstanceRef)}); err != nil {
+		log.Log.Error(err, "Failed to list the dashboards of a GrafanaInstance", "GrafanaInstance", instanceIndexKey(deleted.Spec.GrafanaInstanceRef))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, dashboard := range dashboards.Items {
+		if meta.IsStatusConditionTrue(dashboard.Status.Conditions, grafanav1beta1.ConditionConflict) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/helpers"
+)
+
+// renderDashboardModel substitutes the variables of the dashboard into its model, and sets the current value
+// of the Grafana template variables they are flagged for
+func (r *GrafanaDashboardReconciler) renderDashboardModel(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard, model map[string]interface{}) (map[string]interface{}, error) {
+	values, err := r.getVariableValues(ctx, grafanaDashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, missing := helpers.ExpandVariables(model, values)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the dashboard model references undefined variables: %s", strings.Join(missing, ", "))
+	}
+	rendered := expanded.(map[string]interface{})
+	for _, variable := range grafanaDashboard.Spec.Variables {
+		if !variable.SetCurrent {
+			continue
+		}
+		if !setTemplateCurrent(rendered, variable.Name, values[variable.Name]) {
+			return nil, fmt.Errorf("the dashboard model has no template variable %s", variable.Name)
+		}
+	}
+	return rendered, nil
+}
+
+// getVariableValues returns the values of the variables of the dashboard, by name.
+// The variables read from a missing optional key are left out.
+func (r *GrafanaDashboardReconciler) getVariableValues(ctx context.Context, grafanaDashboard *grafanav1beta1.GrafanaDashboard) (map[string]string, error) {
+	values := make(map[string]string, len(grafanaDashboard.Spec.Variables))
+	for _, variable := range grafanaDashboard.Spec.Variables {
+		switch {
+		case variable.ValueFrom == nil:
+			values[variable.Name] = variable.Value
+		case variable.ValueFrom.ConfigMapKeyRef != nil:
+			ref := variable.ValueFrom.ConfigMapKeyRef
+			configMap := &corev1.ConfigMap{}
+			err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: grafanaDashboard.Namespace}, configMap)
+			if err != nil && !(isOptional(ref.Optional) && errors.IsNotFound(err)) {
+				return nil, fmt.Errorf("failed to get the ConfigMap of variable %s: %w", variable.Name, err)
+			}
+			if value, ok := configMap.Data[ref.Key]; ok {
+				values[variable.Name] = value
+			} else if !isOptional(ref.Optional) {
+				return nil, fmt.Errorf("ConfigMap %s of variable %s has no key %s", ref.Name, variable.Name, ref.Key)
+			}
+		case variable.ValueFrom.SecretKeyRef != nil:
+			ref := variable.ValueFrom.SecretKeyRef
+			secret := &corev1.Secret{}
+			err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: grafanaDashboard.Namespace}, secret)
+			if err != nil && !(isOptional(ref.Optional) && errors.IsNotFound(err)) {
+				return nil, fmt.Errorf("failed to get the Secret of variable %s: %w", variable.Name, err)
+			}
+			if value, ok := secret.Data[ref.Key]; ok {
+				values[variable.Name] = string(value)
+			} else if !isOptional(ref.Optional) {
+				return nil, fmt.Errorf("Secret %s of variable %s has no key %s", ref.Name, variable.Name, ref.Key)
+			}
+		}
+	}
+	return values, nil
+}
+
+// setTemplateCurrent sets the current value of a Grafana template variable of the model,
+// it reports whether the model has such a variable
+func setTemplateCurrent(model map[string]interface{}, name string, value string) bool {
+	templating, _ := model["templating"].(map[string]interface{})
+	list, _ := templating["list"].([]interface{})
+	found := false
+	for _, item := range list {
+		templateVariable, ok := item.(map[string]interface{})
+		if !ok || templateVariable["name"] != name {
+			continue
+		}
+		templateVariable["current"] = map[string]interface{}{"selected": true, "text": value, "value": value}
+		found = true
+	}
+	return found
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// GrafanaDashboardImportReconciler reconciles a GrafanaDashboardImport object
+type GrafanaDashboardImportReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Clients *grafana.ClientRegistry
+}
+
+// invalidNameCharacters matches the characters of a title not allowed in the name of a resource
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboardimports,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboardimports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile imports the dashboards of Grafana matching the filters of the import, once per generation of its spec.
+func (r *GrafanaDashboardImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("GrafanaDashboardImportController").WithValues("GrafanaDashboardImport", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.Info("Starting reconciliation")
+
+	dashboardImport := &grafanav1beta1.GrafanaDashboardImport{}
+	if err := r.Get(ctx, req.NamespacedName, dashboardImport); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaDashboardImport resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get GrafanaDashboardImport")
+		return ctrl.Result{}, err
+	}
+	if dashboardImport.Spec.GrafanaInstanceRef.Namespace == "" {
+		dashboardImport.Spec.GrafanaInstanceRef.Namespace = dashboardImport.Namespace
+	}
+
+	// The import runs once, the dashboards are then managed by their GrafanaDashboards
+	if dashboardImport.Status.ObservedGeneration == dashboardImport.Generation &&
+		meta.IsStatusConditionTrue(dashboardImport.Status.Conditions, grafanav1beta1.ConditionImported) {
+		return ctrl.Result{}, nil
+	}
+
+	instanceRef := dashboardImport.Spec.GrafanaInstanceRef
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: instanceRef.Name, Namespace: instanceRef.Namespace}, instance)
+	if err == nil {
+		var grafanaClient *grafana.GrafanaClient
+		grafanaClient, err = getOrganizationClient(ctx, r.Client, r.Clients, instance, dashboardImport.Namespace, instanceRef, dashboardImport.Spec.OrgRef, dashboardImport.Spec.OrgID)
+		if err == nil {
+			ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+		}
+	}
+	if err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "Failed to get Grafana client")
+		return ctrl.Result{}, err
+	}
+
+	imported, err := r.importDashboards(ctx, log, dashboardImport)
+	condition := metav1.Condition{
+		Type:               grafanav1beta1.ConditionImported,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Imported",
+		Message:            fmt.Sprintf("%d dashboards imported", len(imported)),
+		ObservedGeneration: dashboardImport.Generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ImportFailed"
+		condition.Message = err.Error()
+	} else {
+		dashboardImport.Status.ObservedGeneration = dashboardImport.Generation
+		dashboardImport.Status.Dashboards = imported
+	}
+	meta.SetStatusCondition(&dashboardImport.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, dashboardImport); err != nil {
+		log.Error(err, "Failed to update GrafanaDashboardImport status")
+		return ctrl.Result{}, err
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Finished reconciliation", "dashboards", len(imported))
+	return ctrl.Result{}, nil
+}
+
+// importDashboards generates the GrafanaDashboards of the dashboards matching the filters, or their manifests in the ConfigMap.
+// The dashboards already managed by the operator are left out, the GrafanaDashboards already existing are not updated.
+func (r *GrafanaDashboardImportReconciler) importDashboards(ctx context.Context, log logr.Logger, dashboardImport *grafanav1beta1.GrafanaDashboardImport) ([]grafanav1beta1.ImportedDashboard, error) {
+	grafanaClient := grafana.FromContext(ctx)
+	existing, err := grafanaClient.ListDashboards(ctx, log, dashboardImport.Spec.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := []grafanav1beta1.ImportedDashboard{}
+	manifests := map[string]string{}
+	for _, dashboard := range existing {
+		if dashboard.Managed || !inFolders(dashboard, dashboardImport.Spec.Folders) {
+			continue
+		}
+		live, err := grafanaClient.GetLiveDashboard(ctx, log, dashboard.UID)
+		if err != nil {
+			return nil, err
+		}
+		grafanaDashboard, err := newImportedDashboard(dashboardImport, dashboard, live.Model)
+		if err != nil {
+			return nil, err
+		}
+		item := grafanav1beta1.ImportedDashboard{UID: dashboard.UID, Title: dashboard.Title, Name: grafanaDashboard.Name}
+
+		if dashboardImport.Spec.ConfigMapName != "" {
+			manifest, err := yaml.Marshal(grafanaDashboard)
+			if err != nil {
+				return nil, err
+			}
+			manifests[grafanaDashboard.Name+".yaml"] = string(manifest)
+		} else if err := r.Create(ctx, grafanaDashboard); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return nil, fmt.Errorf("failed to create the GrafanaDashboard of %s: %w", dashboard.UID, err)
+			}
+			item.Skipped = "a GrafanaDashboard with the name already exists"
+		}
+		imported = append(imported, item)
+	}
+
+	if dashboardImport.Spec.ConfigMapName != "" {
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: dashboardImport.Spec.ConfigMapName, Namespace: dashboardImport.Namespace}}
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+			configMap.Data = manifests
+			return controllerutil.SetControllerReference(dashboardImport, configMap, r.Scheme)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write the manifests to the ConfigMap: %w", err)
+		}
+	}
+	return imported, nil
+}
+
+// newImportedDashboard returns the GrafanaDashboard of a dashboard of Grafana, adopting it under its UID
+func newImportedDashboard(dashboardImport *grafanav1beta1.GrafanaDashboardImport, dashboard grafana.ExistingDashboard, model map[string]interface{}) (*grafanav1beta1.GrafanaDashboard, error) {
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the model of %s: %w", dashboard.UID, err)
+	}
+
+	grafanaDashboard := &grafanav1beta1.GrafanaDashboard{
+		TypeMeta: metav1.TypeMeta{APIVersion: grafanav1beta1.GroupVersion.String(), Kind: "GrafanaDashboard"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      importedDashboardName(dashboard.Title, dashboard.UID),
+			Namespace: dashboardImport.Namespace,
+			Labels:    map[string]string{grafanav1beta1.ImportedByLabel: dashboardImport.Name},
+		},
+		Spec: grafanav1beta1.GrafanaDashboardSpec{
+			Source:             grafanav1beta1.GrafanaDashboardSource{JSON: &apiextensionsv1.JSON{Raw: raw}},
+			UID:                dashboard.UID,
+			Adopt:              true,
+			GrafanaInstanceRef: dashboardImport.Spec.GrafanaInstanceRef,
+			OrgRef:             dashboardImport.Spec.OrgRef,
+			OrgID:              dashboardImport.Spec.OrgID,
+		},
+	}
+	if dashboard.FolderUID != "" {
+		grafanaDashboard.Spec.Folder = dashboard.FolderTitle
+	}
+	return grafanaDashboard, nil
+}
+
+// importedDashboardName returns the name of the GrafanaDashboard of a dashboard, from its title.
+// The suffix derived from the UID tells apart the dashboards sharing a title in different folders.
+func importedDashboardName(title string, uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	suffix := hex.EncodeToString(sum[:4])
+
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(name) > 48 {
+		name = strings.TrimRight(name[:48], "-")
+	}
+	if name == "" {
+		name = "dashboard"
+	}
+	return name + "-" + suffix
+}
+
+// inFolders reports whether the dashboard is in one of the folders, given by title. All the folders match when none is given.
+func inFolders(dashboard grafana.ExistingDashboard, folders []string) bool {
+	if len(folders) == 0 {
+		return true
+	}
+	for _, folder := range folders {
+		if dashboard.FolderUID == "" && grafana.IsGeneralFolder(folder) {
+			return true
+		}
+		if dashboard.FolderUID != "" && strings.EqualFold(dashboard.FolderTitle, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaDashboardImportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&grafanav1beta1.GrafanaDashboardImport{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+var _ = Describe("GrafanaDashboardImportReconciler", func() {
+	var (
+		ctx             context.Context
+		dashboardImport *grafanav1beta1.GrafanaDashboardImport
+		r               *GrafanaDashboardImportReconciler
+	)
+
+	BeforeEach(func() {
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/api/search":
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"uid": "ops", "title": "Ops Overview", "folderUid": "f1", "folderTitle": "Operations"},
+					{"uid": "home", "title": "Home"},
+					{"uid": "dev", "title": "Dev", "folderUid": "f2", "folderTitle": "Development"},
+					{"uid": "managed", "title": "Managed", "folderUid": "f1", "folderTitle": "Operations", "tags": []string{grafana.ManagedTag}},
+				})
+			case strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/"):
+				uid := strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"dashboard": map[string]interface{}{"id": 12, "version": 3, "uid": uid, "title": uid},
+				})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+		grafanaClient, err := grafana.NewClient(grafanaServer.URL, grafana.DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+		ctx = grafana.WithGrafanaClient(context.Background(), grafanaClient)
+
+		dashboardImport = &grafanav1beta1.GrafanaDashboardImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default", UID: "import-uid"},
+			Spec: grafanav1beta1.GrafanaDashboardImportSpec{
+				GrafanaInstanceRef: grafanav1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "default"},
+				Folders:            []string{"operations", "General"},
+			},
+		}
+		// A GrafanaDashboard already exists for the home dashboard
+		existing := &grafanav1beta1.GrafanaDashboard{ObjectMeta: metav1.ObjectMeta{Name: importedDashboardName("Home", "home"), Namespace: "default"}}
+		r = &GrafanaDashboardImportReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(dashboardImport, existing).Build(),
+			Scheme: scheme.Scheme,
+		}
+	})
+
+	It("creates the GrafanaDashboards adopting the dashboards of the folders", func() {
+		imported, err := r.importDashboards(ctx, logr.Discard(), dashboardImport)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imported).To(HaveLen(2))
+		Expect(imported[0].UID).To(Equal("ops"))
+		Expect(imported[1].UID).To(Equal("home"))
+		Expect(imported[1].Skipped).NotTo(BeEmpty(), "the home dashboard is skipped")
+
+		created := &grafanav1beta1.GrafanaDashboard{}
+		Expect(r.Get(ctx, client.ObjectKey{Name: imported[0].Name, Namespace: "default"}, created)).To(Succeed())
+		Expect(created.Spec.Adopt).To(BeTrue())
+		Expect(created.Spec.UID).To(Equal("ops"))
+		Expect(created.Spec.Folder).To(Equal("Operations"))
+		Expect(created.Labels).To(HaveKeyWithValue(grafanav1beta1.ImportedByLabel, "legacy"))
+		var model map[string]interface{}
+		Expect(json.Unmarshal(created.Spec.Source.JSON.Raw, &model)).To(Succeed())
+		Expect(model).NotTo(HaveKey("id"))
+	})
+
+	It("writes the manifests to the ConfigMap", func() {
+		dashboardImport.Spec.ConfigMapName = "legacy-dashboards"
+		imported, err := r.importDashboards(ctx, logr.Discard(), dashboardImport)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imported).To(HaveLen(2))
+
+		configMap := &corev1.ConfigMap{}
+		Expect(r.Get(ctx, client.ObjectKey{Name: "legacy-dashboards", Namespace: "default"}, configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue(imported[1].Name+".yaml", ContainSubstring("kind: GrafanaDashboard")))
+	})
+})
+
+var _ = Describe("importedDashboardName", func() {
+	It("derives the name from the title and the UID", func() {
+		name := importedDashboardName("Kubernetes / Compute Resources (Pod)", "abc")
+		Expect(name).To(HavePrefix("kubernetes-compute-resources-pod-"))
+		Expect(importedDashboardName("Kubernetes / Compute Resources (Pod)", "def")).NotTo(Equal(name))
+	})
+
+	It("falls back to a default name", func() {
+		Expect(importedDashboardName("???", "abc")).To(HavePrefix("dashboard-"))
+	})
+})
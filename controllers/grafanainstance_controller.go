@@ -18,16 +18,19 @@ package controllers
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	"github.com/minicali/grafana-operator/internal/reconcilers"
 )
@@ -35,7 +38,8 @@ import (
 // GrafanaInstanceReconciler reconciles a GrafanaInstance object
 type GrafanaInstanceReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme  *runtime.Scheme
+	Clients *grafana.ClientRegistry
 }
 
 type grafanaInstanceReconcileStages string
@@ -46,19 +50,31 @@ const (
 	GrafanaInstanceStageSecret     grafanaInstanceReconcileStages = "secret"
 	GrafanaInstanceStageDeployment grafanaInstanceReconcileStages = "deployment"
 	GrafanaInstanceStageService    grafanaInstanceReconcileStages = "service"
+
+	GrafanaInstanceStageCredentialsRotation grafanaInstanceReconcileStages = "credentials-rotation"
+	GrafanaInstanceStageServiceAccount      grafanaInstanceReconcileStages = "service-account"
+	GrafanaInstanceStageReprovision         grafanaInstanceReconcileStages = "reprovision"
 )
 
+// grafanaNotReadyRequeueDelay is the delay before retrying the stages talking to a Grafana API that is not reachable yet
+const grafanaNotReadyRequeueDelay = 10 * time.Second
+
 var reconcileStages = []grafanaInstanceReconcileStages{
 	GrafanaInstanceStageSecret,
 	GrafanaInstanceStageConfigMap,
 	GrafanaInstanceStagePVC,
 	GrafanaInstanceStageDeployment,
 	GrafanaInstanceStageService,
+	GrafanaInstanceStageCredentialsRotation,
+	GrafanaInstanceStageServiceAccount,
+	GrafanaInstanceStageReprovision,
 }
 
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances/finalizers,verbs=update
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards;grafanalibrarypanels;grafanaorganizations;grafanateams;grafanausers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards/status;grafanalibrarypanels/status;grafanaorganizations/status;grafanateams/status;grafanausers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
@@ -76,19 +92,33 @@ var reconcileStages = []grafanaInstanceReconcileStages{
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *GrafanaInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx).WithName("GrafanaInstanceController").WithValues("GrafanaInstance", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
 	log.Info("Starting reconciliation")
 
-	cr := &grafanav1alpha1.GrafanaInstance{}
+	cr := &grafanav1beta1.GrafanaInstance{}
 	if err := r.Get(ctx, req.NamespacedName, cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaInstance not found, dropping its Grafana client")
+			r.Clients.Invalidate(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "Failed to get GrafanaInstance")
 		return ctrl.Result{}, err
 	}
 
 	// Reconcile
+	grafanaReady := true
 	for _, stage := range reconcileStages {
 		log.Info("Reconciling stage", "Stage", stage)
 		stageReconciler := r.getReconcilerPerStage(stage)
 		if err := stageReconciler.Reconcile(ctx, cr, log); err != nil {
+			// The remaining stages need the Grafana API, update the status and retry later
+			if errors.Is(err, reconcilers.ErrGrafanaNotReady) {
+				log.Info("Grafana is not ready yet", "Stage", stage, "reason", err.Error())
+				grafanaReady = false
+				break
+			}
 			log.Error(err, "Failed to reconcile stage", "Stage", stage)
 			return ctrl.Result{}, err
 		}
@@ -112,7 +142,8 @@ func (r *GrafanaInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	}
 
 	// Update GrafanaInstanceStatus
-	cr.Status.GrafanaUI.AvailableReplicas = fmt.Sprintf("%d/%d", deployment.Status.AvailableReplicas, *deployment.Spec.Replicas)
+	cr.Status.GrafanaUI.Replicas = *deployment.Spec.Replicas
+	cr.Status.GrafanaUI.AvailableReplicas = deployment.Status.AvailableReplicas
 	cr.Status.GrafanaUI.Conditions = deployment.Status.Conditions
 	cr.Status.GrafanaUI.ServiceURL = helpers.GetServiceURL(service)
 
@@ -123,13 +154,47 @@ func (r *GrafanaInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	}
 
 	log.Info("Finished reconciliation")
-	return ctrl.Result{}, nil
+
+	if !grafanaReady {
+		return ctrl.Result{RequeueAfter: grafanaNotReadyRequeueDelay}, nil
+	}
+
+	// Follow the progress of the re-provisioning
+	if reprovision := cr.Status.Reprovision; reprovision != nil && reprovision.Phase != grafanav1beta1.ReprovisionPhaseCompleted {
+		return ctrl.Result{RequeueAfter: grafanaNotReadyRequeueDelay}, nil
+	}
+
+	// Requeue for the next scheduled credentials or token rotation
+	var nextRotation *time.Time
+	for _, next := range []func(*grafanav1beta1.GrafanaInstance) (time.Time, bool){
+		reconcilers.NextCredentialsRotation,
+		reconcilers.NextServiceAccountTokenRotation,
+	} {
+		if at, ok := next(cr); ok && (nextRotation == nil || at.Before(*nextRotation)) {
+			nextRotation = &at
+		}
+	}
+	if nextRotation == nil {
+		return ctrl.Result{}, nil
+	}
+	// A rotation already overdue is retried shortly
+	requeueAfter := time.Until(*nextRotation)
+	if requeueAfter <= 0 {
+		requeueAfter = grafanaNotReadyRequeueDelay
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GrafanaInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&grafanav1alpha1.GrafanaInstance{}).
+		For(&grafanav1beta1.GrafanaInstance{}).
+		// Deleting or editing a child repairs it
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&corev1.Secret{}).
 		Complete(r)
 }
 
@@ -145,6 +210,12 @@ func (r *GrafanaInstanceReconciler) getReconcilerPerStage(stage grafanaInstanceR
 		return reconcilers.NewDeploymentReconciler(r.Client)
 	case GrafanaInstanceStageService:
 		return reconcilers.NewServiceReconciler(r.Client)
+	case GrafanaInstanceStageCredentialsRotation:
+		return reconcilers.NewCredentialsRotationReconciler(r.Client, r.Clients)
+	case GrafanaInstanceStageServiceAccount:
+		return reconcilers.NewServiceAccountReconciler(r.Client, r.Clients)
+	case GrafanaInstanceStageReprovision:
+		return reconcilers.NewReprovisionReconciler(r.Client)
 	default:
 		return nil
 	}
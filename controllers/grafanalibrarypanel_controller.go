@@ -0,0 +1,279 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// grafanaLibraryPanelFinalizer holds the deletion of a library panel until no dashboard uses it
+const grafanaLibraryPanelFinalizer = "librarypanel.grafana.minicali.com"
+
+// GrafanaLibraryPanelReconciler reconciles a GrafanaLibraryPanel object
+type GrafanaLibraryPanelReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Clients  *grafana.ClientRegistry
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanalibrarypanels,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanalibrarypanels/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanalibrarypanels/finalizers,verbs=update
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile creates or updates the library panel in Grafana once per generation of its spec,
+// and deletes it once no dashboard uses it anymore.
+func (r *GrafanaLibraryPanelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("GrafanaLibraryPanelController").WithValues("GrafanaLibraryPanel", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.Info("Starting reconciliation")
+
+	libraryPanel := &grafanav1beta1.GrafanaLibraryPanel{}
+	if err := r.Get(ctx, req.NamespacedName, libraryPanel); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaLibraryPanel resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get GrafanaLibraryPanel")
+		return ctrl.Result{}, err
+	}
+	libraryPanel.SetDefaults()
+
+	if libraryPanel.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, log, libraryPanel)
+	}
+
+	// The library panel is only pushed again when its spec changed
+	if libraryPanel.Status.ObservedGeneration == libraryPanel.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	if !containsString(libraryPanel.Finalizers, grafanaLibraryPanelFinalizer) {
+		libraryPanel.Finalizers = append(libraryPanel.Finalizers, grafanaLibraryPanelFinalizer)
+		if err := r.Update(ctx, libraryPanel); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	grafanaClient, err := r.getGrafanaClient(ctx, libraryPanel)
+	if err != nil {
+		if isGrafanaNotReady(err) || isInstanceGone(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "Failed to get Grafana client")
+		return ctrl.Result{}, err
+	}
+	ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+
+	if err := r.syncLibraryPanel(ctx, log, libraryPanel); err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Referenced resources not created in Grafana yet, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Finished reconciliation")
+	return ctrl.Result{}, nil
+}
+
+// getGrafanaClient returns the client of the organization the library panel belongs to
+func (r *GrafanaLibraryPanelReconciler) getGrafanaClient(ctx context.Context, libraryPanel *grafanav1beta1.GrafanaLibraryPanel) (*grafana.GrafanaClient, error) {
+	instanceRef := libraryPanel.Spec.GrafanaInstanceRef
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: instanceRef.Name, Namespace: instanceRef.Namespace}, instance)
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s/%s", errInstanceGone, instanceRef.Namespace, instanceRef.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getOrganizationClient(ctx, r.Client, r.Clients, instance, libraryPanel.Namespace, instanceRef, libraryPanel.Spec.OrgRef, libraryPanel.Spec.OrgID)
+}
+
+// syncLibraryPanel upserts the library panel and its folder in Grafana
+func (r *GrafanaLibraryPanelReconciler) syncLibraryPanel(ctx context.Context, log logr.Logger, libraryPanel *grafanav1beta1.GrafanaLibraryPanel) error {
+	grafanaClient := grafana.FromContext(ctx)
+
+	var model map[string]interface{}
+	if err := json.Unmarshal(libraryPanel.Spec.Model.Raw, &model); err != nil || model == nil {
+		return fmt.Errorf("the model of GrafanaLibraryPanel %s/%s must be a JSON object", libraryPanel.Namespace, libraryPanel.Name)
+	}
+	name, _ := model["title"].(string)
+	if name == "" {
+		return fmt.Errorf("the model of GrafanaLibraryPanel %s/%s must have a title", libraryPanel.Namespace, libraryPanel.Name)
+	}
+
+	folderUID, folderCreated, err := grafanaClient.EnsureFolderTitle(ctx, log, libraryPanel.Spec.Folder, libraryPanel.Status.FolderUID, libraryPanel.Status.FolderCreated)
+	if err != nil {
+		return err
+	}
+
+	uid := libraryPanel.Spec.UID
+	if uid == "" {
+		uid = libraryPanel.Status.UID
+	}
+	if uid == "" {
+		uid = derivedDashboardUID(libraryPanel.Namespace, libraryPanel.Name)
+	}
+	version, err := grafanaClient.UpsertLibraryPanel(ctx, log, uid, model, folderUID)
+	if err != nil {
+		return err
+	}
+
+	libraryPanel.Status.UID = uid
+	libraryPanel.Status.Name = name
+	libraryPanel.Status.FolderUID = folderUID
+	libraryPanel.Status.FolderCreated = folderCreated
+	libraryPanel.Status.Version = version
+	libraryPanel.Status.ObservedGeneration = libraryPanel.Generation
+	if err := r.Status().Update(ctx, libraryPanel); err != nil {
+		log.Error(err, "Failed to update GrafanaLibraryPanel status")
+		return err
+	}
+	return nil
+}
+
+// reconcileDeletion deletes the library panel from Grafana and releases the finalizer,
+// once no dashboard uses the library panel anymore
+func (r *GrafanaLibraryPanelReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, libraryPanel *grafanav1beta1.GrafanaLibraryPanel) (ctrl.Result, error) {
+	if !containsString(libraryPanel.Finalizers, grafanaLibraryPanelFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	users, err := r.dashboardsUsing(ctx, libraryPanel)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(users) > 0 {
+		// The dashboards are watched, the deletion resumes once the last one stops using the library panel
+		log.Info("Library panel still used by dashboards, not deleting it", "dashboards", users)
+		message := fmt.Sprintf("Library panel cannot be deleted while GrafanaDashboards %s use it, remove their %s first", strings.Join(users, ", "), grafanav1beta1.LibraryPanelRefKey)
+		return ctrl.Result{}, r.setInUse(ctx, libraryPanel, message)
+	}
+
+	if libraryPanel.Status.UID != "" {
+		grafanaClient, err := r.getGrafanaClient(ctx, libraryPanel)
+		switch {
+		case isInstanceGone(err):
+			// Nothing left to clean up
+		case err != nil:
+			log.Info("Grafana is not available, retrying the deletion later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		default:
+			ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+			if err := grafanaClient.DeleteLibraryPanel(ctx, log, libraryPanel.Status.UID); err != nil && !grafana.IsNotFound(err) {
+				// Grafana also refuses while dashboards not managed by the operator use the library panel
+				message := fmt.Sprintf("Library panel could not be deleted from Grafana, retrying: %v", err)
+				return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, r.setInUse(ctx, libraryPanel, message)
+			}
+			r.Recorder.Event(libraryPanel, corev1.EventTypeNormal, "Deleted", "Library panel deleted from Grafana")
+		}
+	}
+
+	libraryPanel.Finalizers = removeString(libraryPanel.Finalizers, grafanaLibraryPanelFinalizer)
+	return ctrl.Result{}, r.Update(ctx, libraryPanel)
+}
+
+// dashboardsUsing returns the names of the dashboards of the namespace using the library panel
+func (r *GrafanaLibraryPanelReconciler) dashboardsUsing(ctx context.Context, libraryPanel *grafanav1beta1.GrafanaLibraryPanel) ([]string, error) {
+	dashboards := &grafanav1beta1.GrafanaDashboardList{}
+	if err := r.List(ctx, dashboards, client.InNamespace(libraryPanel.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, dashboard := range dashboards.Items {
+		if containsString(dashboard.Status.LibraryPanels, libraryPanel.Name) {
+			names = append(names, dashboard.Name)
+		}
+	}
+	return names, nil
+}
+
+// setInUse sets the InUse condition holding the deletion of the library panel
+func (r *GrafanaLibraryPanelReconciler) setInUse(ctx context.Context, libraryPanel *grafanav1beta1.GrafanaLibraryPanel, message string) error {
+	current := meta.FindStatusCondition(libraryPanel.Status.Conditions, grafanav1beta1.ConditionInUse)
+	if current != nil && current.Status == metav1.ConditionTrue && current.Message == message {
+		return nil
+	}
+	meta.SetStatusCondition(&libraryPanel.Status.Conditions, metav1.Condition{
+		Type:               grafanav1beta1.ConditionInUse,
+		Status:             metav1.ConditionTrue,
+		Reason:             "DeletionBlocked",
+		Message:            message,
+		ObservedGeneration: libraryPanel.Generation,
+	})
+	r.Recorder.Event(libraryPanel, corev1.EventTypeWarning, "DeletionBlocked", message)
+	return r.Status().Update(ctx, libraryPanel)
+}
+
+// requestsForDashboard enqueues the library panels of the namespace of the dashboard waiting to be deleted
+func (r *GrafanaLibraryPanelReconciler) requestsForDashboard(obj client.Object) []reconcile.Request {
+	libraryPanels := &grafanav1beta1.GrafanaLibraryPanelList{}
+	if err := r.List(context.Background(), libraryPanels, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list the GrafanaLibraryPanels of a dashboard", "GrafanaDashboard", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, libraryPanel := range libraryPanels.Items {
+		if libraryPanel.DeletionTimestamp != nil {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&libraryPanel)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaLibraryPanelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&grafanav1beta1.GrafanaLibraryPanel{}).
+		// The deletion of a library panel resumes as soon as the dashboards stop using it
+		Watches(
+			&source.Kind{Type: &grafanav1beta1.GrafanaDashboard{}},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForDashboard),
+		).
+		Complete(r)
+}
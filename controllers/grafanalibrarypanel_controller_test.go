@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+)
+
+var _ = Describe("Library panels", func() {
+	var (
+		ctx          context.Context
+		instanceRef  grafanav1beta1.GrafanaInstanceRef
+		libraryPanel *grafanav1beta1.GrafanaLibraryPanel
+		dashboard    *grafanav1beta1.GrafanaDashboard
+		source       map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		instanceRef = grafanav1beta1.GrafanaInstanceRef{Name: "gone", Namespace: "default"}
+		libraryPanel = &grafanav1beta1.GrafanaLibraryPanel{
+			ObjectMeta: metav1.ObjectMeta{Name: "availability", Namespace: "default"},
+			Spec:       grafanav1beta1.GrafanaLibraryPanelSpec{GrafanaInstanceRef: instanceRef},
+			Status:     grafanav1beta1.GrafanaLibraryPanelStatus{UID: "slo-availability", Name: "Availability SLO"},
+		}
+		dashboard = &grafanav1beta1.GrafanaDashboard{
+			ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"},
+			Spec:       grafanav1beta1.GrafanaDashboardSpec{GrafanaInstanceRef: instanceRef},
+		}
+		source = map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(`{"title": "SLOs", "panels": [
+			{"id": 1, "libraryPanelRef": "availability"},
+			{"id": 2, "type": "row", "panels": [{"id": 3, "libraryPanelRef": "availability"}]}
+		]}`), &source)).To(Succeed())
+	})
+
+	Describe("GrafanaDashboardReconciler", func() {
+		var r *GrafanaDashboardReconciler
+
+		BeforeEach(func() {
+			// The latency library panel is not synced yet
+			latency := libraryPanel.DeepCopy()
+			latency.Name = "latency"
+			latency.Status.UID = ""
+			r = &GrafanaDashboardReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(libraryPanel, latency).Build(),
+				Scheme: scheme.Scheme,
+			}
+		})
+
+		It("injects the references to the library panels", func() {
+			Expect(libraryPanelRefs(source)).To(Equal([]string{"availability"}), "the library panel is referenced once")
+			Expect(r.injectLibraryPanels(ctx, dashboard, source)).To(Succeed())
+			walkPanels(source, func(panel map[string]interface{}) {
+				if panel["type"] == "row" {
+					return
+				}
+				Expect(panel).NotTo(HaveKey(grafanav1beta1.LibraryPanelRefKey))
+				Expect(panel).To(HaveKeyWithValue("libraryPanel", map[string]interface{}{"uid": "slo-availability", "name": "Availability SLO"}))
+			})
+		})
+
+		DescribeTable("waits for the library panels not ready",
+			func(name string) {
+				model := map[string]interface{}{"panels": []interface{}{map[string]interface{}{"libraryPanelRef": name}}}
+				Expect(r.injectLibraryPanels(ctx, dashboard, model)).To(MatchError(errLibraryPanelNotReady))
+			},
+			Entry("not synced", "latency"),
+			Entry("missing", "missing"),
+		)
+
+		It("references the library panels by name again in the exported model", func() {
+			live := runtime.DeepCopyJSON(source)
+			Expect(r.injectLibraryPanels(ctx, dashboard, live)).To(Succeed())
+			// A library panel made in Grafana has no resource to reference
+			handmade := map[string]interface{}{"id": 4.0, "libraryPanel": map[string]interface{}{"uid": "handmade"}}
+			live["panels"] = append(live["panels"].([]interface{}), handmade)
+
+			extracted, err := r.extractLibraryPanels(ctx, dashboard, live)
+			Expect(err).NotTo(HaveOccurred())
+			source["panels"] = append(source["panels"].([]interface{}), runtime.DeepCopyJSONValue(handmade))
+			Expect(extracted).To(Equal(source))
+			Expect(libraryPanelRefs(extracted)).To(Equal([]string{"availability"}), "the dashboard goes on using the library panel")
+			Expect(live["panels"].([]interface{})[0]).To(HaveKey("libraryPanel"), "the live model is left alone")
+		})
+	})
+
+	Describe("GrafanaLibraryPanelReconciler", func() {
+		var r *GrafanaLibraryPanelReconciler
+
+		BeforeEach(func() {
+			libraryPanel.Finalizers = []string{grafanaLibraryPanelFinalizer}
+			libraryPanel.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			dashboard.Status.LibraryPanels = []string{"availability"}
+			r = &GrafanaLibraryPanelReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(libraryPanel, dashboard).Build(),
+				Scheme:   scheme.Scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+		})
+
+		It("deletes the library panel once no dashboard uses it", func() {
+			_, err := r.reconcileDeletion(ctx, logr.Discard(), libraryPanel)
+			Expect(err).NotTo(HaveOccurred())
+			updated := &grafanav1beta1.GrafanaLibraryPanel{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(libraryPanel), updated)).To(Succeed())
+			Expect(updated.Finalizers).To(ContainElement(grafanaLibraryPanelFinalizer), "the deletion is blocked while the dashboard uses the library panel")
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, grafanav1beta1.ConditionInUse)).To(BeTrue())
+			Expect(r.requestsForDashboard(dashboard)).To(HaveLen(1), "the library panel waiting to be deleted is enqueued")
+
+			// The instance is gone, nothing is left to clean up in Grafana
+			dashboard.Status.LibraryPanels = nil
+			Expect(r.Status().Update(ctx, dashboard)).To(Succeed())
+			_, err = r.reconcileDeletion(ctx, logr.Discard(), updated)
+			Expect(err).NotTo(HaveOccurred())
+			err = r.Get(ctx, client.ObjectKeyFromObject(libraryPanel), updated)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+		})
+	})
+})
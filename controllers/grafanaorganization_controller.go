@@ -0,0 +1,365 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	"github.com/minicali/grafana-operator/internal/reconcilers"
+)
+
+// GrafanaOrganizationReconciler reconciles a GrafanaOrganization object
+type GrafanaOrganizationReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Clients  *grafana.ClientRegistry
+	Recorder record.EventRecorder
+	// DeletionTimeout bounds how long the deletion of an organization waits for Grafana to be reachable
+	DeletionTimeout time.Duration
+}
+
+const (
+	grafanaOrganizationFinalizer = "finalizer.grafana.minicali.com"
+
+	// organizationOperatorRole is the role of the operator service account and admin user in the organizations
+	organizationOperatorRole = "Admin"
+
+	// organizationReferencedRequeueDelay is the delay before checking again whether a deleted organization is still referenced
+	organizationReferencedRequeueDelay = 30 * time.Second
+
+	// organizationIndex indexes the dashboards, teams and users by the organizations they reference
+	organizationIndex = "spec.organization"
+)
+
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations/finalizers,verbs=update
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanadashboards,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanateams,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanausers,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile creates the organization in Grafana and bootstraps the operator service account of the organization.
+// The deletion of the organization is blocked while dashboards, teams or users still reference it.
+func (r *GrafanaOrganizationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("GrafanaOrganizationController").WithValues("GrafanaOrganization", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.Info("Starting reconciliation")
+
+	org := &grafanav1beta1.GrafanaOrganization{}
+	if err := r.Get(ctx, req.NamespacedName, org); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaOrganization resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get GrafanaOrganization")
+		return ctrl.Result{}, err
+	}
+
+	// Deletion is handled first, it must not be held by an instance that is gone or unreachable
+	if org.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, log, org)
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: org.Spec.GrafanaInstanceRef.Name, Namespace: org.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if err != nil {
+		log.Error(err, "Failed to get GrafanaInstance")
+		return ctrl.Result{}, err
+	}
+
+	adminClient, adminLogin, err := getAdminClient(ctx, r.Client, r.Clients, instance)
+	if err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "Failed to create Grafana admin client")
+		return ctrl.Result{}, err
+	}
+	defer adminClient.Close()
+
+	if !containsString(org.Finalizers, grafanaOrganizationFinalizer) {
+		org.Finalizers = append(org.Finalizers, grafanaOrganizationFinalizer)
+		if err := r.Update(ctx, org); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	orgID, orgCreated, err := adminClient.EnsureOrganization(ctx, log, org.GetOrganizationName(), org.Status.OrgID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Organizations found by name may not include the admin user yet
+	if err := adminClient.AddOrganizationUser(ctx, log, orgID, adminLogin, organizationOperatorRole); err != nil {
+		return ctrl.Result{}, err
+	}
+	orgAdminClient := adminClient.WithOrgID(orgID)
+
+	auths, err := grafana.GetInstanceAuthenticators(ctx, r.Client, instance)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana authenticators")
+		return ctrl.Result{}, err
+	}
+	transport := r.Clients.TransportOptions(instance)
+
+	token := &reconcilers.ServiceAccountToken{
+		Client:           r.Client,
+		Secret:           client.ObjectKey{Name: helpers.GetOrganizationTokenSecretName(org.Name), Namespace: org.Namespace},
+		Labels:           helpers.GetGrafanaLabels(instance.Name, "org-token"),
+		Role:             organizationOperatorRole,
+		RotationInterval: instance.GetTokenRotationInterval(),
+		NewTokenClient: func(secret *corev1.Secret) (*grafana.GrafanaClient, error) {
+			tokenClient, err := grafana.CreateGrafanaClientFromTokenSecret(ctx, secret, instance.Status.GrafanaUI.ServiceURL, transport, auths...)
+			if err != nil {
+				return nil, err
+			}
+			return tokenClient.WithOrgID(orgID), nil
+		},
+		NewAdminClient: func() (*grafana.GrafanaClient, error) {
+			return orgAdminClient, nil
+		},
+	}
+	tokenSecret, err := token.Reconcile(ctx, log)
+	if err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// An organization found by name existed before and is left in Grafana on deletion
+	if orgID != org.Status.OrgID {
+		org.Status.Created = orgCreated
+	}
+	org.Status.OrgID = orgID
+	org.Status.TokenSecretName = tokenSecret.Name
+	created, ok := reconcilers.GetTokenCreationTime(tokenSecret)
+	if ok {
+		createdAt := metav1.NewTime(created)
+		org.Status.LastTokenRotationTime = &createdAt
+	}
+	if err := r.Status().Update(ctx, org); err != nil {
+		log.Error(err, "Failed to update GrafanaOrganization status")
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Finished reconciliation", "orgID", orgID)
+
+	// Requeue for the next token rotation
+	requeueAfter := time.Until(created.Add(instance.GetTokenRotationInterval()))
+	if !ok || requeueAfter <= 0 {
+		requeueAfter = serviceAccountRequeueDelay
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// reconcileDeletion deletes the organization from Grafana once nothing references it anymore, and releases the finalizer.
+// While Grafana cannot be reached, the deletion is retried until the deletion timeout, then the finalizer is released anyway.
+func (r *GrafanaOrganizationReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, org *grafanav1beta1.GrafanaOrganization) (ctrl.Result, error) {
+	if !containsString(org.Finalizers, grafanaOrganizationFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	references, err := r.referencingResources(ctx, org)
+	if err != nil {
+		log.Error(err, "Failed to list the resources referencing the organization")
+		return ctrl.Result{}, err
+	}
+	if len(references) > 0 {
+		log.Info("GrafanaOrganization is still referenced, deletion blocked", "references", references)
+		message := fmt.Sprintf("Organization cannot be deleted while %s reference it", strings.Join(references, ", "))
+		return ctrl.Result{RequeueAfter: organizationReferencedRequeueDelay}, r.setReferenced(ctx, org, message)
+	}
+
+	err = r.deleteOrganization(ctx, log, org)
+	switch {
+	case err == nil:
+	case time.Since(org.DeletionTimestamp.Time) >= r.DeletionTimeout:
+		log.Error(err, "Giving up deleting the Grafana organization", "timeout", r.DeletionTimeout)
+		r.Recorder.Eventf(org, corev1.EventTypeWarning, "DeletionAbandoned", "Organization left in Grafana, it could not be deleted within %s: %v", r.DeletionTimeout, err)
+	default:
+		log.Info("Failed to delete the Grafana organization, retrying later", "reason", err.Error())
+		r.Recorder.Eventf(org, corev1.EventTypeWarning, "DeletionFailed", "Organization could not be deleted from Grafana, retrying: %v", err)
+		return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+	}
+
+	tokenSecret := &corev1.Secret{}
+	tokenSecret.Name = helpers.GetOrganizationTokenSecretName(org.Name)
+	tokenSecret.Namespace = org.Namespace
+	if err := r.Delete(ctx, tokenSecret); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Failed to delete token Secret")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, r.removeFinalizer(ctx, org)
+}
+
+// deleteOrganization deletes the organization from Grafana, nothing is left to clean up once the instance is gone
+func (r *GrafanaOrganizationReconciler) deleteOrganization(ctx context.Context, log logr.Logger, org *grafanav1beta1.GrafanaOrganization) error {
+	// Only the organizations the operator created are deleted, the default organization cannot be
+	switch {
+	case org.Status.OrgID == 0 || org.Status.OrgID == grafana.DefaultOrgID:
+		return nil
+	case !org.Status.Created:
+		log.Info("Leaving Grafana organization the operator did not create", "ID", org.Status.OrgID)
+		return nil
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: org.Spec.GrafanaInstanceRef.Name, Namespace: org.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	adminClient, _, err := getAdminClient(ctx, r.Client, r.Clients, instance)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+	return adminClient.DeleteOrganization(ctx, log, org.Status.OrgID)
+}
+
+// referencingResources returns the dashboards, teams and users referencing the organization, by kind and name
+func (r *GrafanaOrganizationReconciler) referencingResources(ctx context.Context, org *grafanav1beta1.GrafanaOrganization) ([]string, error) {
+	var references []string
+	for _, key := range getOrganizationIndexKeys(org) {
+		for _, referencing := range []struct {
+			kind string
+			list client.ObjectList
+		}{
+			{kind: "GrafanaDashboard", list: &grafanav1beta1.GrafanaDashboardList{}},
+			{kind: "GrafanaTeam", list: &grafanav1beta1.GrafanaTeamList{}},
+			{kind: "GrafanaUser", list: &grafanav1beta1.GrafanaUserList{}},
+		} {
+			if err := r.List(ctx, referencing.list, client.MatchingFields{organizationIndex: key}); err != nil {
+				return nil, err
+			}
+			err := meta.EachListItem(referencing.list, func(obj runtime.Object) error {
+				item := obj.(client.Object)
+				reference := fmt.Sprintf("%s %s/%s", referencing.kind, item.GetNamespace(), item.GetName())
+				if !containsString(references, reference) {
+					references = append(references, reference)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return references, nil
+}
+
+// setReferenced sets the Referenced condition holding the deletion of the organization
+func (r *GrafanaOrganizationReconciler) setReferenced(ctx context.Context, org *grafanav1beta1.GrafanaOrganization, message string) error {
+	current := meta.FindStatusCondition(org.Status.Conditions, grafanav1beta1.ConditionReferenced)
+	if current != nil && current.Status == metav1.ConditionTrue && current.Message == message {
+		return nil
+	}
+	meta.SetStatusCondition(&org.Status.Conditions, metav1.Condition{
+		Type:               grafanav1beta1.ConditionReferenced,
+		Status:             metav1.ConditionTrue,
+		Reason:             "DeletionBlocked",
+		Message:            message,
+		ObservedGeneration: org.Generation,
+	})
+	r.Recorder.Event(org, corev1.EventTypeWarning, "DeletionBlocked", message)
+	return r.Status().Update(ctx, org)
+}
+
+func (r *GrafanaOrganizationReconciler) removeFinalizer(ctx context.Context, org *grafanav1beta1.GrafanaOrganization) error {
+	if !containsString(org.Finalizers, grafanaOrganizationFinalizer) {
+		return nil
+	}
+	org.Finalizers = removeString(org.Finalizers, grafanaOrganizationFinalizer)
+	return r.Update(ctx, org)
+}
+
+// getOrganizationIndexKeys returns the keys under which the resources referencing the organization are indexed
+func getOrganizationIndexKeys(org *grafanav1beta1.GrafanaOrganization) []string {
+	keys := []string{organizationRefIndexKey(org.Namespace, org.Name)}
+	if org.Status.OrgID != 0 {
+		keys = append(keys, organizationIDIndexKey(org.Spec.GrafanaInstanceRef, org.Status.OrgID))
+	}
+	return keys
+}
+
+// indexDashboardOrganization returns the index keys of the organization a dashboard belongs to
+func indexDashboardOrganization(obj client.Object) []string {
+	dashboard := obj.(*grafanav1beta1.GrafanaDashboard)
+	return organizationIndexKeys(dashboard.Namespace, dashboard.Spec.GrafanaInstanceRef, dashboard.Spec.OrgRef, dashboard.Spec.OrgID)
+}
+
+// indexTeamOrganization returns the index keys of the organization a team belongs to
+func indexTeamOrganization(obj client.Object) []string {
+	team := obj.(*grafanav1beta1.GrafanaTeam)
+	return organizationIndexKeys(team.Namespace, team.Spec.GrafanaInstanceRef, team.Spec.OrgRef, team.Spec.OrgID)
+}
+
+// indexUserOrganizations returns the index keys of the organizations a user has a role in
+func indexUserOrganizations(obj client.Object) []string {
+	user := obj.(*grafanav1beta1.GrafanaUser)
+	var keys []string
+	for _, orgRole := range user.Spec.OrgRoles {
+		keys = append(keys, organizationIndexKeys(user.Namespace, user.Spec.GrafanaInstanceRef, orgRole.OrgRef, orgRole.OrgID)...)
+	}
+	return keys
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaOrganizationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &grafanav1beta1.GrafanaDashboard{}, organizationIndex, indexDashboardOrganization); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &grafanav1beta1.GrafanaTeam{}, organizationIndex, indexTeamOrganization); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &grafanav1beta1.GrafanaUser{}, organizationIndex, indexUserOrganizations); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&grafanav1beta1.GrafanaOrganization{}).
+		Complete(r)
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+var _ = Describe("GrafanaOrganizationReconciler", func() {
+	var (
+		ctx         context.Context
+		instanceRef grafanav1beta1.GrafanaInstanceRef
+		org         *grafanav1beta1.GrafanaOrganization
+		instance    *grafanav1beta1.GrafanaInstance
+		objects     []client.Object
+		recorder    *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		instanceRef = grafanav1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "default"}
+		org = &grafanav1beta1.GrafanaOrganization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "org",
+				Namespace:         "default",
+				Finalizers:        []string{grafanaOrganizationFinalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec:   grafanav1beta1.GrafanaOrganizationSpec{GrafanaInstanceRef: instanceRef},
+			Status: grafanav1beta1.GrafanaOrganizationStatus{OrgID: 2, Created: true},
+		}
+		// The service URL of the instance is not known yet, Grafana cannot be reached
+		instance = &grafanav1beta1.GrafanaInstance{ObjectMeta: metav1.ObjectMeta{Name: instanceRef.Name, Namespace: instanceRef.Namespace}}
+		objects = nil
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	// reconcile reconciles the organization with a client holding it, its instance and the objects
+	reconcile := func() (*GrafanaOrganizationReconciler, ctrl.Result) {
+		r := &GrafanaOrganizationReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(append(objects, org, instance)...).
+				WithIndex(&grafanav1beta1.GrafanaDashboard{}, organizationIndex, indexDashboardOrganization).
+				WithIndex(&grafanav1beta1.GrafanaTeam{}, organizationIndex, indexTeamOrganization).
+				WithIndex(&grafanav1beta1.GrafanaUser{}, organizationIndex, indexUserOrganizations).
+				Build(),
+			Scheme:          scheme.Scheme,
+			Clients:         grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0),
+			Recorder:        recorder,
+			DeletionTimeout: 10 * time.Minute,
+		}
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(org)})
+		Expect(err).NotTo(HaveOccurred())
+		return r, result
+	}
+
+	DescribeTable("handles the deletion of the organization",
+		func(deletedSince time.Duration, released bool, event string) {
+			org.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedSince)}
+			r, result := reconcile()
+
+			// Without its finalizer, the deleted organization is gone
+			err := r.Get(ctx, client.ObjectKeyFromObject(org), &grafanav1beta1.GrafanaOrganization{})
+			if released {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).NotTo(BeZero(), "the deletion is retried")
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+		},
+		Entry("retried within the timeout", time.Minute, false, "DeletionFailed"),
+		Entry("released after the timeout", time.Hour, true, "DeletionAbandoned"),
+	)
+
+	It("blocks the deletion while resources reference the organization", func() {
+		objects = []client.Object{
+			&grafanav1beta1.GrafanaDashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"},
+				Spec:       grafanav1beta1.GrafanaDashboardSpec{GrafanaInstanceRef: instanceRef, OrgID: 2},
+			},
+			&grafanav1beta1.GrafanaTeam{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "default"},
+				Spec:       grafanav1beta1.GrafanaTeamSpec{GrafanaInstanceRef: instanceRef, OrgRef: &grafanav1beta1.GrafanaOrganizationRef{Name: "org"}},
+			},
+		}
+		r, result := reconcile()
+		Expect(result.RequeueAfter).To(Equal(organizationReferencedRequeueDelay))
+
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(org), org)).To(Succeed())
+		condition := meta.FindStatusCondition(org.Status.Conditions, grafanav1beta1.ConditionReferenced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("GrafanaTeam default/team"))
+		Expect(condition.Message).To(ContainSubstring("GrafanaDashboard default/dashboard"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("DeletionBlocked")))
+	})
+
+	Context("when Grafana is reachable", func() {
+		var (
+			mu       sync.Mutex
+			requests []string
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.URL.Path == "/api/health" {
+					_, _ = w.Write([]byte(`{"database": "ok", "version": "9.5.0"}`))
+					return
+				}
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			DeferCleanup(grafanaServer.Close)
+
+			instance.Spec.CredentialsSecretName = "grafana-admin"
+			instance.Status.GrafanaUI.ServiceURL = grafanaServer.URL
+			objects = []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "grafana-admin", Namespace: "default"},
+				Data: map[string][]byte{
+					grafanav1beta1.DefaultUsernameKey: []byte("admin"),
+					grafanav1beta1.DefaultPasswordKey: []byte("admin"),
+				},
+			}}
+		})
+
+		DescribeTable("deletes the organizations it created",
+			func(orgID int64, created bool, expected []string) {
+				org.Status = grafanav1beta1.GrafanaOrganizationStatus{OrgID: orgID, Created: created}
+				r, _ := reconcile()
+
+				mu.Lock()
+				defer mu.Unlock()
+				Expect(requests).To(Equal(expected))
+				err := r.Get(ctx, client.ObjectKeyFromObject(org), &grafanav1beta1.GrafanaOrganization{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+			},
+			Entry("created organization deleted", int64(2), true, []string{"DELETE /api/orgs/2"}),
+			Entry("adopted organization left", int64(2), false, nil),
+			Entry("default organization left", grafana.DefaultOrgID, true, nil),
+		)
+	})
+})
@@ -0,0 +1,245 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+// GrafanaTeamReconciler reconciles a GrafanaTeam object
+type GrafanaTeamReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Clients  *grafana.ClientRegistry
+	Recorder record.EventRecorder
+	// DeletionTimeout bounds how long the deletion of a team waits for Grafana to be reachable
+	DeletionTimeout time.Duration
+}
+
+const (
+	grafanaTeamFinalizer = "finalizer.grafana.minicali.com"
+
+	// accessResyncPeriod is the delay between two syncs of the teams and users, reverting changes made in Grafana
+	accessResyncPeriod = 5 * time.Minute
+)
+
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanateams,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanateams/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanateams/finalizers,verbs=update
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile creates the team in its organization and syncs its members and external groups.
+func (r *GrafanaTeamReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("GrafanaTeamController").WithValues("GrafanaTeam", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.Info("Starting reconciliation")
+
+	team := &grafanav1beta1.GrafanaTeam{}
+	if err := r.Get(ctx, req.NamespacedName, team); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaTeam resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get GrafanaTeam")
+		return ctrl.Result{}, err
+	}
+
+	// Deletion is handled first, it must not be held by an instance that is gone or unreachable
+	if team.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, log, team)
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: team.Spec.GrafanaInstanceRef.Name, Namespace: team.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if err != nil {
+		log.Error(err, "Failed to get GrafanaInstance")
+		return ctrl.Result{}, err
+	}
+
+	orgID, err := getOrganizationID(ctx, r.Client, team.Namespace, team.Spec.GrafanaInstanceRef, team.Spec.OrgRef, team.Spec.OrgID)
+	if err == nil {
+		var grafanaClient *grafana.GrafanaClient
+		grafanaClient, err = getOrganizationClient(ctx, r.Client, r.Clients, instance, team.Namespace, team.Spec.GrafanaInstanceRef, team.Spec.OrgRef, team.Spec.OrgID)
+		if err == nil {
+			ctx = grafana.WithGrafanaClient(ctx, grafanaClient)
+		}
+	}
+	if err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "Failed to get Grafana client")
+		return ctrl.Result{}, err
+	}
+	grafanaClient := grafana.FromContext(ctx)
+
+	if !containsString(team.Finalizers, grafanaTeamFinalizer) {
+		team.Finalizers = append(team.Finalizers, grafanaTeamFinalizer)
+		if err := r.Update(ctx, team); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// A team moved to another organization is deleted from the previous one and created again
+	teamID := team.Status.TeamID
+	if team.Status.OrgID != orgID {
+		if err := r.deleteTeam(ctx, log, team, team.Status.OrgID, teamID); err != nil {
+			if isGrafanaNotReady(err) {
+				log.Info("Previous organization is not available, retrying later", "reason", err.Error())
+				return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		teamID = 0
+	}
+	teamID, created, err := grafanaClient.EnsureTeam(ctx, log, teamID, team.GetTeamName(), team.Spec.Email)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Record the team before syncing its members, so it is deleted with the resource even if the sync fails.
+	// A team found by name existed before and is left in Grafana on deletion.
+	if team.Status.TeamID != teamID || team.Status.OrgID != orgID {
+		if !created {
+			r.Recorder.Event(team, corev1.EventTypeNormal, "Adopted", "Team already in Grafana taken over, it is left in Grafana on deletion")
+		}
+		team.Status.TeamID = teamID
+		team.Status.OrgID = orgID
+		team.Status.Created = created
+		if err := r.Status().Update(ctx, team); err != nil {
+			log.Error(err, "Failed to update GrafanaTeam status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	members := make([]grafana.TeamMember, 0, len(team.Spec.Members))
+	for _, member := range team.Spec.Members {
+		members = append(members, grafana.TeamMember{
+			LoginOrEmail: member.LoginOrEmail,
+			Permission:   grafana.ParseTeamMemberPermission(member.Role),
+		})
+	}
+	if err := grafanaClient.SyncTeamMembers(ctx, log, teamID, members); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Team sync is only available with Grafana Enterprise, leave it alone unless asked for
+	if len(team.Spec.ExternalGroups) > 0 || len(team.Status.ExternalGroups) > 0 {
+		if err := grafanaClient.SyncTeamGroups(ctx, log, teamID, team.Spec.ExternalGroups); err != nil {
+			return ctrl.Result{}, err
+		}
+		team.Status.ExternalGroups = team.Spec.ExternalGroups
+		if err := r.Status().Update(ctx, team); err != nil {
+			log.Error(err, "Failed to update GrafanaTeam status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("Finished reconciliation", "teamID", teamID)
+	return ctrl.Result{RequeueAfter: accessResyncPeriod}, nil
+}
+
+// reconcileDeletion deletes the team from Grafana and releases the finalizer. While Grafana cannot be reached,
+// the deletion is retried until the deletion timeout, then the finalizer is released anyway.
+func (r *GrafanaTeamReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, team *grafanav1beta1.GrafanaTeam) (ctrl.Result, error) {
+	if !containsString(team.Finalizers, grafanaTeamFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	err := r.deleteTeam(ctx, log, team, team.Status.OrgID, team.Status.TeamID)
+	switch {
+	case err == nil:
+	case time.Since(team.DeletionTimestamp.Time) >= r.DeletionTimeout:
+		log.Error(err, "Giving up deleting the Grafana team", "timeout", r.DeletionTimeout)
+		r.Recorder.Eventf(team, corev1.EventTypeWarning, "DeletionAbandoned", "Team left in Grafana, it could not be deleted within %s: %v", r.DeletionTimeout, err)
+	default:
+		log.Info("Failed to delete the Grafana team, retrying later", "reason", err.Error())
+		r.Recorder.Eventf(team, corev1.EventTypeWarning, "DeletionFailed", "Team could not be deleted from Grafana, retrying: %v", err)
+		return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+	}
+
+	return ctrl.Result{}, r.removeFinalizer(ctx, team)
+}
+
+// deleteTeam deletes a team of the resource from an organization, a team the operator did not create is only
+// left without the members of the resource. Nothing is left to clean up once the instance or the GrafanaOrganization is gone.
+func (r *GrafanaTeamReconciler) deleteTeam(ctx context.Context, log logr.Logger, team *grafanav1beta1.GrafanaTeam, orgID int64, teamID int64) error {
+	if teamID == 0 {
+		return nil
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: team.Spec.GrafanaInstanceRef.Name, Namespace: team.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	grafanaClient, err := getOrganizationClient(ctx, r.Client, r.Clients, instance, team.Namespace, team.Spec.GrafanaInstanceRef, nil, orgID)
+	if errors.Is(err, errOrganizationGone) {
+		log.Info("Organization of the team is gone", "orgID", orgID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !team.Status.Created {
+		log.Info("Leaving Grafana team the operator did not create", "ID", teamID)
+		members := make([]string, 0, len(team.Spec.Members))
+		for _, member := range team.Spec.Members {
+			members = append(members, member.LoginOrEmail)
+		}
+		return grafanaClient.RemoveTeamMembers(ctx, log, teamID, members)
+	}
+	return grafanaClient.DeleteTeam(ctx, log, teamID)
+}
+
+func (r *GrafanaTeamReconciler) removeFinalizer(ctx context.Context, team *grafanav1beta1.GrafanaTeam) error {
+	if !containsString(team.Finalizers, grafanaTeamFinalizer) {
+		return nil
+	}
+	team.Finalizers = removeString(team.Finalizers, grafanaTeamFinalizer)
+	return r.Update(ctx, team)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaTeamReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&grafanav1beta1.GrafanaTeam{}).
+		Complete(r)
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+)
+
+var _ = Describe("GrafanaTeamReconciler", func() {
+	var (
+		ctx         context.Context
+		instanceRef grafanav1beta1.GrafanaInstanceRef
+		team        *grafanav1beta1.GrafanaTeam
+		objects     []client.Object
+		recorder    *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		instanceRef = grafanav1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "default"}
+		team = &grafanav1beta1.GrafanaTeam{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "default", Finalizers: []string{grafanaTeamFinalizer}},
+			Spec:       grafanav1beta1.GrafanaTeamSpec{GrafanaInstanceRef: instanceRef},
+			Status:     grafanav1beta1.GrafanaTeamStatus{TeamID: 7, OrgID: grafana.DefaultOrgID},
+		}
+		objects = nil
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	// reconcile reconciles the team with a client holding it and the objects
+	reconcile := func() (*GrafanaTeamReconciler, ctrl.Result) {
+		r := &GrafanaTeamReconciler{
+			Client:          fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, team)...).Build(),
+			Scheme:          scheme.Scheme,
+			Clients:         grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0),
+			Recorder:        recorder,
+			DeletionTimeout: 10 * time.Minute,
+		}
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(team)})
+		Expect(err).NotTo(HaveOccurred())
+		return r, result
+	}
+
+	DescribeTable("handles the deletion of the team",
+		func(deletedSince time.Duration, instanceGone bool, released bool, event string) {
+			team.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedSince)}
+			// The operator service account of the instance is not bootstrapped, Grafana cannot be reached
+			if !instanceGone {
+				objects = append(objects, &grafanav1beta1.GrafanaInstance{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "default"}})
+			}
+			r, result := reconcile()
+
+			// Without its finalizer, the deleted team is gone
+			err := r.Get(ctx, client.ObjectKeyFromObject(team), &grafanav1beta1.GrafanaTeam{})
+			if released {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).NotTo(BeZero(), "the deletion is retried")
+			}
+			if event == "" {
+				Expect(recorder.Events).NotTo(Receive())
+			} else {
+				Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			}
+		},
+		Entry("retried within the timeout", time.Minute, false, false, "DeletionFailed"),
+		Entry("released after the timeout", time.Hour, false, true, "DeletionAbandoned"),
+		Entry("released with the instance gone", time.Minute, true, true, ""),
+	)
+
+	Context("when the team is moved to another organization", func() {
+		var (
+			mu             sync.Mutex
+			requests       []string
+			deletedFrom    *string
+			removedMembers []string
+		)
+
+		BeforeEach(func() {
+			requests, deletedFrom, removedMembers = nil, nil, nil
+			grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case r.URL.Path == "/api/health":
+					_, _ = w.Write([]byte(`{"database": "ok", "version": "9.5.0"}`))
+				case r.Method == http.MethodDelete && r.URL.Path == "/api/teams/7":
+					orgID := r.Header.Get("X-Grafana-Org-Id")
+					deletedFrom = &orgID
+					_, _ = w.Write([]byte(`{"message": "Team deleted"}`))
+				case r.Method == http.MethodGet && r.URL.Path == "/api/teams/7/members":
+					_, _ = w.Write([]byte(`[{"userId": 1, "login": "alice"}, {"userId": 2, "login": "bob"}]`))
+				case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/teams/7/members/"):
+					removedMembers = append(removedMembers, strings.TrimPrefix(r.URL.Path, "/api/teams/7/members/"))
+					_, _ = w.Write([]byte(`{}`))
+				case r.Method == http.MethodGet && r.URL.Path == "/api/teams/search":
+					_, _ = w.Write([]byte(`{"teams": []}`))
+				case r.Method == http.MethodPost && r.URL.Path == "/api/teams":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"teamId": 8})
+				case r.Method == http.MethodGet && r.URL.Path == "/api/org/users/lookup":
+					_, _ = w.Write([]byte(`[{"userId": 1, "login": "alice"}]`))
+				case r.URL.Path == "/api/teams/8/members":
+					_, _ = w.Write([]byte(`[]`))
+				default:
+					requests = append(requests, r.Method+" "+r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			DeferCleanup(grafanaServer.Close)
+
+			objects = []client.Object{
+				&grafanav1beta1.GrafanaInstance{
+					ObjectMeta: metav1.ObjectMeta{Name: instanceRef.Name, Namespace: instanceRef.Namespace},
+					Status: grafanav1beta1.GrafanaInstanceStatus{
+						GrafanaUI:              grafanav1beta1.GrafanaUIStatus{ServiceURL: grafanaServer.URL},
+						OperatorServiceAccount: grafanav1beta1.OperatorServiceAccountStatus{TokenSecretName: "grafana-token"},
+					},
+				},
+				&grafanav1beta1.GrafanaOrganization{
+					ObjectMeta: metav1.ObjectMeta{Name: "org", Namespace: "default"},
+					Spec:       grafanav1beta1.GrafanaOrganizationSpec{GrafanaInstanceRef: instanceRef},
+					Status:     grafanav1beta1.GrafanaOrganizationStatus{OrgID: 2, TokenSecretName: "org-token"},
+				},
+			}
+			for _, name := range []string{"grafana-token", "org-token"} {
+				objects = append(objects, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+					Data:       map[string][]byte{helpers.ServiceAccountTokenKey: []byte(name)},
+				})
+			}
+			// The team was synced in the default organization, then moved to another one
+			team.Spec.OrgRef = &grafanav1beta1.GrafanaOrganizationRef{Name: "org"}
+			team.Spec.Members = []grafanav1beta1.GrafanaTeamMember{{LoginOrEmail: "alice"}}
+		})
+
+		DescribeTable("leaves the default organization and creates the team in the new one",
+			func(created bool, deleted bool, removed []string) {
+				team.Status.Created = created
+				r, _ := reconcile()
+
+				mu.Lock()
+				defer mu.Unlock()
+				Expect(requests).To(BeEmpty(), "unexpected requests")
+				if deleted {
+					// The client of the instance sends its requests to the default organization
+					Expect(deletedFrom).To(HaveValue(BeEmpty()))
+				} else {
+					Expect(deletedFrom).To(BeNil())
+				}
+				Expect(removedMembers).To(Equal(removed))
+				Expect(r.Get(ctx, client.ObjectKeyFromObject(team), team)).To(Succeed())
+				Expect(team.Status.TeamID).To(BeEquivalentTo(8))
+				Expect(team.Status.OrgID).To(BeEquivalentTo(2))
+				Expect(team.Status.Created).To(BeTrue())
+			},
+			Entry("created team deleted", true, true, nil),
+			Entry("adopted team left without the members of the resource", false, false, []string{"1"}),
+		)
+	})
+})
@@ -0,0 +1,296 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+)
+
+// GrafanaUserReconciler reconciles a GrafanaUser object
+type GrafanaUserReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Clients  *grafana.ClientRegistry
+	Recorder record.EventRecorder
+	// DeletionTimeout bounds how long the deletion of a user waits for Grafana to be reachable
+	DeletionTimeout time.Duration
+}
+
+const (
+	grafanaUserFinalizer = "finalizer.grafana.minicali.com"
+
+	// defaultUserPasswordKey is the key of the password in the Secret when the selector does not give one
+	defaultUserPasswordKey = "password"
+)
+
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanausers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanausers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanausers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=grafana.minicali.com,resources=grafanaorganizations,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile creates the user with the admin credentials of the instance and syncs its organization roles.
+func (r *GrafanaUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("GrafanaUserController").WithValues("GrafanaUser", req.NamespacedName)
+	// Requests to Grafana are logged with the values of the reconcile
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.Info("Starting reconciliation")
+
+	user := &grafanav1beta1.GrafanaUser{}
+	if err := r.Get(ctx, req.NamespacedName, user); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("GrafanaUser resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get GrafanaUser")
+		return ctrl.Result{}, err
+	}
+
+	// Deletion is handled first, it must not be held by an instance that is gone or unreachable
+	if user.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, log, user)
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: user.Spec.GrafanaInstanceRef.Name, Namespace: user.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if err != nil {
+		log.Error(err, "Failed to get GrafanaInstance")
+		return ctrl.Result{}, err
+	}
+
+	// Users are server wide, they are managed with the admin credentials
+	adminClient, adminLogin, err := getAdminClient(ctx, r.Client, r.Clients, instance)
+	if err != nil {
+		if isGrafanaNotReady(err) {
+			log.Info("Grafana is not available, retrying later", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+		}
+		log.Error(err, "Failed to get Grafana admin client")
+		return ctrl.Result{}, err
+	}
+	defer adminClient.Close()
+
+	// Deleting the resource would delete the admin user
+	if user.Spec.Login == adminLogin {
+		return ctrl.Result{}, fmt.Errorf("the user %q is managed by the GrafanaInstance", adminLogin)
+	}
+
+	if !containsString(user.Finalizers, grafanaUserFinalizer) {
+		user.Finalizers = append(user.Finalizers, grafanaUserFinalizer)
+		if err := r.Update(ctx, user); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	password, passwordVersion, err := r.getPassword(ctx, user)
+	if err != nil {
+		log.Error(err, "Failed to get the password of the user")
+		return ctrl.Result{}, err
+	}
+
+	userID, created, err := adminClient.EnsureUser(ctx, log, user.Status.UserID, user.Spec.Login, user.Spec.Email, user.GetDisplayName(), password)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// A user found by login existed before, its password is left alone and it is left in Grafana on deletion
+	if user.Status.UserID != userID {
+		if !created {
+			r.Recorder.Event(user, corev1.EventTypeNormal, "Adopted", "User already in Grafana taken over, its password is left alone")
+		}
+		user.Status.Created = created
+	}
+	// A new user was created with the current password
+	if user.Status.Created && !created && user.Spec.PasswordSecretRef != nil && passwordVersion != user.Status.PasswordSecretVersion {
+		if err := adminClient.SetUserPassword(ctx, log, userID, password); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Record the user before syncing its roles, so it is deleted with the resource even if the sync fails
+	if user.Status.UserID != userID || user.Status.PasswordSecretVersion != passwordVersion {
+		user.Status.UserID = userID
+		user.Status.PasswordSecretVersion = passwordVersion
+		if err := r.Status().Update(ctx, user); err != nil {
+			log.Error(err, "Failed to update GrafanaUser status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := adminClient.SetGrafanaAdmin(ctx, log, userID, user.Spec.GrafanaAdmin); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	orgIDs := make([]int64, 0, len(user.Spec.OrgRoles))
+	for _, orgRole := range user.Spec.OrgRoles {
+		orgID, err := getOrganizationID(ctx, r.Client, user.Namespace, user.Spec.GrafanaInstanceRef, orgRole.OrgRef, orgRole.OrgID)
+		if err != nil {
+			if isGrafanaNotReady(err) {
+				log.Info("Organization is not available, retrying later", "reason", err.Error())
+				return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if err := adminClient.SetOrganizationRole(ctx, log, orgID, userID, user.Spec.Login, orgRole.Role); err != nil {
+			return ctrl.Result{}, err
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+
+	// Grafana adds every user to the default organization, it is left alone
+	for _, orgID := range user.Status.OrgIDs {
+		if orgID == grafana.DefaultOrgID || containsOrgID(orgIDs, orgID) {
+			continue
+		}
+		if err := adminClient.RemoveOrganizationUser(ctx, log, orgID, userID); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	user.Status.OrgIDs = orgIDs
+	if err := r.Status().Update(ctx, user); err != nil {
+		log.Error(err, "Failed to update GrafanaUser status")
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Finished reconciliation", "userID", userID)
+	return ctrl.Result{RequeueAfter: accessResyncPeriod}, nil
+}
+
+// getPassword returns the password of the user and the resource version of its Secret.
+// Without a Secret, a random password is returned, only used when the user is created.
+func (r *GrafanaUserReconciler) getPassword(ctx context.Context, user *grafanav1beta1.GrafanaUser) (string, string, error) {
+	selector := user.Spec.PasswordSecretRef
+	if selector == nil {
+		password, err := helpers.GenerateRandomString(32)
+		return password, "", err
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: selector.Name, Namespace: user.Namespace}, secret); err != nil {
+		return "", "", err
+	}
+	key := selector.Key
+	if key == "" {
+		key = defaultUserPasswordKey
+	}
+	password, ok := secret.Data[key]
+	if !ok || len(password) == 0 {
+		return "", "", fmt.Errorf("key %q not found in Secret %s/%s", key, secret.Namespace, secret.Name)
+	}
+	return string(password), secret.ResourceVersion, nil
+}
+
+// reconcileDeletion deletes the user from Grafana and releases the finalizer. While Grafana cannot be reached,
+// the deletion is retried until the deletion timeout, then the finalizer is released anyway.
+func (r *GrafanaUserReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, user *grafanav1beta1.GrafanaUser) (ctrl.Result, error) {
+	if !containsString(user.Finalizers, grafanaUserFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	err := r.deleteUser(ctx, log, user)
+	switch {
+	case err == nil:
+	case time.Since(user.DeletionTimestamp.Time) >= r.DeletionTimeout:
+		log.Error(err, "Giving up deleting the Grafana user", "timeout", r.DeletionTimeout)
+		r.Recorder.Eventf(user, corev1.EventTypeWarning, "DeletionAbandoned", "User left in Grafana, it could not be deleted within %s: %v", r.DeletionTimeout, err)
+	default:
+		log.Info("Failed to delete the Grafana user, retrying later", "reason", err.Error())
+		r.Recorder.Eventf(user, corev1.EventTypeWarning, "DeletionFailed", "User could not be deleted from Grafana, retrying: %v", err)
+		return ctrl.Result{RequeueAfter: serviceAccountRequeueDelay}, nil
+	}
+
+	return ctrl.Result{}, r.removeFinalizer(ctx, user)
+}
+
+// deleteUser deletes the user from Grafana, a user the operator did not create is only removed from the
+// organizations it was given a role in. Nothing is left to clean up once the instance is gone.
+func (r *GrafanaUserReconciler) deleteUser(ctx context.Context, log logr.Logger, user *grafanav1beta1.GrafanaUser) error {
+	if user.Status.UserID == 0 {
+		return nil
+	}
+
+	instance := &grafanav1beta1.GrafanaInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: user.Spec.GrafanaInstanceRef.Name, Namespace: user.Spec.GrafanaInstanceRef.Namespace}, instance)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	adminClient, _, err := getAdminClient(ctx, r.Client, r.Clients, instance)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	if !user.Status.Created {
+		log.Info("Leaving Grafana user the operator did not create", "ID", user.Status.UserID)
+		// Grafana adds every user to the default organization, it is left alone
+		for _, orgID := range user.Status.OrgIDs {
+			if orgID == grafana.DefaultOrgID {
+				continue
+			}
+			if err := adminClient.RemoveOrganizationUser(ctx, log, orgID, user.Status.UserID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return adminClient.DeleteUser(ctx, log, user.Status.UserID)
+}
+
+func (r *GrafanaUserReconciler) removeFinalizer(ctx context.Context, user *grafanav1beta1.GrafanaUser) error {
+	if !containsString(user.Finalizers, grafanaUserFinalizer) {
+		return nil
+	}
+	user.Finalizers = removeString(user.Finalizers, grafanaUserFinalizer)
+	return r.Update(ctx, user)
+}
+
+func containsOrgID(orgIDs []int64, orgID int64) bool {
+	for _, id := range orgIDs {
+		if id == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&grafanav1beta1.GrafanaUser{}).
+		Complete(r)
+}
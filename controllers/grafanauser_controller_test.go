@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+)
+
+var _ = Describe("GrafanaUserReconciler", func() {
+	var (
+		ctx      context.Context
+		user     *grafanav1beta1.GrafanaUser
+		instance *grafanav1beta1.GrafanaInstance
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		user = &grafanav1beta1.GrafanaUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default", Finalizers: []string{grafanaUserFinalizer}},
+			Spec:       grafanav1beta1.GrafanaUserSpec{GrafanaInstanceRef: grafanav1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "default"}},
+			Status:     grafanav1beta1.GrafanaUserStatus{UserID: 7},
+		}
+		// The service URL of the instance is not known yet, Grafana cannot be reached
+		instance = &grafanav1beta1.GrafanaInstance{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "default"}}
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	DescribeTable("handles the deletion of the user",
+		func(deletedSince time.Duration, released bool, event string) {
+			user.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedSince)}
+			r := &GrafanaUserReconciler{
+				Client:          fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(user, instance).Build(),
+				Scheme:          scheme.Scheme,
+				Clients:         grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0),
+				Recorder:        recorder,
+				DeletionTimeout: 10 * time.Minute,
+			}
+
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(user)})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Without its finalizer, the deleted user is gone
+			err = r.Get(ctx, client.ObjectKeyFromObject(user), &grafanav1beta1.GrafanaUser{})
+			if released {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).NotTo(BeZero(), "the deletion is retried")
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+		},
+		Entry("retried within the timeout", time.Minute, false, "DeletionFailed"),
+		Entry("released after the timeout", time.Hour, true, "DeletionAbandoned"),
+	)
+
+	Context("when Grafana is reachable", func() {
+		var (
+			mu       sync.Mutex
+			requests []string
+			objects  []client.Object
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.URL.Path == "/api/health" {
+					_, _ = w.Write([]byte(`{"database": "ok", "version": "9.5.0"}`))
+					return
+				}
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			DeferCleanup(grafanaServer.Close)
+
+			instance.Spec.CredentialsSecretName = "grafana-admin"
+			instance.Status.GrafanaUI.ServiceURL = grafanaServer.URL
+			objects = []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "grafana-admin", Namespace: "default"},
+				Data: map[string][]byte{
+					grafanav1beta1.DefaultUsernameKey: []byte("admin"),
+					grafanav1beta1.DefaultPasswordKey: []byte("admin"),
+				},
+			}}
+			user.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			user.Status.OrgIDs = []int64{grafana.DefaultOrgID, 2}
+		})
+
+		DescribeTable("deletes the users it created",
+			func(created bool, expected []string) {
+				user.Status.Created = created
+				r := &GrafanaUserReconciler{
+					Client:          fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, user, instance)...).Build(),
+					Scheme:          scheme.Scheme,
+					Clients:         grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0),
+					Recorder:        recorder,
+					DeletionTimeout: 10 * time.Minute,
+				}
+
+				_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(user)})
+				Expect(err).NotTo(HaveOccurred())
+				mu.Lock()
+				defer mu.Unlock()
+				Expect(requests).To(Equal(expected))
+				err = r.Get(ctx, client.ObjectKeyFromObject(user), &grafanav1beta1.GrafanaUser{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the finalizer is released, got %v", err)
+			},
+			Entry("created user deleted", true, []string{"DELETE /api/admin/users/7"}),
+			Entry("adopted user removed from the organizations", false, []string{"DELETE /api/orgs/2/users/7"}),
+		)
+	})
+})
@@ -2,9 +2,20 @@ package grafana
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 
 	gapi "github.com/grafana/grafana-api-golang-client"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 type Authenticator interface {
@@ -28,6 +39,152 @@ func (auth *BasicAuthenticator) ApplyCredentials(config *gapi.Config) {
 	config.BasicAuth = url.UserPassword(auth.Username, auth.Password)
 }
 
+// OAuth2Authenticator adds an access token obtained through the OAuth2 client credentials flow to every request.
+// With the default Authorization header the token replaces the Grafana credentials,
+// Proxy-Authorization keeps them for proxies accepting it.
+type OAuth2Authenticator struct {
+	Header      string
+	TokenSource oauth2.TokenSource
+
+	// tokenSourceKey is the key of the cached token source
+	tokenSourceKey string
+}
+
+// oauth2TokenSources caches the token sources across clients so tokens are reused until they expire
+var oauth2TokenSources sync.Map
+
+// NewOAuth2Authenticator returns an authenticator sharing a cached token source with every
+// authenticator created for the same token URL, client and scopes.
+func NewOAuth2Authenticator(tokenURL string, clientID string, clientSecret string, scopes []string, header string) *OAuth2Authenticator {
+	hash := sha256.Sum256([]byte(strings.Join(append([]string{tokenURL, clientID, clientSecret}, scopes...), "\n")))
+	key := hex.EncodeToString(hash[:])
+
+	tokenSource, ok := oauth2TokenSources.Load(key)
+	if !ok {
+		config := &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       scopes,
+		}
+		// The token source outlives any reconcile, it must not be bound to its context
+		tokenSource, _ = oauth2TokenSources.LoadOrStore(key, config.TokenSource(context.Background()))
+	}
+
+	if header == "" {
+		header = "Authorization"
+	}
+
+	return &OAuth2Authenticator{
+		Header:         header,
+		TokenSource:    tokenSource.(oauth2.TokenSource),
+		tokenSourceKey: key,
+	}
+}
+
+// releaseOAuth2TokenSources drops the cached token sources of the authenticators, the clients still
+// using them keep working and the next authenticators fetch a new token
+func releaseOAuth2TokenSources(auths []Authenticator) {
+	for _, auth := range auths {
+		if oauth2Auth, ok := auth.(*OAuth2Authenticator); ok && oauth2Auth.tokenSourceKey != "" {
+			oauth2TokenSources.Delete(oauth2Auth.tokenSourceKey)
+		}
+	}
+}
+
+func (auth *OAuth2Authenticator) ApplyCredentials(config *gapi.Config) {
+	config.Client = withTransport(config.Client, &oauth2Transport{
+		header: auth.Header,
+		source: auth.TokenSource,
+		base:   getTransport(config.Client),
+	})
+}
+
+type oauth2Transport struct {
+	header string
+	source oauth2.TokenSource
+	base   http.RoundTripper
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth2 token: %w", err)
+	}
+
+	// RoundTrippers must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, token.Type()+" "+token.AccessToken)
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the base transport
+func (t *oauth2Transport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+// MTLSAuthenticator presents a client certificate on every connection.
+type MTLSAuthenticator struct {
+	Certificate tls.Certificate
+	RootCAs     *x509.CertPool
+}
+
+// NewMTLSAuthenticator parses the PEM encoded client certificate and key.
+// The CA bundle is optional, the system roots are used without it.
+func NewMTLSAuthenticator(certPEM []byte, keyPEM []byte, caPEM []byte) (*MTLSAuthenticator, error) {
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	auth := &MTLSAuthenticator{
+		Certificate: certificate,
+	}
+	if len(caPEM) > 0 {
+		auth.RootCAs = x509.NewCertPool()
+		if !auth.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("failed to parse CA certificate")
+		}
+	}
+
+	return auth, nil
+}
+
+// ApplyCredentials sets the TLS configuration on the client transport.
+// It must be applied before any authenticator wrapping the transport.
+func (auth *MTLSAuthenticator) ApplyCredentials(config *gapi.Config) {
+	transport, ok := getTransport(config.Client).(*http.Transport)
+	if !ok {
+		return
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = &tls.Config{
+		Certificates: []tls.Certificate{auth.Certificate},
+		RootCAs:      auth.RootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	config.Client = withTransport(config.Client, transport)
+}
+
+// getTransport returns the transport of the client, or the default one
+func getTransport(client *http.Client) http.RoundTripper {
+	if client == nil || client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return client.Transport
+}
+
+// withTransport returns a copy of the client using the given transport
+func withTransport(client *http.Client, transport http.RoundTripper) *http.Client {
+	newClient := &http.Client{}
+	if client != nil {
+		*newClient = *client
+	}
+	newClient.Transport = transport
+	return newClient
+}
+
 type (
 	authenticatorCtxKey struct{}
 	clientCtxKey        struct{}
@@ -0,0 +1,228 @@
+package grafana
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// newHealthHandler returns a handler answering the Grafana health endpoint,
+// checking each request before.
+func newHealthHandler(check func(*http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(r); err != nil {
+			GinkgoWriter.Println(err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"database": "ok", "version": "10.0.0"})
+	}
+}
+
+// newTokenServer returns an OAuth2 token endpoint issuing tokens valid for the given duration.
+func newTokenServer(expiresIn time.Duration, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if clientID, clientSecret, ok := r.BasicAuth(); !ok || clientID != "operator" || clientSecret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		n := atomic.AddInt32(issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   int(expiresIn.Seconds()),
+		})
+	}))
+}
+
+// newClientCertificate generates a self-signed client certificate, returning it PEM encoded.
+func newClientCertificate() (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "grafana-operator"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return certificate,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var _ = Describe("OAuth2Authenticator", func() {
+	var (
+		issued      int32
+		tokenServer *httptest.Server
+	)
+
+	BeforeEach(func() {
+		issued = 0
+		tokenServer = newTokenServer(time.Hour, &issued)
+		DeferCleanup(tokenServer.Close)
+	})
+
+	It("caches the token across the clients", func() {
+		grafanaServer := httptest.NewServer(newHealthHandler(func(r *http.Request) error {
+			if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+				return fmt.Errorf("unexpected Authorization header %q", got)
+			}
+			return nil
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		for i := 0; i < 3; i++ {
+			auth := NewOAuth2Authenticator(tokenServer.URL, "operator", "s3cr3t", []string{"grafana"}, "")
+			gc, err := NewClient(grafanaServer.URL, DefaultTransportOptions(), auth)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = gc.IsGrafanaHealthy(context.Background())
+			Expect(err).NotTo(HaveOccurred(), "request %d", i)
+		}
+
+		Expect(atomic.LoadInt32(&issued)).To(BeEquivalentTo(1))
+	})
+
+	Context("when the tokens expire", func() {
+		BeforeEach(func() {
+			// Tokens expiring within the expiry delta of the oauth2 package are refreshed on every use
+			tokenServer = newTokenServer(time.Second, &issued)
+			DeferCleanup(tokenServer.Close)
+		})
+
+		It("refreshes the expired token", func() {
+			grafanaServer := httptest.NewServer(newHealthHandler(func(r *http.Request) error {
+				if got, want := r.Header.Get("Authorization"), fmt.Sprintf("Bearer token-%d", atomic.LoadInt32(&issued)); got != want {
+					return fmt.Errorf("unexpected Authorization header %q, want %q", got, want)
+				}
+				return nil
+			}))
+			DeferCleanup(grafanaServer.Close)
+
+			gc, err := NewClient(grafanaServer.URL, DefaultTransportOptions(), NewOAuth2Authenticator(tokenServer.URL, "operator", "s3cr3t", nil, ""))
+			Expect(err).NotTo(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				_, err := gc.IsGrafanaHealthy(context.Background())
+				Expect(err).NotTo(HaveOccurred(), "request %d", i)
+			}
+
+			Expect(atomic.LoadInt32(&issued)).To(BeEquivalentTo(2))
+		})
+	})
+
+	It("keeps the credentials when the token goes in the Proxy-Authorization header", func() {
+		grafanaServer := httptest.NewServer(newHealthHandler(func(r *http.Request) error {
+			if got := r.Header.Get("Proxy-Authorization"); got != "Bearer token-1" {
+				return fmt.Errorf("unexpected Proxy-Authorization header %q", got)
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer glsa_token" {
+				return fmt.Errorf("unexpected Authorization header %q", got)
+			}
+			return nil
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		gc, err := NewClient(grafanaServer.URL, DefaultTransportOptions(),
+			NewOAuth2Authenticator(tokenServer.URL, "operator", "s3cr3t", []string{"proxy"}, "Proxy-Authorization"),
+			&APITokenAuthenticator{Token: "glsa_token"},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gc.IsGrafanaHealthy(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("MTLSAuthenticator", func() {
+	var (
+		clientCert    *x509.Certificate
+		certPEM       []byte
+		keyPEM        []byte
+		caPEM         []byte
+		grafanaServer *httptest.Server
+		auth          *MTLSAuthenticator
+	)
+
+	BeforeEach(func() {
+		clientCert, certPEM, keyPEM = newClientCertificate()
+
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(clientCert)
+
+		grafanaServer = httptest.NewUnstartedServer(newHealthHandler(func(r *http.Request) error {
+			if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "grafana-operator" {
+				return fmt.Errorf("missing client certificate")
+			}
+			return nil
+		}))
+		grafanaServer.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		grafanaServer.StartTLS()
+		DeferCleanup(grafanaServer.Close)
+
+		caPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: grafanaServer.Certificate().Raw})
+
+		var err error
+		auth, err = NewMTLSAuthenticator(certPEM, keyPEM, caPEM)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("presents the client certificate", func() {
+		gc, err := NewClient(grafanaServer.URL, DefaultTransportOptions(), auth, &BasicAuthenticator{Username: "admin", Password: "admin"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gc.IsGrafanaHealthy(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails the handshake without a client certificate", func() {
+		auth.Certificate = tls.Certificate{}
+		gc, err := NewClient(grafanaServer.URL, DefaultTransportOptions(), auth)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gc.IsGrafanaHealthy(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an invalid certificate", func() {
+		_, err := NewMTLSAuthenticator([]byte("invalid"), keyPEM, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an invalid CA bundle", func() {
+		_, err := NewMTLSAuthenticator(certPEM, keyPEM, []byte("invalid"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,38 +1,164 @@
 package grafana
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 
 	gapi "github.com/grafana/grafana-api-golang-client"
 )
 
+// GrafanaClient wraps the Grafana API client. Every call is bound to a context
+// so that cancelling a reconcile aborts its in-flight requests.
 type GrafanaClient struct {
-	Client *gapi.Client
+	apiURL     string
+	config     gapi.Config
+	httpClient *http.Client
 }
 
-func NewClient(apiURL string, timeout time.Duration, auth Authenticator) (*GrafanaClient, error) {
+// NewClient creates a Grafana client, applying the authenticators in order.
+// Retries and rate limiting wrap the transport set up by the authenticators.
+func NewClient(apiURL string, options TransportOptions, auths ...Authenticator) (*GrafanaClient, error) {
 	clientConfig := gapi.Config{
 		HTTPHeaders: nil,
 		Client: &http.Client{
-			Timeout: time.Second * timeout,
+			// Every client keeps its own connection pool, released by Close
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
 		},
-		OrgID:      0,
+		OrgID: 0,
+		// Retries are handled by the transport
 		NumRetries: 0,
 	}
-	auth.ApplyCredentials(&clientConfig)
+	for _, auth := range auths {
+		auth.ApplyCredentials(&clientConfig)
+	}
+	clientConfig.Client = withTransport(clientConfig.Client, &retryTransport{
+		options: options,
+		base:    getTransport(clientConfig.Client),
+	})
 
-	grafanaClient, err := gapi.New(apiURL, clientConfig)
-	if err != nil {
+	// Validate the configuration once, per call clients are created from it
+	if _, err := gapi.New(apiURL, clientConfig); err != nil {
 		return nil, err
 	}
 
 	return &GrafanaClient{
-		Client: grafanaClient,
+		apiURL:     apiURL,
+		config:     clientConfig,
+		httpClient: clientConfig.Client,
 	}, nil
 }
 
+// api returns a Grafana API client whose requests are bound to the context.
+// The clients share the connection pool, retries and rate limiter of the GrafanaClient.
+func (gc *GrafanaClient) api(ctx context.Context) *gapi.Client {
+	config := gc.config
+	config.Client = withTransport(gc.httpClient, &contextTransport{
+		ctx:  ctx,
+		base: getTransport(gc.httpClient),
+	})
+
+	// The configuration was validated by NewClient
+	client, _ := gapi.New(gc.apiURL, config)
+	return client
+}
+
+// do sends a request to an endpoint the Grafana API client does not cover,
+// authenticated and bound to the context like the requests of the Grafana API client.
+// The body is encoded and the response decoded as JSON, errors read like the ones of the Grafana API client.
+func (gc *GrafanaClient) do(ctx context.Context, method string, requestPath string, body interface{}, out interface{}) error {
+	requestURL, err := url.Parse(gc.apiURL)
+	if err != nil {
+		return err
+	}
+	// The request path may carry a query
+	requestRef, err := url.Parse(requestPath)
+	if err != nil {
+		return err
+	}
+	requestURL.Path = path.Join(requestURL.Path, requestRef.Path)
+	requestURL.RawQuery = requestRef.RawQuery
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL.String(), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range gc.config.HTTPHeaders {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case gc.config.APIKey != "":
+		req.Header.Set("Authorization", "Bearer "+gc.config.APIKey)
+	case gc.config.BasicAuth != nil:
+		password, _ := gc.config.BasicAuth.Password()
+		req.SetBasicAuth(gc.config.BasicAuth.Username(), password)
+	}
+	if gc.config.OrgID != 0 {
+		req.Header.Set("X-Grafana-Org-Id", strconv.FormatInt(gc.config.OrgID, 10))
+	}
+
+	httpClient := withTransport(gc.httpClient, &contextTransport{
+		ctx:  ctx,
+		base: getTransport(gc.httpClient),
+	})
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status: %d, body: %v", resp.StatusCode, string(contents))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(contents, out)
+}
+
+// Close releases the idle connections of the client.
+func (gc *GrafanaClient) Close() {
+	gc.httpClient.CloseIdleConnections()
+}
+
+// FromContext returns the client stored with WithGrafanaClient, or nil.
 func FromContext(ctx context.Context) *GrafanaClient {
-	return ctx.Value(clientCtxKey{}).(*GrafanaClient)
+	gc, _ := ctx.Value(clientCtxKey{}).(*GrafanaClient)
+	return gc
+}
+
+// IsNotFound reports whether the Grafana API answered a request with 404
+func IsNotFound(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "status: 404")
+}
+
+// IsUnauthorized reports whether the Grafana API answered a request with 401
+func IsUnauthorized(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "status: 401")
+}
+
+// IsConflict reports whether the Grafana API answered a request with 409
+func IsConflict(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "status: 409")
 }
@@ -1,47 +1,97 @@
 package grafana
 
 import (
-	"errors"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	grapi "github.com/grafana/grafana-api-golang-client"
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
-	"github.com/minicali/grafana-operator/internal/helpers"
 )
 
-func (gc *GrafanaClient) UpsertDashboard(log logr.Logger, cr *grafanav1alpha1.GrafanaDashboard, folderUID string) (string, error) {
+// ManagedTag is the tag of the dashboards and annotations created by the operator
+const ManagedTag = "grafana-operator"
+
+// ownerTagPrefix prefixes the tag naming the resource managing a dashboard
+const ownerTagPrefix = ManagedTag + ":"
+
+// OwnerTag returns the tag naming the resource managing a dashboard. Grafana stores tags of up to
+// 50 characters, so the namespace and the name of the resource are hashed.
+func OwnerTag(namespace string, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	return ownerTagPrefix + hex.EncodeToString(sum[:12])
+}
+
+// ExistingDashboard is a dashboard found in Grafana
+type ExistingDashboard struct {
+	UID         string
+	Title       string
+	FolderUID   string
+	FolderTitle string
+	// Managed reports whether the dashboard carries the tag of the operator
+	Managed bool
+	// Owner is the tag naming the resource managing the dashboard, empty when it carries none
+	Owner string
+}
+
+// LiveDashboard is a dashboard as stored in Grafana, with the metadata of its latest version
+type LiveDashboard struct {
+	// Model of the dashboard, without the id and the version specific to the Grafana database nor the tags of the operator
+	Model     map[string]interface{}
+	FolderUID string
+	Version   int64
+	UpdatedBy string
+	Updated   time.Time
+}
+
+// DashboardVersion is a version of a dashboard saved in Grafana
+type DashboardVersion struct {
+	Version   int64     `json:"version"`
+	CreatedBy string    `json:"createdBy"`
+	Message   string    `json:"message"`
+	Created   time.Time `json:"created"`
+}
+
+// dashboardSearchLimit is the number of dashboards listed per page of search, the maximum Grafana accepts
+const dashboardSearchLimit = 5000
+
+// UpsertDashboard creates or overwrites the dashboard in the folder and returns its UID and its new version,
+// saved with the message. The dashboard is tagged as managed by the operator, with the tag of its owner.
+func (gc *GrafanaClient) UpsertDashboard(ctx context.Context, log logr.Logger, dashboardModel map[string]interface{}, folderUID string, owner string, message string) (string, int64, error) {
 	log = log.WithValues("Resource", "Dashboard")
 
-	dashboardModel, err := getModelFromCR(cr)
-	if err != nil {
-		log.Error(err, "Failed to create/update Grafana dashboard")
-		return "", err
-	}
+	tags, _ := dashboardModel["tags"].([]interface{})
+	dashboardModel["tags"] = append(withoutOperatorTags(tags), ManagedTag, owner)
 
-	resp, err := gc.Client.NewDashboard(grapi.Dashboard{
+	resp, err := gc.api(ctx).NewDashboard(grapi.Dashboard{
 		Model:     dashboardModel,
 		FolderUID: folderUID,
 		Overwrite: true,
-		Message:   "Upserted by Grafana-Operator at " + time.Now().Format("2006-01-02 15:04:05"),
+		Message:   message,
 	})
 
 	if err != nil {
 		log.Error(err, "Failed to create/update Grafana dashboard")
-		return "", err
+		return "", 0, err
 	}
 
 	if resp.Status != "success" {
 		log.Error(nil, "Error creating dashboard, status was not 'success'", "status", resp.Status)
-		return "", fmt.Errorf("error creating dashboard, status was %v", resp.Status)
+		return "", 0, fmt.Errorf("error creating dashboard, status was %v", resp.Status)
 	}
 
-	log.Info("Successfully created/updated Grafana dashboard", "dashboardName", cr.Spec.Name)
-	return resp.UID, nil
+	log.Info("Successfully created/updated Grafana dashboard", "dashboardUID", resp.UID, "version", resp.Version)
+	return resp.UID, resp.Version, nil
 }
 
-func (gc *GrafanaClient) DeleteDashboard(log logr.Logger, dashboardUID string) error {
+func (gc *GrafanaClient) DeleteDashboard(ctx context.Context, log logr.Logger, dashboardUID string) error {
 	log = log.WithValues("Resource", "Dashboard")
 
 	if dashboardUID == "" {
@@ -49,7 +99,7 @@ func (gc *GrafanaClient) DeleteDashboard(log logr.Logger, dashboardUID string) e
 		return fmt.Errorf("error deleting dashboard, UID is missing")
 	}
 
-	err := gc.Client.DeleteDashboardByUID(dashboardUID)
+	err := gc.api(ctx).DeleteDashboardByUID(dashboardUID)
 	if err != nil {
 		log.Error(err, "Failed to delete Grafana dashboard")
 		return err
@@ -59,12 +109,233 @@ func (gc *GrafanaClient) DeleteDashboard(log logr.Logger, dashboardUID string) e
 	return nil
 }
 
-func getModelFromCR(cr *grafanav1alpha1.GrafanaDashboard) (map[string]interface{}, error) {
+// AnnotateDashboard adds an annotation with the text to the dashboard, tagged as coming from the operator.
+func (gc *GrafanaClient) AnnotateDashboard(ctx context.Context, log logr.Logger, dashboardUID string, text string) error {
+	log = log.WithValues("Resource", "Annotation")
+
+	_, err := gc.api(ctx).NewAnnotation(&grapi.Annotation{
+		DashboardUID: dashboardUID,
+		Time:         time.Now().UnixMilli(),
+		Text:         text,
+		Tags:         []string{ManagedTag},
+	})
+	if err != nil {
+		log.Error(err, "Failed to annotate Grafana dashboard", "dashboardUID", dashboardUID)
+		return fmt.Errorf("failed to annotate Grafana dashboard: %w", err)
+	}
+	return nil
+}
+
+// FindDashboards returns the dashboards an upsert of the dashboard would overwrite: the one with the UID,
+// and the ones with the same title in the folder under another UID. The title is not searched when empty.
+func (gc *GrafanaClient) FindDashboards(ctx context.Context, log logr.Logger, dashboardUID string, title string, folderUID string) ([]ExistingDashboard, error) {
+	log = log.WithValues("Resource", "Dashboard")
+
+	var found []ExistingDashboard
+	dashboard, err := gc.api(ctx).DashboardByUID(dashboardUID)
 	switch {
-	case cr.Spec.Json.Raw != nil:
-		return helpers.UnmarshalJSONToMap(cr.Spec.Json)
-	default:
+	case err == nil:
+		existingTitle, _ := dashboard.Model["title"].(string)
+		tags, _ := dashboard.Model["tags"].([]interface{})
+		found = append(found, ExistingDashboard{
+			UID:       dashboardUID,
+			Title:     existingTitle,
+			FolderUID: dashboard.Meta.FolderUID,
+			Managed:   containsTag(tags, ManagedTag),
+			Owner:     ownerTag(tags),
+		})
+	case !IsNotFound(err):
+		log.Error(err, "Failed to get Grafana dashboard", "dashboardUID", dashboardUID)
+		return nil, err
+	}
+
+	if title == "" {
+		return found, nil
+	}
+
+	// The search matches the titles containing the query, in any folder
+	results, err := gc.api(ctx).FolderDashboardSearch(url.Values{"query": {title}, "type": {"dash-db"}})
+	if err != nil {
+		log.Error(err, "Failed to search Grafana dashboards", "title", title)
+		return nil, err
+	}
+	for _, result := range results {
+		if result.UID == dashboardUID || result.FolderUID != folderUID || !strings.EqualFold(result.Title, title) {
+			continue
+		}
+		found = append(found, existingDashboard(result))
+	}
+	return found, nil
+}
+
+// ListDashboards returns the dashboards of the organization carrying all the tags
+func (gc *GrafanaClient) ListDashboards(ctx context.Context, log logr.Logger, tags []string) ([]ExistingDashboard, error) {
+	log = log.WithValues("Resource", "Dashboard")
+
+	var dashboards []ExistingDashboard
+	for page := 1; ; page++ {
+		params := url.Values{
+			"type":  {"dash-db"},
+			"tag":   tags,
+			"limit": {strconv.Itoa(dashboardSearchLimit)},
+			"page":  {strconv.Itoa(page)},
+		}
+		results, err := gc.api(ctx).FolderDashboardSearch(params)
+		if err != nil {
+			log.Error(err, "Failed to list Grafana dashboards")
+			return nil, err
+		}
+		for _, result := range results {
+			dashboards = append(dashboards, existingDashboard(result))
+		}
+		if len(results) < dashboardSearchLimit {
+			return dashboards, nil
+		}
+	}
+}
+
+// GetLiveDashboard returns the dashboard as stored in Grafana. The API client drops the version metadata.
+func (gc *GrafanaClient) GetLiveDashboard(ctx context.Context, log logr.Logger, dashboardUID string) (*LiveDashboard, error) {
+	var resp struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      struct {
+			FolderUID string    `json:"folderUid"`
+			Version   int64     `json:"version"`
+			UpdatedBy string    `json:"updatedBy"`
+			Updated   time.Time `json:"updated"`
+		} `json:"meta"`
+	}
+	if err := gc.do(ctx, http.MethodGet, "/api/dashboards/uid/"+url.PathEscape(dashboardUID), nil, &resp); err != nil {
+		if !IsNotFound(err) {
+			log.WithValues("Resource", "Dashboard").Error(err, "Failed to get Grafana dashboard", "dashboardUID", dashboardUID)
+		}
+		return nil, err
+	}
+
+	model := resp.Dashboard
+	delete(model, "id")
+	delete(model, "version")
+	if tags, ok := model["tags"].([]interface{}); ok {
+		kept := withoutOperatorTags(tags)
+		model["tags"] = kept
+		// The tags were only added to carry the ones of the operator
+		if len(kept) == 0 && len(tags) > 0 {
+			delete(model, "tags")
+		}
+	}
+	return &LiveDashboard{
+		Model:     model,
+		FolderUID: resp.Meta.FolderUID,
+		Version:   resp.Meta.Version,
+		UpdatedBy: resp.Meta.UpdatedBy,
+		Updated:   resp.Meta.Updated,
+	}, nil
+}
+
+// ReleaseDashboard removes the tags of the operator from the dashboard, saved with the message,
+// so no resource takes it over without adopting it
+func (gc *GrafanaClient) ReleaseDashboard(ctx context.Context, log logr.Logger, dashboardUID string, message string) error {
+	live, err := gc.GetLiveDashboard(ctx, log, dashboardUID)
+	if err != nil {
+		return err
+	}
+
+	_, err = gc.api(ctx).NewDashboard(grapi.Dashboard{
+		Model:     live.Model,
+		FolderUID: live.FolderUID,
+		Overwrite: true,
+		Message:   message,
+	})
+	if err != nil {
+		log.WithValues("Resource", "Dashboard").Error(err, "Failed to release Grafana dashboard", "dashboardUID", dashboardUID)
+		return err
+	}
+	return nil
+}
+
+// ListDashboardVersions returns the latest versions of the dashboard, newest first
+func (gc *GrafanaClient) ListDashboardVersions(ctx context.Context, log logr.Logger, dashboardUID string, limit int) ([]DashboardVersion, error) {
+	// Recent Grafana versions wrap the versions in a page
+	var resp json.RawMessage
+	requestPath := fmt.Sprintf("/api/dashboards/uid/%s/versions?limit=%d", url.PathEscape(dashboardUID), limit)
+	if err := gc.do(ctx, http.MethodGet, requestPath, nil, &resp); err != nil {
+		log.WithValues("Resource", "Dashboard").Error(err, "Failed to list Grafana dashboard versions", "dashboardUID", dashboardUID)
+		return nil, err
+	}
+
+	var versions []DashboardVersion
+	if err := json.Unmarshal(resp, &versions); err != nil {
+		var page struct {
+			Versions []DashboardVersion `json:"versions"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode the versions of the dashboard: %w", err)
+		}
+		versions = page.Versions
+	}
+	if len(versions) > limit {
+		versions = versions[:limit]
+	}
+	return versions, nil
+}
+
+// RestoreDashboardVersion saves the model of a previous version of the dashboard as its new version
+func (gc *GrafanaClient) RestoreDashboardVersion(ctx context.Context, log logr.Logger, dashboardUID string, version int64) error {
+	log = log.WithValues("Resource", "Dashboard")
+
+	body := map[string]int64{"version": version}
+	if err := gc.do(ctx, http.MethodPost, "/api/dashboards/uid/"+url.PathEscape(dashboardUID)+"/restore", body, nil); err != nil {
+		log.Error(err, "Failed to restore Grafana dashboard version", "dashboardUID", dashboardUID, "version", version)
+		return err
+	}
+	log.Info("Successfully restored Grafana dashboard version", "dashboardUID", dashboardUID, "version", version)
+	return nil
+}
+
+// existingDashboard returns the dashboard of a search result
+func existingDashboard(result grapi.FolderDashboardSearchResponse) ExistingDashboard {
+	tags := make([]interface{}, 0, len(result.Tags))
+	for _, tag := range result.Tags {
+		tags = append(tags, tag)
+	}
+	return ExistingDashboard{
+		UID:         result.UID,
+		Title:       result.Title,
+		FolderUID:   result.FolderUID,
+		FolderTitle: result.FolderTitle,
+		Managed:     containsTag(tags, ManagedTag),
+		Owner:       ownerTag(tags),
+	}
+}
+
+// containsTag reports whether the tags of a dashboard model hold the tag
+func containsTag(tags []interface{}, tag string) bool {
+	for _, item := range tags {
+		if item == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerTag returns the tag naming the resource managing a dashboard, or nothing
+func ownerTag(tags []interface{}) string {
+	for _, item := range tags {
+		if tag, _ := item.(string); strings.HasPrefix(tag, ownerTagPrefix) {
+			return tag
+		}
+	}
+	return ""
+}
 
-		return nil, errors.New("no valid dashboard model found")
+// withoutOperatorTags returns a copy of the tags of a dashboard model without the ones of the operator
+func withoutOperatorTags(tags []interface{}) []interface{} {
+	kept := make([]interface{}, 0, len(tags))
+	for _, item := range tags {
+		if tag, _ := item.(string); tag == ManagedTag || strings.HasPrefix(tag, ownerTagPrefix) {
+			continue
+		}
+		kept = append(kept, item)
 	}
+	return kept
 }
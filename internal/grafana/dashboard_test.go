@@ -0,0 +1,198 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dashboards", func() {
+	var (
+		ctx     context.Context
+		handler http.HandlerFunc
+		gc      *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	JustBeforeEach(func() {
+		grafanaServer := httptest.NewServer(handler)
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("FindDashboards", func() {
+		var query string
+
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/dashboards/uid/managed":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": map[string]interface{}{"uid": "managed", "title": "Managed", "tags": []string{"team", ManagedTag, OwnerTag("default", "managed")}},
+					})
+				case "/api/search":
+					query = r.URL.Query().Get("query")
+					// The search matches titles containing the query, in any folder
+					_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+						{"uid": "same-title", "title": "overview", "folderUid": "ops"},
+						{"uid": "other-folder", "title": "Overview", "folderUid": "dev"},
+						{"uid": "longer-title", "title": "Overview of the cluster", "folderUid": "ops"},
+					})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}
+		})
+
+		It("finds the dashboard with the UID and the one with the same title in the folder", func() {
+			found, err := gc.FindDashboards(ctx, logr.Discard(), "managed", "Overview", "ops")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query).To(Equal("Overview"))
+			Expect(found).To(Equal([]ExistingDashboard{
+				{UID: "managed", Title: "Managed", Managed: true, Owner: OwnerTag("default", "managed")},
+				{UID: "same-title", Title: "overview", FolderUID: "ops"},
+			}))
+		})
+
+		It("finds nothing in the General folder", func() {
+			found, err := gc.FindDashboards(ctx, logr.Discard(), "missing", "Overview", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeEmpty())
+		})
+	})
+
+	Describe("UpsertDashboard", func() {
+		var saved map[string]interface{}
+
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				saved, _ = body["dashboard"].(map[string]interface{})
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "uid": "tagged"})
+			}
+		})
+
+		It("tags the dashboard once with its owner", func() {
+			// The owner tag of another resource is replaced
+			model := map[string]interface{}{"title": "Tagged", "tags": []interface{}{"team", OwnerTag("default", "other")}}
+			owner := OwnerTag("default", "tagged")
+			for i := 0; i < 2; i++ {
+				_, _, err := gc.UpsertDashboard(ctx, logr.Discard(), model, "", owner, "message")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(saved["tags"]).To(Equal([]interface{}{"team", ManagedTag, owner}))
+			}
+		})
+
+		It("fits the owner tag in the 50 characters of a Grafana tag", func() {
+			Expect(len(OwnerTag("a-namespace-of-63-characters-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "name"))).To(BeNumerically("<=", 50))
+		})
+	})
+
+	Describe("ReleaseDashboard", func() {
+		var saved map[string]interface{}
+
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/retained":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"dashboard": map[string]interface{}{"id": 7, "uid": "retained", "title": "Retained", "tags": []string{"team", ManagedTag, OwnerTag("default", "retained")}},
+						"meta":      map[string]interface{}{"folderUid": "ops", "version": 2},
+					})
+				case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+					_ = json.NewDecoder(r.Body).Decode(&saved)
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "uid": "retained"})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}
+		})
+
+		It("removes the tags of the operator and keeps the dashboard in its folder", func() {
+			Expect(gc.ReleaseDashboard(ctx, logr.Discard(), "retained", "released")).To(Succeed())
+			dashboard, _ := saved["dashboard"].(map[string]interface{})
+			Expect(dashboard["tags"]).To(Equal([]interface{}{"team"}))
+			Expect(saved).To(HaveKeyWithValue("folderUid", "ops"))
+			Expect(saved).To(HaveKeyWithValue("message", "released"))
+		})
+	})
+
+	Describe("GetLiveDashboard", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/dashboards/uid/overview" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"dashboard": map[string]interface{}{"id": 7, "version": 5, "uid": "overview", "title": "Overview", "tags": []string{"team", ManagedTag, OwnerTag("default", "overview")}},
+					"meta":      map[string]interface{}{"version": 5, "updatedBy": "jane", "updated": "2023-05-01T12:00:00Z"},
+				})
+			}
+		})
+
+		It("returns the model without the id and the tags of the operator", func() {
+			live, err := gc.GetLiveDashboard(ctx, logr.Discard(), "overview")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(live.Version).To(BeEquivalentTo(5))
+			Expect(live.UpdatedBy).To(Equal("jane"))
+			Expect(live.Updated.IsZero()).To(BeFalse())
+			Expect(live.Model).NotTo(HaveKey("id"))
+			Expect(live.Model["tags"]).To(Equal([]interface{}{"team"}))
+		})
+
+		It("returns a not found error for a missing dashboard", func() {
+			_, err := gc.GetLiveDashboard(ctx, logr.Discard(), "missing")
+			Expect(IsNotFound(err)).To(BeTrue(), "got %v", err)
+		})
+	})
+
+	Describe("ListDashboardVersions", func() {
+		var limit string
+
+		BeforeEach(func() {
+			versions := []map[string]interface{}{
+				{"version": 3, "createdBy": "admin", "message": "Synced", "created": "2023-05-02T12:00:00Z"},
+				{"version": 2, "createdBy": "jane", "message": "", "created": "2023-05-01T12:00:00Z"},
+			}
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				limit = r.URL.Query().Get("limit")
+				switch r.URL.Path {
+				case "/api/dashboards/uid/listed/versions":
+					_ = json.NewEncoder(w).Encode(versions)
+				case "/api/dashboards/uid/paged/versions":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"versions": versions})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}
+		})
+
+		DescribeTable("decodes the versions",
+			func(uid string) {
+				listed, err := gc.ListDashboardVersions(ctx, logr.Discard(), uid, 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(limit).To(Equal("2"))
+				Expect(listed).To(HaveLen(2))
+				Expect(listed[0].Version).To(BeEquivalentTo(3))
+				Expect(listed[0].Message).To(Equal("Synced"))
+				Expect(listed[1].CreatedBy).To(Equal("jane"))
+				Expect(listed[1].Created.IsZero()).To(BeFalse())
+			},
+			Entry("listed", "listed"),
+			Entry("paged", "paged"),
+		)
+	})
+})
@@ -1,84 +1,118 @@
 package grafana
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/go-logr/logr"
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
 )
 
 // getFolderUIDByName retrieves the UID of a Grafana folder by its name.
-// It returns the UID and a error indicating whether the folder was found.
-func (gc *GrafanaClient) getFolderUIDByName(log logr.Logger, folderName string) (string, error) {
+// It returns the UID and whether the folder was found.
+func (gc *GrafanaClient) getFolderUIDByName(ctx context.Context, log logr.Logger, folderName string) (string, bool, error) {
 	log = log.WithValues("Resource", "Folder")
 
-	// exception, pre-existing folder aren't returned from API
-	if IsGeneralFolder(folderName) {
-		return "0", nil
-	}
-
 	log.Info("Listing Grafana folders")
 	// Fetch the list of folders from Grafana
-	folders, err := gc.Client.Folders()
+	folders, err := gc.api(ctx).Folders()
 	if err != nil {
 		log.Error(err, "Failed to list Grafana folders")
-		return "", err
+		return "", false, err
 	}
 
 	// Loop through the folders to find the one that matches `cr.Spec.Folder`
 	for _, folder := range folders {
 		if strings.EqualFold(folder.Title, folderName) {
 			log.Info("Found matching Grafana folder", "folderUID", folder.UID)
-			return folder.UID, nil
+			return folder.UID, true, nil
 		}
 	}
 
 	log.Info("No matching Grafana folder found", "folderName", folderName)
-	return "", fmt.Errorf("Folder '%s' not found", folderName)
+	return "", false, nil
 }
 
 // EnsureFolder ensures that a Grafana folder exists.
-// If the GrafanaDashboard's Status includes a folder ID, it updates the folder with the name.
-// Otherwise, it creates a new folder and returns its ID.
-func (c *GrafanaClient) EnsureFolder(log logr.Logger, cr *grafanav1alpha1.GrafanaDashboard) (string, error) {
-	// Check if UID exists in the status
-	existingUID := cr.Status.FolderUID
+// If the GrafanaDashboard's Status includes a folder the operator created, it updates the folder with the name.
+// Otherwise, it finds the folder by its name or creates a new one, and returns its UID.
+func (c *GrafanaClient) EnsureFolder(ctx context.Context, log logr.Logger, cr *grafanav1beta1.GrafanaDashboard) (string, bool, error) {
+	return c.EnsureFolderTitle(ctx, log, cr.Spec.Folder, cr.Status.FolderUID, cr.Status.FolderCreated)
+}
 
+// EnsureFolderTitle ensures that a Grafana folder with the title exists. The folder with the existing UID is
+// renamed when the operator created it, a folder found by its title may be shared and is left alone.
+// It returns the UID of the folder, none for the General folder, and whether the operator created it.
+func (c *GrafanaClient) EnsureFolderTitle(ctx context.Context, log logr.Logger, title string, existingUID string, created bool) (string, bool, error) {
 	// General folder already exist
-	if IsGeneralFolder(cr.Spec.Folder) {
-		return "", nil
+	if IsGeneralFolder(title) {
+		return "", false, nil
 	}
 
-	// If UID exists, update the folder
-	if existingUID != "" {
+	// If the operator created the folder, update it
+	if existingUID != "" && created {
 		log.Info("Updating existing Grafana folder", "UID", existingUID)
-		err := c.Client.UpdateFolder(existingUID, cr.Spec.Folder)
-		if err != nil {
-			return "", fmt.Errorf("failed to update Grafana folder: %w", err)
+		err := c.api(ctx).UpdateFolder(existingUID, title)
+		if err == nil {
+			return existingUID, true, nil
 		}
-		return existingUID, nil
+		if !IsNotFound(err) {
+			return "", false, fmt.Errorf("failed to update Grafana folder: %w", err)
+		}
+		log.Info("Grafana folder not found anymore", "UID", existingUID)
+	}
+
+	// A folder created by another resource, or in Grafana, is shared
+	uid, found, err := c.getFolderUIDByName(ctx, log, title)
+	if err != nil {
+		return "", false, err
+	}
+	if found {
+		return uid, false, nil
 	}
 
 	// Otherwise, create a new folder
-	log.Info("Creating new Grafana folder", "Title", cr.Spec.Folder)
-	resp, err := c.Client.NewFolder(cr.Spec.Folder)
+	log.Info("Creating new Grafana folder", "Title", title)
+	resp, err := c.api(ctx).NewFolder(title)
 	if err != nil {
-		return "", fmt.Errorf("failed to create new Grafana folder: %w", err)
+		return "", false, fmt.Errorf("failed to create new Grafana folder: %w", err)
+	}
+
+	return resp.UID, true, nil
+}
+
+// FindFolder returns the UID of the folder EnsureFolderTitle syncs to without writing to Grafana, and whether
+// the folder exists already: the folder the operator created, or the one found by its title.
+func (c *GrafanaClient) FindFolder(ctx context.Context, log logr.Logger, title string, existingUID string, created bool) (string, bool, error) {
+	// General folder already exist
+	if IsGeneralFolder(title) {
+		return "", true, nil
+	}
+
+	// The folder the operator created is renamed, if it still exists
+	if existingUID != "" && created {
+		_, err := c.api(ctx).FolderByUID(existingUID)
+		if err == nil {
+			return existingUID, true, nil
+		}
+		if !IsNotFound(err) {
+			return "", false, fmt.Errorf("failed to get Grafana folder: %w", err)
+		}
 	}
 
-	return resp.UID, nil
+	return c.getFolderUIDByName(ctx, log, title)
 }
 
 // GetFolderIDByUID retrieves the folder ID based on its UID.
 // Returns an error if UID is empty or if the API call fails.
-func (gc *GrafanaClient) GetFolderIDByUID(uid string) (int64, error) {
+func (gc *GrafanaClient) GetFolderIDByUID(ctx context.Context, uid string) (int64, error) {
 	if uid == "" {
 		return -1, errors.New("UID cannot be empty")
 	}
 
-	folder, err := gc.Client.FolderByUID(uid)
+	folder, err := gc.api(ctx).FolderByUID(uid)
 	if err != nil {
 		return -1, fmt.Errorf("failed to fetch folder by UID: %w", err)
 	}
@@ -89,7 +123,7 @@ func (gc *GrafanaClient) GetFolderIDByUID(uid string) (int64, error) {
 // IsGeneralFolder checks if the folder is the pre-existing one General.
 // This folder is an exception and isn't included in the API.
 func IsGeneralFolder(folderName string) bool {
-	if strings.EqualFold(folderName, grafanav1alpha1.GrafanaGeneralFolder) {
+	if strings.EqualFold(folderName, grafanav1beta1.GrafanaGeneralFolder) {
 		return true
 	}
 	return false
@@ -0,0 +1,86 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Folders", func() {
+	var (
+		writes []string
+		gc     *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		writes = nil
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && strings.TrimSuffix(r.URL.Path, "/") == "/api/folders":
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"uid": "shared", "title": "Shared"},
+					{"uid": "mine", "title": "Mine"},
+				})
+				return
+			case r.Method == http.MethodGet && r.URL.Path == "/api/folders/gone":
+				w.WriteHeader(http.StatusNotFound)
+				return
+			case r.Method == http.MethodGet:
+				_, _ = w.Write([]byte(`{}`))
+				return
+			}
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writes = append(writes, r.Method+" "+r.URL.Path+" "+body["title"].(string))
+			switch {
+			case r.Method == http.MethodPut && r.URL.Path == "/api/folders/gone":
+				w.WriteHeader(http.StatusNotFound)
+			case r.Method == http.MethodPost && r.URL.Path == "/api/folders":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"uid": "new", "title": body["title"]})
+			default:
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("ensures the folder, only renaming the one the operator created",
+		func(title string, existingUID string, created bool, expectedUID string, expectedCreated bool, expectedWrites []string) {
+			uid, folderCreated, err := gc.EnsureFolderTitle(context.Background(), logr.Discard(), title, existingUID, created)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uid).To(Equal(expectedUID))
+			Expect(folderCreated).To(Equal(expectedCreated))
+			Expect(writes).To(Equal(expectedWrites))
+		},
+		Entry("General folder", "General", "mine", true, "", false, nil),
+		Entry("folder found by its title", "Shared", "", false, "shared", false, nil),
+		Entry("created folder renamed", "Renamed", "mine", true, "mine", true, []string{"PUT /api/folders/mine Renamed"}),
+		Entry("shared folder left alone", "Renamed", "shared", false, "new", true, []string{"POST /api/folders Renamed"}),
+		Entry("created folder gone", "Renamed", "gone", true, "new", true, []string{"PUT /api/folders/gone Renamed", "POST /api/folders Renamed"}),
+	)
+
+	DescribeTable("finds the folder without writing to Grafana",
+		func(title string, existingUID string, created bool, expectedUID string, expectedExists bool) {
+			uid, exists, err := gc.FindFolder(context.Background(), logr.Discard(), title, existingUID, created)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uid).To(Equal(expectedUID))
+			Expect(exists).To(Equal(expectedExists))
+			Expect(writes).To(BeEmpty())
+		},
+		Entry("General folder", "General", "", false, "", true),
+		Entry("folder found by its title", "Shared", "", false, "shared", true),
+		Entry("created folder to rename", "Renamed", "mine", true, "mine", true),
+		Entry("shared folder left alone", "Renamed", "shared", false, "", false),
+		Entry("created folder gone", "Shared", "gone", true, "shared", true),
+	)
+})
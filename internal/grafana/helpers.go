@@ -2,15 +2,21 @@ package grafana
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/helpers"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ErrCredentialsRotationInProgress is returned while the admin credentials are being rotated
+var ErrCredentialsRotationInProgress = errors.New("admin credentials rotation in progress")
+
 // IsGrafanaHealthy checks the health of the Grafana instance using grafanaClient.Health()
-func (gc *GrafanaClient) IsGrafanaHealthy() (bool, error) {
-	healthStatus, err := gc.Client.Health()
+func (gc *GrafanaClient) IsGrafanaHealthy(ctx context.Context) (bool, error) {
+	healthStatus, err := gc.api(ctx).Health()
 	if err != nil {
 		return false, fmt.Errorf("error checking Grafana health: %v", err)
 	}
@@ -23,22 +29,129 @@ func (gc *GrafanaClient) IsGrafanaHealthy() (bool, error) {
 	// return false, fmt.Errorf("received empty health status")
 }
 
-func CreateGrafanaClientFromSecret(ctx context.Context, grafanaInstanceSecret *corev1.Secret, grafanaURL string) (*GrafanaClient, error) {
+// CreateGrafanaClientFromSecret creates a client authenticated with the admin credentials
+// stored in the GrafanaInstance credentials Secret.
+// It returns ErrCredentialsRotationInProgress while the admin password is being rotated.
+func CreateGrafanaClientFromSecret(ctx context.Context, grafanaInstance *grafanav1beta1.GrafanaInstance, grafanaInstanceSecret *corev1.Secret, grafanaURL string, options TransportOptions, auths ...Authenticator) (*GrafanaClient, error) {
+	// The password may already have changed in Grafana, wait for the rotation to complete
+	if helpers.IsCredentialsRotationPending(grafanaInstanceSecret) {
+		return nil, ErrCredentialsRotationInProgress
+	}
+
 	// Retrieve username and password from the secret
-	username := string(grafanaInstanceSecret.Data["admin_username"])
-	password := string(grafanaInstanceSecret.Data["admin_password"])
+	username, password, err := helpers.GetCredentialsFromSecret(grafanaInstanceSecret, grafanaInstance.GetUsernameKey(), grafanaInstance.GetPasswordKey())
+	if err != nil {
+		return nil, err
+	}
 
 	// Create Grafana client
 	auth := &BasicAuthenticator{
 		Username: username,
 		Password: password,
 	}
-	timeout := 5 // adjust as needed
-
-	grafanaClient, err := NewClient(grafanaURL, time.Duration(timeout)*time.Second, auth)
+	grafanaClient, err := NewClient(grafanaURL, options, append(auths[:len(auths):len(auths)], auth)...)
 	if err != nil {
 		return nil, err
 	}
 
 	return grafanaClient, nil
 }
+
+// CreateGrafanaClientFromTokenSecret creates a client authenticated with the operator service account token.
+func CreateGrafanaClientFromTokenSecret(ctx context.Context, tokenSecret *corev1.Secret, grafanaURL string, options TransportOptions, auths ...Authenticator) (*GrafanaClient, error) {
+	token := string(tokenSecret.Data[helpers.ServiceAccountTokenKey])
+	if token == "" {
+		return nil, fmt.Errorf("key '%s' not found in Secret %s", helpers.ServiceAccountTokenKey, tokenSecret.Name)
+	}
+
+	auth := &APITokenAuthenticator{
+		Token: token,
+	}
+	return NewClient(grafanaURL, options, append(auths[:len(auths):len(auths)], auth)...)
+}
+
+// GetInstanceAuthenticators returns the authenticators configured in the auth block of the GrafanaInstance.
+// They must be applied before the Grafana credentials.
+func GetInstanceAuthenticators(ctx context.Context, c client.Client, grafanaInstance *grafanav1beta1.GrafanaInstance) ([]Authenticator, error) {
+	secrets, err := getInstanceAuthSecrets(ctx, c, grafanaInstance)
+	if err != nil {
+		return nil, err
+	}
+	return newInstanceAuthenticators(grafanaInstance, secrets)
+}
+
+// instanceAuthSecrets holds the Secrets referenced by the auth block of a GrafanaInstance
+type instanceAuthSecrets struct {
+	mtls   *corev1.Secret
+	oauth2 *corev1.Secret
+}
+
+func (s instanceAuthSecrets) list() []*corev1.Secret {
+	var secrets []*corev1.Secret
+	for _, secret := range []*corev1.Secret{s.mtls, s.oauth2} {
+		if secret != nil {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+func getInstanceAuthSecrets(ctx context.Context, c client.Client, grafanaInstance *grafanav1beta1.GrafanaInstance) (instanceAuthSecrets, error) {
+	secrets := instanceAuthSecrets{}
+	auth := grafanaInstance.Spec.Auth
+	if auth == nil {
+		return secrets, nil
+	}
+
+	if auth.MTLS != nil {
+		secrets.mtls = &corev1.Secret{}
+		if err := c.Get(ctx, client.ObjectKey{Name: auth.MTLS.SecretName, Namespace: grafanaInstance.Namespace}, secrets.mtls); err != nil {
+			return secrets, fmt.Errorf("failed to get mTLS Secret: %w", err)
+		}
+	}
+
+	if auth.OAuth2 != nil {
+		secrets.oauth2 = &corev1.Secret{}
+		if err := c.Get(ctx, client.ObjectKey{Name: auth.OAuth2.SecretName, Namespace: grafanaInstance.Namespace}, secrets.oauth2); err != nil {
+			return secrets, fmt.Errorf("failed to get OAuth2 Secret: %w", err)
+		}
+	}
+
+	return secrets, nil
+}
+
+func newInstanceAuthenticators(grafanaInstance *grafanav1beta1.GrafanaInstance, secrets instanceAuthSecrets) ([]Authenticator, error) {
+	auth := grafanaInstance.Spec.Auth
+	if auth == nil {
+		return nil, nil
+	}
+
+	var auths []Authenticator
+
+	// The TLS configuration goes first, the other authenticators wrap the transport
+	if auth.MTLS != nil {
+		secret := secrets.mtls
+		mtls, err := NewMTLSAuthenticator(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], secret.Data[corev1.ServiceAccountRootCAKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid mTLS Secret %s: %w", secret.Name, err)
+		}
+		auths = append(auths, mtls)
+	}
+
+	if auth.OAuth2 != nil {
+		clientIDKey, clientSecretKey := auth.OAuth2.ClientIDKey, auth.OAuth2.ClientSecretKey
+		if clientIDKey == "" {
+			clientIDKey = "client_id"
+		}
+		if clientSecretKey == "" {
+			clientSecretKey = "client_secret"
+		}
+		clientID, clientSecret, err := helpers.GetCredentialsFromSecret(secrets.oauth2, clientIDKey, clientSecretKey)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, NewOAuth2Authenticator(auth.OAuth2.TokenURL, clientID, clientSecret, auth.OAuth2.Scopes, auth.OAuth2.Header))
+	}
+
+	return auths, nil
+}
@@ -0,0 +1,72 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-logr/logr"
+)
+
+// libraryPanelKind is the kind of the library elements holding a panel
+const libraryPanelKind = 1
+
+// libraryElement is a library element of the Grafana API, the folder is given by UID which gapi does not support
+type libraryElement struct {
+	UID       string                 `json:"uid"`
+	Name      string                 `json:"name"`
+	Kind      int64                  `json:"kind"`
+	Model     map[string]interface{} `json:"model"`
+	FolderUID string                 `json:"folderUid,omitempty"`
+	Version   int64                  `json:"version,omitempty"`
+}
+
+type libraryElementResponse struct {
+	Result libraryElement `json:"result"`
+}
+
+// UpsertLibraryPanel creates or overwrites the library panel in the folder and returns its new version.
+// The name of the library panel is the title of its model.
+func (gc *GrafanaClient) UpsertLibraryPanel(ctx context.Context, log logr.Logger, uid string, model map[string]interface{}, folderUID string) (int64, error) {
+	log = log.WithValues("Resource", "LibraryPanel")
+	requestPath := "/api/library-elements/" + url.PathEscape(uid)
+	name, _ := model["title"].(string)
+	element := libraryElement{UID: uid, Name: name, Kind: libraryPanelKind, Model: model, FolderUID: folderUID}
+
+	var existing libraryElementResponse
+	err := gc.do(ctx, http.MethodGet, requestPath, nil, &existing)
+	switch {
+	case IsNotFound(err):
+		var created libraryElementResponse
+		if err := gc.do(ctx, http.MethodPost, "/api/library-elements", element, &created); err != nil {
+			log.Error(err, "Failed to create Grafana library panel", "uid", uid)
+			return 0, err
+		}
+		log.Info("Successfully created Grafana library panel", "uid", uid)
+		return created.Result.Version, nil
+	case err != nil:
+		log.Error(err, "Failed to get Grafana library panel", "uid", uid)
+		return 0, err
+	}
+
+	// The version guards against overwriting a change made meanwhile
+	element.Version = existing.Result.Version
+	var patched libraryElementResponse
+	if err := gc.do(ctx, http.MethodPatch, requestPath, element, &patched); err != nil {
+		log.Error(err, "Failed to update Grafana library panel", "uid", uid)
+		return 0, err
+	}
+	log.Info("Successfully updated Grafana library panel", "uid", uid, "version", patched.Result.Version)
+	return patched.Result.Version, nil
+}
+
+// DeleteLibraryPanel deletes the library panel, Grafana refuses it while dashboards use the panel
+func (gc *GrafanaClient) DeleteLibraryPanel(ctx context.Context, log logr.Logger, uid string) error {
+	log = log.WithValues("Resource", "LibraryPanel")
+	if err := gc.do(ctx, http.MethodDelete, "/api/library-elements/"+url.PathEscape(uid), nil, nil); err != nil {
+		log.Error(err, "Failed to delete Grafana library panel", "uid", uid)
+		return err
+	}
+	log.Info("Successfully deleted Grafana library panel", "uid", uid)
+	return nil
+}
@@ -0,0 +1,64 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpsertLibraryPanel", func() {
+	var (
+		requests []string
+		saved    libraryElement
+		gc       *GrafanaClient
+		model    map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		saved = libraryElement{}
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/library-elements/existing":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{"uid": "existing", "version": 3}})
+			case r.Method == http.MethodGet:
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				_ = json.NewDecoder(r.Body).Decode(&saved)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{"uid": saved.UID, "version": saved.Version + 1}})
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+		model = map[string]interface{}{"title": "Availability SLO", "type": "stat"}
+	})
+
+	It("creates a missing library panel", func() {
+		version, err := gc.UpsertLibraryPanel(context.Background(), logr.Discard(), "new", model, "slos")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1]).To(Equal("POST /api/library-elements"))
+		Expect(version).To(BeEquivalentTo(1))
+		Expect(saved.Name).To(Equal("Availability SLO"))
+		Expect(saved.FolderUID).To(Equal("slos"))
+		Expect(saved.Kind).To(BeEquivalentTo(libraryPanelKind))
+	})
+
+	It("updates an existing library panel from its version", func() {
+		version, err := gc.UpsertLibraryPanel(context.Background(), logr.Discard(), "existing", model, "slos")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1]).To(Equal("PATCH /api/library-elements/existing"))
+		Expect(saved.Version).To(BeEquivalentTo(3))
+		Expect(version).To(BeEquivalentTo(4))
+	})
+})
@@ -0,0 +1,205 @@
+package grafana
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LintRule checks a dashboard model, it returns a message for every part of the model breaking the rule
+type LintRule interface {
+	Check(model map[string]interface{}) []string
+}
+
+// LintRuleFactory creates a rule from the parameters set on a lint policy
+type LintRuleFactory func(params map[string]string) (LintRule, error)
+
+// lintRules are the rules the lint policies can enable, by name
+var lintRules = map[string]LintRuleFactory{
+	"datasource-uid":    newDatasourceUIDRule,
+	"deprecated-panels": newDeprecatedPanelsRule,
+	"required-tags":     newRequiredTagsRule,
+	"min-refresh":       newMinRefreshRule,
+}
+
+// RegisterLintRule makes a rule available to the lint policies under its name,
+// it must be called before the manager starts
+func RegisterLintRule(name string, factory LintRuleFactory) {
+	lintRules[name] = factory
+}
+
+// NewLintRule creates the rule registered under the name with its parameters
+func NewLintRule(name string, params map[string]string) (LintRule, error) {
+	factory, ok := lintRules[name]
+	if !ok {
+		names := make([]string, 0, len(lintRules))
+		for registered := range lintRules {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown lint rule %s, expected one of %s", name, strings.Join(names, ", "))
+	}
+	rule, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters of lint rule %s: %w", name, err)
+	}
+	return rule, nil
+}
+
+// LintRuleFunc adapts a function to a LintRule
+type LintRuleFunc func(model map[string]interface{}) []string
+
+// Check implements LintRule
+func (f LintRuleFunc) Check(model map[string]interface{}) []string {
+	return f(model)
+}
+
+// newDatasourceUIDRule requires the panels and their queries to reference their datasource by UID.
+// Panels without a datasource use the default one and are left out.
+func newDatasourceUIDRule(map[string]string) (LintRule, error) {
+	return LintRuleFunc(func(model map[string]interface{}) []string {
+		var messages []string
+		for _, panel := range dashboardPanels(model) {
+			if message := checkDatasourceUID(panel["datasource"]); message != "" {
+				messages = append(messages, fmt.Sprintf("panel %s %s", panelName(panel), message))
+			}
+			targets, _ := panel["targets"].([]interface{})
+			for i, item := range targets {
+				target, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if message := checkDatasourceUID(target["datasource"]); message != "" {
+					messages = append(messages, fmt.Sprintf("query %d of panel %s %s", i, panelName(panel), message))
+				}
+			}
+		}
+		return messages
+	}), nil
+}
+
+// checkDatasourceUID returns why a datasource reference is not a UID, or nothing
+func checkDatasourceUID(datasource interface{}) string {
+	switch datasource := datasource.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("references the datasource %q by name", datasource)
+	case map[string]interface{}:
+		if uid, _ := datasource["uid"].(string); uid == "" {
+			return "references a datasource without UID"
+		}
+	}
+	return ""
+}
+
+// newDeprecatedPanelsRule forbids the deprecated panel types, given by the types param
+func newDeprecatedPanelsRule(params map[string]string) (LintRule, error) {
+	deprecated := splitParam(params["types"])
+	if len(deprecated) == 0 {
+		deprecated = []string{"graph", "singlestat", "table-old"}
+	}
+	return LintRuleFunc(func(model map[string]interface{}) []string {
+		var messages []string
+		for _, panel := range dashboardPanels(model) {
+			panelType, _ := panel["type"].(string)
+			for _, deprecatedType := range deprecated {
+				if panelType == deprecatedType {
+					messages = append(messages, fmt.Sprintf("panel %s uses the deprecated %s panel type", panelName(panel), panelType))
+				}
+			}
+		}
+		return messages
+	}), nil
+}
+
+// newRequiredTagsRule requires the dashboard to carry the tags param
+func newRequiredTagsRule(params map[string]string) (LintRule, error) {
+	required := splitParam(params["tags"])
+	if len(required) == 0 {
+		return nil, fmt.Errorf("the tags param must list the required tags")
+	}
+	return LintRuleFunc(func(model map[string]interface{}) []string {
+		tags, _ := model["tags"].([]interface{})
+		var messages []string
+		for _, tag := range required {
+			if !containsTag(tags, tag) {
+				messages = append(messages, fmt.Sprintf("dashboard is not tagged %s", tag))
+			}
+		}
+		return messages
+	}), nil
+}
+
+// newMinRefreshRule forbids refreshing the dashboard faster than the interval param
+func newMinRefreshRule(params map[string]string) (LintRule, error) {
+	interval := 30 * time.Second
+	if value, ok := params["interval"]; ok {
+		parsed, err := parseRefresh(value)
+		if err != nil {
+			return nil, err
+		}
+		interval = parsed
+	}
+	return LintRuleFunc(func(model map[string]interface{}) []string {
+		// An empty or false refresh disables it
+		refresh, _ := model["refresh"].(string)
+		if refresh == "" {
+			return nil
+		}
+		parsed, err := parseRefresh(refresh)
+		if err != nil {
+			return []string{fmt.Sprintf("dashboard refresh %q is not an interval", refresh)}
+		}
+		if parsed < interval {
+			return []string{fmt.Sprintf("dashboard refreshes every %s, faster than every %s", refresh, interval)}
+		}
+		return nil
+	}), nil
+}
+
+// parseRefresh parses a refresh interval of Grafana, which also counts in days and weeks
+func parseRefresh(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if count, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); strings.HasSuffix(value, suffix) && err == nil {
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+// dashboardPanels returns the panels of a dashboard model, with the ones nested in collapsed rows
+func dashboardPanels(model map[string]interface{}) []map[string]interface{} {
+	var panels []map[string]interface{}
+	items, _ := model["panels"].([]interface{})
+	for _, item := range items {
+		panel, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		panels = append(panels, panel)
+		panels = append(panels, dashboardPanels(panel)...)
+	}
+	return panels
+}
+
+// panelName names a panel in the messages, by title or else by id
+func panelName(panel map[string]interface{}) string {
+	if title, _ := panel["title"].(string); title != "" {
+		return strconv.Quote(title)
+	}
+	return fmt.Sprintf("%v", panel["id"])
+}
+
+// splitParam returns the comma-separated values of a param
+func splitParam(param string) []string {
+	var values []string
+	for _, value := range strings.Split(param, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
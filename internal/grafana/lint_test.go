@@ -0,0 +1,89 @@
+package grafana
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint rules", func() {
+	var model map[string]interface{}
+
+	BeforeEach(func() {
+		model = map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(`{
+			"title": "Nodes",
+			"tags": ["team"],
+			"refresh": "10s",
+			"panels": [
+				{"id": 1, "title": "CPU Usage", "type": "graph", "targets": [{"expr": "up"}]},
+				{"id": 2, "title": "Memory", "type": "timeseries", "datasource": "Prometheus",
+				 "targets": [{"expr": "up", "datasource": {"type": "prometheus"}}]},
+				{"id": 3, "type": "row", "panels": [{"id": 4, "title": "Disk", "type": "singlestat", "datasource": {"uid": "P1809F7CD0C75ACF3"}}]}
+			]
+		}`), &model)).To(Succeed())
+	})
+
+	DescribeTable("check the model",
+		func(name string, params map[string]string, expected []string) {
+			rule, err := NewLintRule(name, params)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rule.Check(model)).To(Equal(expected))
+		},
+		Entry("datasource-uid", "datasource-uid", nil, []string{
+			`panel "Memory" references the datasource "Prometheus" by name`,
+			`query 0 of panel "Memory" references a datasource without UID`,
+		}),
+		Entry("deprecated-panels", "deprecated-panels", nil, []string{
+			`panel "CPU Usage" uses the deprecated graph panel type`,
+			`panel "Disk" uses the deprecated singlestat panel type`,
+		}),
+		Entry("deprecated-panels with the types", "deprecated-panels", map[string]string{"types": "timeseries"}, []string{
+			`panel "Memory" uses the deprecated timeseries panel type`,
+		}),
+		Entry("required-tags", "required-tags", map[string]string{"tags": "team, production"}, []string{"dashboard is not tagged production"}),
+		Entry("min-refresh", "min-refresh", nil, []string{"dashboard refreshes every 10s, faster than every 30s"}),
+		Entry("min-refresh with the interval", "min-refresh", map[string]string{"interval": "5s"}, nil),
+	)
+
+	DescribeTable("reject an invalid configuration",
+		func(name string, params map[string]string) {
+			_, err := NewLintRule(name, params)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("required-tags without the tags", "required-tags", nil),
+		Entry("min-refresh with an invalid interval", "min-refresh", map[string]string{"interval": "often"}),
+		Entry("unknown rule", "unknown", nil),
+	)
+
+	It("checks the model with a registered rule", func() {
+		RegisterLintRule("titled", func(map[string]string) (LintRule, error) {
+			return LintRuleFunc(func(model map[string]interface{}) []string {
+				if model["title"] == "" {
+					return []string{"dashboard has no title"}
+				}
+				return nil
+			}), nil
+		})
+		DeferCleanup(func() {
+			delete(lintRules, "titled")
+		})
+
+		rule, err := NewLintRule("titled", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rule.Check(map[string]interface{}{"title": ""})).To(HaveLen(1))
+	})
+})
+
+var _ = DescribeTable("parseRefresh",
+	func(value string, expected string) {
+		parsed, err := parseRefresh(value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.String()).To(Equal(expected))
+	},
+	Entry("seconds", "30s", "30s"),
+	Entry("minutes", "5m", "5m0s"),
+	Entry("days", "1d", "24h0m0s"),
+	Entry("weeks", "2w", "336h0m0s"),
+)
@@ -0,0 +1,88 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultOrgID is the ID of the organization created with every Grafana instance
+const DefaultOrgID int64 = 1
+
+// WithOrgID returns a copy of the client sending its requests to the given organization.
+// It shares the connection pool of the client.
+func (gc *GrafanaClient) WithOrgID(orgID int64) *GrafanaClient {
+	orgClient := *gc
+	orgClient.config.OrgID = orgID
+	return &orgClient
+}
+
+// EnsureOrganization ensures that a Grafana organization with the given name exists.
+// An organization with a known ID is renamed, otherwise it is looked up by name or created.
+// It returns the ID of the organization and whether it was created.
+func (gc *GrafanaClient) EnsureOrganization(ctx context.Context, log logr.Logger, name string, orgID int64) (int64, bool, error) {
+	log = log.WithValues("Resource", "Organization")
+
+	if orgID != 0 {
+		org, err := gc.api(ctx).Org(orgID)
+		switch {
+		case err == nil && org.Name == name:
+			return orgID, false, nil
+		case err == nil:
+			log.Info("Renaming Grafana organization", "ID", orgID, "Name", name)
+			if err := gc.api(ctx).UpdateOrg(orgID, name); err != nil {
+				return -1, false, fmt.Errorf("failed to update Grafana organization: %w", err)
+			}
+			return orgID, false, nil
+		case !IsNotFound(err):
+			log.Error(err, "Failed to get Grafana organization", "ID", orgID)
+			return -1, false, fmt.Errorf("failed to get Grafana organization: %w", err)
+		}
+		log.Info("Grafana organization not found anymore", "ID", orgID)
+	}
+
+	org, err := gc.api(ctx).OrgByName(name)
+	if err == nil {
+		log.Info("Using existing Grafana organization", "ID", org.ID, "Name", name)
+		return org.ID, false, nil
+	}
+	if !IsNotFound(err) {
+		log.Error(err, "Failed to look up Grafana organization", "Name", name)
+		return -1, false, fmt.Errorf("failed to look up Grafana organization: %w", err)
+	}
+
+	log.Info("Creating new Grafana organization", "Name", name)
+	orgID, err = gc.api(ctx).NewOrg(name)
+	if err != nil {
+		return -1, false, fmt.Errorf("failed to create Grafana organization: %w", err)
+	}
+
+	return orgID, true, nil
+}
+
+// DeleteOrganization deletes a Grafana organization, an organization already gone is ignored.
+func (gc *GrafanaClient) DeleteOrganization(ctx context.Context, log logr.Logger, orgID int64) error {
+	log = log.WithValues("Resource", "Organization")
+
+	if err := gc.api(ctx).DeleteOrg(orgID); err != nil && !IsNotFound(err) {
+		log.Error(err, "Failed to delete Grafana organization", "ID", orgID)
+		return fmt.Errorf("failed to delete Grafana organization: %w", err)
+	}
+
+	log.Info("Successfully deleted Grafana organization", "ID", orgID)
+	return nil
+}
+
+// AddOrganizationUser adds a user to the organization with the given role.
+// A user already member of the organization is left as is.
+func (gc *GrafanaClient) AddOrganizationUser(ctx context.Context, log logr.Logger, orgID int64, loginOrEmail string, role string) error {
+	log = log.WithValues("Resource", "Organization")
+
+	if err := gc.api(ctx).AddOrgUser(orgID, loginOrEmail, role); err != nil && !IsConflict(err) {
+		log.Error(err, "Failed to add user to Grafana organization", "ID", orgID, "login", loginOrEmail)
+		return fmt.Errorf("failed to add user '%s' to Grafana organization: %w", loginOrEmail, err)
+	}
+
+	return nil
+}
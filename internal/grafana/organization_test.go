@@ -0,0 +1,68 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnsureOrganization", func() {
+	var (
+		orgs map[int64]string
+		gc   *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		orgs = map[int64]string{1: "Main Org.", 2: "existing"}
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/orgs/2":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 2, "name": orgs[2]})
+			case r.Method == http.MethodPut && r.URL.Path == "/api/orgs/2":
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				orgs[2] = body["name"]
+				_ = json.NewEncoder(w).Encode(map[string]string{"message": "Organization updated"})
+			case r.Method == http.MethodGet && r.URL.Path == "/api/orgs/name/existing":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 2, "name": "existing"})
+			case r.Method == http.MethodPost && r.URL.Path == "/api/orgs":
+				orgs[3] = "new"
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"orgId": 3})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]string{"message": "Organization not found"})
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("finds or creates the organization",
+		func(orgName string, knownID int64, wantID int64, wantCreated bool) {
+			orgID, created, err := gc.EnsureOrganization(context.Background(), logr.Discard(), orgName, knownID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orgID).To(Equal(wantID))
+			Expect(created).To(Equal(wantCreated))
+		},
+		Entry("existing organization found by name", "existing", int64(0), int64(2), false),
+		Entry("missing organization created", "new", int64(0), int64(3), true),
+		Entry("known organization kept", "existing", int64(2), int64(2), false),
+		Entry("known organization gone created again", "new", int64(5), int64(3), true),
+	)
+
+	It("renames a known organization", func() {
+		orgID, created, err := gc.EnsureOrganization(context.Background(), logr.Discard(), "renamed", 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orgID).To(BeEquivalentTo(2))
+		Expect(created).To(BeFalse())
+		Expect(orgs[2]).To(Equal("renamed"))
+	})
+})
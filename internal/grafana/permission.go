@@ -0,0 +1,128 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// Permission levels of the dashboards and folders
+const (
+	ViewPermission  int64 = 1
+	EditPermission  int64 = 2
+	AdminPermission int64 = 4
+)
+
+// Permission grants a permission level to either a role, a team or a user
+type Permission struct {
+	Role       string
+	TeamID     int64
+	UserID     int64
+	Permission int64
+}
+
+// ParsePermissionLevel converts the name of a permission level to its Grafana value
+func ParsePermissionLevel(level string) (int64, error) {
+	switch level {
+	case "View":
+		return ViewPermission, nil
+	case "Edit":
+		return EditPermission, nil
+	case "Admin":
+		return AdminPermission, nil
+	default:
+		return -1, fmt.Errorf("unknown permission level '%s'", level)
+	}
+}
+
+// SyncDashboardPermissions makes the permissions of the dashboard match the given ones.
+// Permissions inherited from the folder are left alone, the others are replaced when they drifted.
+func (gc *GrafanaClient) SyncDashboardPermissions(ctx context.Context, log logr.Logger, dashboardUID string, permissions []Permission) error {
+	log = log.WithValues("Resource", "DashboardPermission")
+
+	items, err := gc.api(ctx).DashboardPermissionsByUID(dashboardUID)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana dashboard permissions", "dashboardUID", dashboardUID)
+		return fmt.Errorf("failed to get Grafana dashboard permissions: %w", err)
+	}
+
+	current := make([]Permission, 0, len(items))
+	for _, item := range items {
+		if item.Inherited {
+			continue
+		}
+		current = append(current, Permission{Role: item.Role, TeamID: item.TeamID, UserID: item.UserID, Permission: item.Permission})
+	}
+	if permissionsEqual(current, permissions) {
+		return nil
+	}
+
+	log.Info("Updating Grafana dashboard permissions", "dashboardUID", dashboardUID)
+	if err := gc.api(ctx).UpdateDashboardPermissionsByUID(dashboardUID, toPermissionItems(permissions)); err != nil {
+		return fmt.Errorf("failed to update Grafana dashboard permissions: %w", err)
+	}
+	return nil
+}
+
+// SyncFolderPermissions makes the permissions of the folder match the given ones, they are replaced when they drifted.
+func (gc *GrafanaClient) SyncFolderPermissions(ctx context.Context, log logr.Logger, folderUID string, permissions []Permission) error {
+	log = log.WithValues("Resource", "FolderPermission")
+
+	items, err := gc.api(ctx).FolderPermissions(folderUID)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana folder permissions", "folderUID", folderUID)
+		return fmt.Errorf("failed to get Grafana folder permissions: %w", err)
+	}
+
+	current := make([]Permission, 0, len(items))
+	for _, item := range items {
+		current = append(current, Permission{Role: item.Role, TeamID: item.TeamID, UserID: item.UserID, Permission: item.Permission})
+	}
+	if permissionsEqual(current, permissions) {
+		return nil
+	}
+
+	log.Info("Updating Grafana folder permissions", "folderUID", folderUID)
+	if err := gc.api(ctx).UpdateFolderPermissions(folderUID, toPermissionItems(permissions)); err != nil {
+		return fmt.Errorf("failed to update Grafana folder permissions: %w", err)
+	}
+	return nil
+}
+
+func toPermissionItems(permissions []Permission) *gapi.PermissionItems {
+	items := &gapi.PermissionItems{Items: make([]*gapi.PermissionItem, 0, len(permissions))}
+	for _, permission := range permissions {
+		items.Items = append(items.Items, &gapi.PermissionItem{
+			Role:       permission.Role,
+			TeamID:     permission.TeamID,
+			UserID:     permission.UserID,
+			Permission: permission.Permission,
+		})
+	}
+	return items
+}
+
+// permissionsEqual compares the permissions regardless of their order
+func permissionsEqual(a []Permission, b []Permission) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = sortedPermissions(a), sortedPermissions(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedPermissions(permissions []Permission) []Permission {
+	sorted := append([]Permission(nil), permissions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return fmt.Sprint(sorted[i]) < fmt.Sprint(sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,65 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyncDashboardPermissions", func() {
+	var (
+		updates []map[string]interface{}
+		gc      *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		updates = nil
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/dashboards/uid/prod/permissions" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.Method == http.MethodPost {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				updates = append(updates, body)
+				return
+			}
+			// The folder permission is inherited and ignored
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"teamId": 3, "permission": EditPermission},
+				{"role": "Viewer", "permission": ViewPermission},
+				{"role": "Editor", "permission": EditPermission, "inherited": true},
+			})
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("leaves the same permissions in another order alone", func() {
+		err := gc.SyncDashboardPermissions(context.Background(), logr.Discard(), "prod", []Permission{
+			{Role: "Viewer", Permission: ViewPermission},
+			{TeamID: 3, Permission: EditPermission},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+	})
+
+	It("sends every permission when one drifted", func() {
+		err := gc.SyncDashboardPermissions(context.Background(), logr.Discard(), "prod", []Permission{
+			{Role: "Viewer", Permission: ViewPermission},
+			{TeamID: 3, Permission: ViewPermission},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(HaveLen(1))
+		Expect(updates[0]["items"]).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,243 @@
+package grafana
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultHealthTTL is the default duration a successful health check is trusted
+const DefaultHealthTTL = 30 * time.Second
+
+// ErrServiceAccountNotReady is returned until the operator service account token of the instance exists
+var ErrServiceAccountNotReady = errors.New("operator service account not bootstrapped yet")
+
+// ClientRegistry shares the Grafana clients authenticated with the operator service account
+// across reconciles. A client is kept per GrafanaInstance UID and organization, and rebuilt whenever the
+// checksum of its service URL, auth configuration or Secrets changes.
+// It also holds the rate limiter of every instance, shared by all the clients talking to it.
+type ClientRegistry struct {
+	healthTTL time.Duration
+	transport TransportOptions
+	rateLimit rate.Limit
+	burst     int
+
+	mu       sync.Mutex
+	entries  map[clientKey]*registryEntry
+	limiters map[types.UID]*limiterEntry
+}
+
+// clientKey identifies the client of an organization of an instance
+type clientKey struct {
+	instance types.UID
+	orgID    int64
+}
+
+type registryEntry struct {
+	instance     types.NamespacedName
+	checksum     string
+	client       *GrafanaClient
+	auths        []Authenticator
+	healthyUntil time.Time
+}
+
+// close releases the connections and the cached OAuth2 token sources of the entry
+func (entry *registryEntry) close() {
+	entry.client.Close()
+	releaseOAuth2TokenSources(entry.auths)
+}
+
+type limiterEntry struct {
+	instance types.NamespacedName
+	limiter  *rate.Limiter
+}
+
+// NewClientRegistry returns a registry whose clients use the given transport options.
+// A rate limit of zero disables rate limiting.
+func NewClientRegistry(healthTTL time.Duration, transport TransportOptions, rateLimit float64, burst int) *ClientRegistry {
+	return &ClientRegistry{
+		healthTTL: healthTTL,
+		transport: transport,
+		rateLimit: rate.Limit(rateLimit),
+		burst:     burst,
+		entries:   make(map[clientKey]*registryEntry),
+		limiters:  make(map[types.UID]*limiterEntry),
+	}
+}
+
+// TransportOptions returns the transport options of the clients of the GrafanaInstance,
+// including its rate limiter.
+func (r *ClientRegistry) TransportOptions(grafanaInstance *grafanav1beta1.GrafanaInstance) TransportOptions {
+	options := r.transport
+	if r.rateLimit <= 0 {
+		return options
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.limiters[grafanaInstance.UID]
+	if !ok {
+		entry = &limiterEntry{
+			instance: client.ObjectKeyFromObject(grafanaInstance),
+			limiter:  rate.NewLimiter(r.rateLimit, r.burst),
+		}
+		r.limiters[grafanaInstance.UID] = entry
+	}
+	options.Limiter = entry.limiter
+	return options
+}
+
+// GetClient returns the client of the default organization of the GrafanaInstance, once Grafana is healthy.
+// The health check is only repeated once the previous successful one is older than the TTL.
+func (r *ClientRegistry) GetClient(ctx context.Context, c client.Client, grafanaInstance *grafanav1beta1.GrafanaInstance) (*GrafanaClient, error) {
+	tokenSecretName := grafanaInstance.Status.OperatorServiceAccount.TokenSecretName
+	return r.getClient(ctx, c, grafanaInstance, client.ObjectKey{Name: tokenSecretName, Namespace: grafanaInstance.Namespace}, 0)
+}
+
+// GetOrganizationClient returns the client of a GrafanaOrganization of the GrafanaInstance,
+// authenticated with the operator service account of the organization.
+func (r *ClientRegistry) GetOrganizationClient(ctx context.Context, c client.Client, grafanaInstance *grafanav1beta1.GrafanaInstance, org *grafanav1beta1.GrafanaOrganization) (*GrafanaClient, error) {
+	if org.Status.OrgID == 0 {
+		return nil, ErrServiceAccountNotReady
+	}
+	return r.getClient(ctx, c, grafanaInstance, client.ObjectKey{Name: org.Status.TokenSecretName, Namespace: org.Namespace}, org.Status.OrgID)
+}
+
+func (r *ClientRegistry) getClient(ctx context.Context, c client.Client, grafanaInstance *grafanav1beta1.GrafanaInstance, tokenSecretKey client.ObjectKey, orgID int64) (*GrafanaClient, error) {
+	if tokenSecretKey.Name == "" {
+		return nil, ErrServiceAccountNotReady
+	}
+
+	tokenSecret := &corev1.Secret{}
+	if err := c.Get(ctx, tokenSecretKey, tokenSecret); err != nil {
+		return nil, fmt.Errorf("failed to get token Secret: %w", err)
+	}
+
+	authSecrets, err := getInstanceAuthSecrets(ctx, c, grafanaInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := getClientChecksum(grafanaInstance, append(authSecrets.list(), tokenSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	transport := r.TransportOptions(grafanaInstance)
+	key := clientKey{instance: grafanaInstance.UID, orgID: orgID}
+	entry, err := r.getOrCreateEntry(key, client.ObjectKeyFromObject(grafanaInstance), checksum, func() (*GrafanaClient, []Authenticator, error) {
+		auths, err := newInstanceAuthenticators(grafanaInstance, authSecrets)
+		if err != nil {
+			return nil, nil, err
+		}
+		gc, err := CreateGrafanaClientFromTokenSecret(ctx, tokenSecret, grafanaInstance.Status.GrafanaUI.ServiceURL, transport, auths...)
+		if err != nil || orgID == 0 {
+			return gc, auths, err
+		}
+		return gc.WithOrgID(orgID), auths, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkHealth(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry.client, nil
+}
+
+func (r *ClientRegistry) getOrCreateEntry(key clientKey, instance types.NamespacedName, checksum string, newClient func() (*GrafanaClient, []Authenticator, error)) (*registryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if ok && entry.checksum == checksum {
+		return entry, nil
+	}
+
+	// The token source of changed OAuth2 credentials is released before the new one is cached
+	if ok {
+		entry.close()
+	}
+	gc, auths, err := newClient()
+	if err != nil {
+		delete(r.entries, key)
+		return nil, err
+	}
+
+	entry = &registryEntry{
+		instance: instance,
+		checksum: checksum,
+		client:   gc,
+		auths:    auths,
+	}
+	r.entries[key] = entry
+	return entry, nil
+}
+
+func (r *ClientRegistry) checkHealth(ctx context.Context, entry *registryEntry) error {
+	r.mu.Lock()
+	healthyUntil := entry.healthyUntil
+	r.mu.Unlock()
+
+	if time.Now().Before(healthyUntil) {
+		return nil
+	}
+
+	// Failures are not cached, the next reconcile checks again
+	if _, err := entry.client.IsGrafanaHealthy(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.healthyUntil = time.Now().Add(r.healthTTL)
+	return nil
+}
+
+// Invalidate drops the client and rate limiter of the GrafanaInstance, the next call to GetClient builds a new one.
+func (r *ClientRegistry) Invalidate(instance types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, entry := range r.entries {
+		if entry.instance == instance {
+			entry.close()
+			delete(r.entries, key)
+		}
+	}
+	for uid, entry := range r.limiters {
+		if entry.instance == instance {
+			delete(r.limiters, uid)
+		}
+	}
+}
+
+// getClientChecksum returns a checksum of everything the client of an instance is built from
+func getClientChecksum(grafanaInstance *grafanav1beta1.GrafanaInstance, secrets []*corev1.Secret) (string, error) {
+	auth, err := json.Marshal(grafanaInstance.Spec.Auth)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(grafanaInstance.Status.GrafanaUI.ServiceURL))
+	hash.Write(auth)
+	for _, secret := range secrets {
+		hash.Write([]byte(secret.Namespace + "/" + secret.Name + "/" + secret.ResourceVersion + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
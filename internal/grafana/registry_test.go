@@ -0,0 +1,145 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ClientRegistry", func() {
+	var (
+		ctx           context.Context
+		issued        int32
+		tokenServer   *httptest.Server
+		grafanaServer *httptest.Server
+		instance      *grafanav1beta1.GrafanaInstance
+		c             client.Client
+		registry      *ClientRegistry
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		issued = 0
+		tokenServer = newTokenServer(time.Hour, &issued)
+		DeferCleanup(tokenServer.Close)
+		grafanaServer = httptest.NewServer(newHealthHandler(func(r *http.Request) error {
+			if got := r.Header.Get("Authorization"); !strings.HasPrefix(got, "Bearer token-") {
+				return fmt.Errorf("unexpected Authorization header %q", got)
+			}
+			return nil
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		// The instance reaches Grafana with the token of its service account and OAuth2
+		instance = &grafanav1beta1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "default", UID: "uid"},
+			Spec: grafanav1beta1.GrafanaInstanceSpec{
+				Auth: &grafanav1beta1.GrafanaAuth{
+					OAuth2: &grafanav1beta1.OAuth2ClientCredentials{TokenURL: tokenServer.URL, SecretName: "grafana-oauth2"},
+				},
+			},
+			Status: grafanav1beta1.GrafanaInstanceStatus{
+				GrafanaUI:              grafanav1beta1.GrafanaUIStatus{ServiceURL: grafanaServer.URL},
+				OperatorServiceAccount: grafanav1beta1.OperatorServiceAccountStatus{TokenSecretName: "grafana-token"},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "grafana-oauth2", Namespace: "default"},
+				Data:       map[string][]byte{"client_id": []byte("operator"), "client_secret": []byte("s3cr3t")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "grafana-token", Namespace: "default"},
+				Data:       map[string][]byte{helpers.ServiceAccountTokenKey: []byte("token")},
+			},
+		).Build()
+		registry = NewClientRegistry(DefaultHealthTTL, DefaultTransportOptions(), 0, 0)
+		DeferCleanup(func() {
+			registry.Invalidate(client.ObjectKeyFromObject(instance))
+		})
+	})
+
+	It("releases the OAuth2 token source of an invalidated client", func() {
+		_, err := registry.GetClient(ctx, c, instance)
+		Expect(err).NotTo(HaveOccurred())
+		registry.Invalidate(client.ObjectKeyFromObject(instance))
+		_, err = registry.GetClient(ctx, c, instance)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The token source of the invalidated client is not reused
+		Expect(atomic.LoadInt32(&issued)).To(BeEquivalentTo(2))
+	})
+
+	It("closes the idle connections of an invalidated client", func() {
+		// The server counts the connections the client keeps open
+		var open int32
+		idleServer := httptest.NewUnstartedServer(newHealthHandler(func(r *http.Request) error { return nil }))
+		idleServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				atomic.AddInt32(&open, 1)
+			case http.StateClosed, http.StateHijacked:
+				atomic.AddInt32(&open, -1)
+			}
+		}
+		idleServer.Start()
+		DeferCleanup(idleServer.Close)
+		instance.Status.GrafanaUI.ServiceURL = idleServer.URL
+
+		_, err := registry.GetClient(ctx, c, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&open)).To(BeNumerically(">", 0), "the health check keeps a connection alive")
+
+		registry.Invalidate(client.ObjectKeyFromObject(instance))
+		Eventually(func() int32 { return atomic.LoadInt32(&open) }).Should(BeZero())
+	})
+
+	updateSecret := func(name string) func() {
+		return func() {
+			secret := &corev1.Secret{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: name, Namespace: instance.Namespace}, secret)).To(Succeed())
+			secret.Data["rotated"] = []byte("true")
+			Expect(c.Update(ctx, secret)).To(Succeed())
+		}
+	}
+
+	DescribeTable("rebuilds the changed clients",
+		func(change func(), rebuilt bool) {
+			first, err := registry.GetClient(ctx, c, instance)
+			Expect(err).NotTo(HaveOccurred())
+			change()
+			second, err := registry.GetClient(ctx, c, instance)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first != second).To(Equal(rebuilt))
+		},
+		Entry("unchanged", func() {}, false),
+		Entry("token Secret changed", updateSecret("grafana-token"), true),
+		Entry("OAuth2 Secret changed", updateSecret("grafana-oauth2"), true),
+		Entry("auth configuration changed", func() {
+			instance.Spec.Auth.OAuth2.Scopes = []string{"grafana"}
+		}, true),
+		Entry("service URL changed", func() {
+			otherServer := httptest.NewServer(newHealthHandler(func(r *http.Request) error { return nil }))
+			DeferCleanup(otherServer.Close)
+			instance.Status.GrafanaUI.ServiceURL = otherServer.URL
+		}, true),
+		Entry("invalidated", func() {
+			registry.Invalidate(client.ObjectKeyFromObject(instance))
+		}, true),
+	)
+})
@@ -0,0 +1,109 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// AdminRole is the organization role allowed to manage service accounts
+const AdminRole = "Admin"
+
+// EnsureServiceAccount ensures that a Grafana service account with the given name and role exists.
+// It returns the ID of the service account.
+func (gc *GrafanaClient) EnsureServiceAccount(ctx context.Context, log logr.Logger, name string, role string) (int64, error) {
+	log = log.WithValues("Resource", "ServiceAccount")
+
+	serviceAccounts, err := gc.api(ctx).GetServiceAccounts()
+	if err != nil {
+		log.Error(err, "Failed to list Grafana service accounts")
+		return -1, fmt.Errorf("failed to list Grafana service accounts: %w", err)
+	}
+
+	for _, serviceAccount := range serviceAccounts {
+		if serviceAccount.Name != name {
+			continue
+		}
+
+		if serviceAccount.Role != role {
+			log.Info("Updating Grafana service account role", "ID", serviceAccount.ID, "Role", role)
+			_, err := gc.api(ctx).UpdateServiceAccount(serviceAccount.ID, gapi.UpdateServiceAccountRequest{Role: role})
+			if err != nil {
+				return -1, fmt.Errorf("failed to update Grafana service account: %w", err)
+			}
+		}
+		return serviceAccount.ID, nil
+	}
+
+	log.Info("Creating new Grafana service account", "Name", name)
+	serviceAccount, err := gc.api(ctx).CreateServiceAccount(gapi.CreateServiceAccountRequest{Name: name, Role: role})
+	if err != nil {
+		return -1, fmt.Errorf("failed to create Grafana service account: %w", err)
+	}
+
+	return serviceAccount.ID, nil
+}
+
+// CreateServiceAccountToken creates a new token for the service account expiring after the given time to live.
+// It returns the ID of the token and its key.
+func (gc *GrafanaClient) CreateServiceAccountToken(ctx context.Context, log logr.Logger, serviceAccountID int64, name string, ttl time.Duration) (int64, string, error) {
+	log = log.WithValues("Resource", "ServiceAccountToken")
+
+	resp, err := gc.api(ctx).CreateServiceAccountToken(gapi.CreateServiceAccountTokenRequest{
+		Name:             name,
+		ServiceAccountID: serviceAccountID,
+		SecondsToLive:    int64(ttl.Seconds()),
+	})
+	if err != nil {
+		log.Error(err, "Failed to create Grafana service account token")
+		return -1, "", fmt.Errorf("failed to create Grafana service account token: %w", err)
+	}
+
+	log.Info("Successfully created Grafana service account token", "tokenID", resp.ID)
+	return resp.ID, resp.Key, nil
+}
+
+// DeleteServiceAccountToken deletes a token of the service account.
+func (gc *GrafanaClient) DeleteServiceAccountToken(ctx context.Context, log logr.Logger, serviceAccountID int64, tokenID int64) error {
+	log = log.WithValues("Resource", "ServiceAccountToken")
+
+	if _, err := gc.api(ctx).DeleteServiceAccountToken(serviceAccountID, tokenID); err != nil {
+		log.Error(err, "Failed to delete Grafana service account token", "tokenID", tokenID)
+		return fmt.Errorf("failed to delete Grafana service account token: %w", err)
+	}
+
+	log.Info("Successfully deleted Grafana service account token", "tokenID", tokenID)
+	return nil
+}
+
+// IsTokenValid reports whether Grafana accepts the token of the client. Searching is allowed to every role,
+// the token is rejected when it was revoked or the service account no longer exists.
+func (gc *GrafanaClient) IsTokenValid(ctx context.Context) (bool, error) {
+	err := gc.do(ctx, http.MethodGet, "/api/search?limit=1", nil, nil)
+	switch {
+	case err == nil:
+		return true, nil
+	case IsUnauthorized(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check Grafana service account token: %w", err)
+	}
+}
+
+// ServiceAccountExists reports whether the service account exists in the organization of the client.
+func (gc *GrafanaClient) ServiceAccountExists(ctx context.Context, serviceAccountID int64) (bool, error) {
+	serviceAccounts, err := gc.api(ctx).GetServiceAccounts()
+	if err != nil {
+		return false, fmt.Errorf("failed to list Grafana service accounts: %w", err)
+	}
+	for _, serviceAccount := range serviceAccounts {
+		if serviceAccount.ID == serviceAccountID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
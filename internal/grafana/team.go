@@ -0,0 +1,222 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// Permissions of the members of a team
+const (
+	TeamMemberPermission int64 = 0
+	TeamAdminPermission  int64 = 4
+)
+
+// TeamMember is a member of a team with its permission, identified by login or email
+type TeamMember struct {
+	LoginOrEmail string
+	Permission   int64
+}
+
+// EnsureTeam ensures that a team with the given name and email exists in the organization of the client.
+// A team with a known ID is updated, otherwise it is looked up by name or created.
+// It returns the ID of the team and whether it was created.
+func (gc *GrafanaClient) EnsureTeam(ctx context.Context, log logr.Logger, teamID int64, name string, email string) (int64, bool, error) {
+	log = log.WithValues("Resource", "Team")
+
+	if teamID == 0 {
+		result, err := gc.api(ctx).SearchTeam(name)
+		if err != nil {
+			log.Error(err, "Failed to search Grafana teams", "Name", name)
+			return -1, false, fmt.Errorf("failed to search Grafana teams: %w", err)
+		}
+		for _, team := range result.Teams {
+			if team.Name == name {
+				teamID = team.ID
+				break
+			}
+		}
+	}
+
+	if teamID != 0 {
+		team, err := gc.api(ctx).Team(teamID)
+		switch {
+		case err == nil && team.Name == name && team.Email == email:
+			return teamID, false, nil
+		case err == nil:
+			log.Info("Updating Grafana team", "ID", teamID, "Name", name)
+			if err := gc.api(ctx).UpdateTeam(teamID, name, email); err != nil {
+				return -1, false, fmt.Errorf("failed to update Grafana team: %w", err)
+			}
+			return teamID, false, nil
+		case !IsNotFound(err):
+			log.Error(err, "Failed to get Grafana team", "ID", teamID)
+			return -1, false, fmt.Errorf("failed to get Grafana team: %w", err)
+		}
+		log.Info("Grafana team not found anymore", "ID", teamID)
+	}
+
+	log.Info("Creating new Grafana team", "Name", name)
+	teamID, err := gc.api(ctx).AddTeam(name, email)
+	if err != nil {
+		return -1, false, fmt.Errorf("failed to create Grafana team: %w", err)
+	}
+
+	return teamID, true, nil
+}
+
+// SyncTeamMembers makes the members of the team and their permissions match the given ones.
+// Members not listed are removed from the team.
+func (gc *GrafanaClient) SyncTeamMembers(ctx context.Context, log logr.Logger, teamID int64, members []TeamMember) error {
+	log = log.WithValues("Resource", "TeamMember")
+
+	current, err := gc.api(ctx).TeamMembers(teamID)
+	if err != nil {
+		log.Error(err, "Failed to list Grafana team members", "teamID", teamID)
+		return fmt.Errorf("failed to list Grafana team members: %w", err)
+	}
+
+	desired := make(map[int64]int64, len(members))
+	for _, member := range members {
+		userID, err := gc.GetOrgUserID(ctx, log, member.LoginOrEmail)
+		if err != nil {
+			return err
+		}
+		desired[userID] = member.Permission
+	}
+
+	for _, member := range current {
+		permission, ok := desired[member.UserID]
+		if !ok {
+			log.Info("Removing Grafana team member", "teamID", teamID, "login", member.Login)
+			if err := gc.api(ctx).RemoveMemberFromTeam(teamID, member.UserID); err != nil {
+				return fmt.Errorf("failed to remove Grafana team member: %w", err)
+			}
+			continue
+		}
+		delete(desired, member.UserID)
+		if member.Permission != permission {
+			log.Info("Updating Grafana team member permission", "teamID", teamID, "login", member.Login)
+			if err := gc.updateTeamMemberPermission(ctx, teamID, member.UserID, permission); err != nil {
+				return err
+			}
+		}
+	}
+
+	for userID, permission := range desired {
+		log.Info("Adding Grafana team member", "teamID", teamID, "userID", userID)
+		if err := gc.api(ctx).AddTeamMember(teamID, userID); err != nil {
+			return fmt.Errorf("failed to add Grafana team member: %w", err)
+		}
+		if permission != TeamMemberPermission {
+			if err := gc.updateTeamMemberPermission(ctx, teamID, userID, permission); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RemoveTeamMembers removes the members identified by login or email from the team, the other members are left alone.
+// A team already gone is ignored.
+func (gc *GrafanaClient) RemoveTeamMembers(ctx context.Context, log logr.Logger, teamID int64, loginOrEmails []string) error {
+	log = log.WithValues("Resource", "TeamMember")
+
+	current, err := gc.api(ctx).TeamMembers(teamID)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to list Grafana team members", "teamID", teamID)
+		return fmt.Errorf("failed to list Grafana team members: %w", err)
+	}
+
+	for _, member := range current {
+		for _, loginOrEmail := range loginOrEmails {
+			if !strings.EqualFold(member.Login, loginOrEmail) && !strings.EqualFold(member.Email, loginOrEmail) {
+				continue
+			}
+			log.Info("Removing Grafana team member", "teamID", teamID, "login", member.Login)
+			if err := gc.api(ctx).RemoveMemberFromTeam(teamID, member.UserID); err != nil && !IsNotFound(err) {
+				return fmt.Errorf("failed to remove Grafana team member: %w", err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// updateTeamMemberPermission is not covered by the Grafana API client
+func (gc *GrafanaClient) updateTeamMemberPermission(ctx context.Context, teamID int64, userID int64, permission int64) error {
+	body := map[string]int64{"permission": permission}
+	if err := gc.do(ctx, "PUT", fmt.Sprintf("/api/teams/%d/members/%d", teamID, userID), body, nil); err != nil {
+		return fmt.Errorf("failed to update Grafana team member permission: %w", err)
+	}
+	return nil
+}
+
+// SyncTeamGroups makes the external groups synced with the team match the given ones.
+// Team sync requires Grafana Enterprise or Grafana Cloud.
+func (gc *GrafanaClient) SyncTeamGroups(ctx context.Context, log logr.Logger, teamID int64, groups []string) error {
+	log = log.WithValues("Resource", "TeamGroup")
+
+	current, err := gc.api(ctx).TeamGroups(teamID)
+	if err != nil {
+		log.Error(err, "Failed to list Grafana team groups", "teamID", teamID)
+		return fmt.Errorf("failed to list Grafana team groups: %w", err)
+	}
+
+	desired := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		desired[group] = true
+	}
+
+	for _, group := range current {
+		if desired[group.GroupID] {
+			delete(desired, group.GroupID)
+			continue
+		}
+		log.Info("Removing Grafana team group", "teamID", teamID, "group", group.GroupID)
+		if err := gc.api(ctx).DeleteTeamGroup(teamID, group.GroupID); err != nil {
+			return fmt.Errorf("failed to remove Grafana team group: %w", err)
+		}
+	}
+
+	for _, group := range groups {
+		if !desired[group] {
+			continue
+		}
+		log.Info("Adding Grafana team group", "teamID", teamID, "group", group)
+		if err := gc.api(ctx).NewTeamGroup(teamID, group); err != nil {
+			return fmt.Errorf("failed to add Grafana team group: %w", err)
+		}
+		delete(desired, group)
+	}
+
+	return nil
+}
+
+// DeleteTeam deletes a team, a team already gone is ignored.
+func (gc *GrafanaClient) DeleteTeam(ctx context.Context, log logr.Logger, teamID int64) error {
+	log = log.WithValues("Resource", "Team")
+
+	if err := gc.api(ctx).DeleteTeam(teamID); err != nil && !IsNotFound(err) {
+		log.Error(err, "Failed to delete Grafana team", "ID", teamID)
+		return fmt.Errorf("failed to delete Grafana team: %w", err)
+	}
+
+	log.Info("Successfully deleted Grafana team", "ID", teamID)
+	return nil
+}
+
+// ParseTeamMemberPermission converts the role of a team member to its Grafana permission
+func ParseTeamMemberPermission(role string) int64 {
+	if strings.EqualFold(role, "Admin") {
+		return TeamAdminPermission
+	}
+	return TeamMemberPermission
+}
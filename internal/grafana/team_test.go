@@ -0,0 +1,74 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyncTeamMembers", func() {
+	var (
+		calls []string
+		gc    *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		calls = nil
+		users := map[string]int64{"alice": 1, "bob": 2, "carol": 3, "dave": 4}
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/org/users/lookup":
+				// The lookup also matches other users than the one queried
+				query := r.URL.Query().Get("query")
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"userId": 99, "login": query + "-admin", "email": query + "-admin@example.com"},
+					{"userId": users[query], "login": query, "email": query + "@example.com"},
+				})
+			case r.Method == http.MethodGet && r.URL.Path == "/api/teams/7/members":
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"userId": 1, "login": "alice", "permission": TeamAdminPermission},
+					{"userId": 2, "login": "bob", "permission": TeamAdminPermission},
+					{"userId": 3, "login": "carol", "permission": TeamMemberPermission},
+				})
+			default:
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				calls = append(calls, fmt.Sprintf("%s %s %v", r.Method, r.URL.Path, body))
+				_, _ = fmt.Fprint(w, `{}`)
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("adds, updates and removes the members", func() {
+		// alice stays an admin, bob is demoted, carol removed and dave added
+		err := gc.SyncTeamMembers(context.Background(), logr.Discard(), 7, []TeamMember{
+			{LoginOrEmail: "alice", Permission: TeamAdminPermission},
+			{LoginOrEmail: "bob", Permission: TeamMemberPermission},
+			{LoginOrEmail: "dave", Permission: TeamAdminPermission},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(ConsistOf(
+			"DELETE /api/teams/7/members/3 map[]",
+			"POST /api/teams/7/members map[userID:4]",
+			"PUT /api/teams/7/members/2 map[permission:0]",
+			"PUT /api/teams/7/members/4 map[permission:4]",
+		))
+	})
+
+	It("removes only the given members", func() {
+		err := gc.RemoveTeamMembers(context.Background(), logr.Discard(), 7, []string{"BOB", "dave"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"DELETE /api/teams/7/members/2 map[]"}))
+	})
+})
@@ -0,0 +1,216 @@
+package grafana
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TransportOptions configures how the requests of a client reach the Grafana API.
+type TransportOptions struct {
+	// Timeout bounds every single attempt of a request
+	Timeout time.Duration
+	// MaxRetries is the number of retries of a request answered with 429, or with 5xx for the idempotent methods
+	MaxRetries int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between retries
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// Limiter throttles the requests, it is shared by all the clients of an instance
+	Limiter *rate.Limiter
+}
+
+// DefaultTransportOptions returns the options used when the operator flags are not set
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		Timeout:      5 * time.Second,
+		MaxRetries:   3,
+		RetryWaitMin: 500 * time.Millisecond,
+		RetryWaitMax: 30 * time.Second,
+	}
+}
+
+// retryTransport retries the requests answered with 429, or with 5xx for the idempotent methods, with an
+// exponential backoff honoring the Retry-After header, and waits for the rate limiter before every attempt.
+// A POST answered with 5xx, by a proxy for instance, may have been applied by Grafana and is not sent again.
+type retryTransport struct {
+	options TransportOptions
+	base    http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if t.options.Limiter != nil {
+			if err := t.options.Limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		// The body was consumed by the previous attempt
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.roundTripWithTimeout(req)
+		if err != nil || !isRetryable(req.Method, resp.StatusCode) || attempt >= t.options.MaxRetries {
+			return resp, err
+		}
+		// A consumed body cannot be sent again
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait := t.backoff(attempt, resp)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// roundTripWithTimeout sends a single attempt, the timeout covers reading the body as well
+// CloseIdleConnections closes the idle connections of the base transport, http.Client.CloseIdleConnections
+// only reaches the outermost transport
+func (t *retryTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+func (t *retryTransport) roundTripWithTimeout(req *http.Request) (*http.Response, error) {
+	if t.options.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.options.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay before the next attempt, the Retry-After header takes precedence
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		if retryAfter > t.options.RetryWaitMax {
+			return t.options.RetryWaitMax
+		}
+		return retryAfter
+	}
+
+	wait := time.Duration(float64(t.options.RetryWaitMin) * math.Pow(2, float64(attempt)))
+	if wait <= 0 || wait > t.options.RetryWaitMax {
+		return t.options.RetryWaitMax
+	}
+	return wait
+}
+
+// parseRetryAfter parses the Retry-After header, given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// isRetryable reports whether a request answered with the status can be sent again. Grafana rejects
+// the requests it throttles with 429 before applying them, the other failures may come after.
+func isRetryable(method string, statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return isIdempotent(method) && statusCode >= http.StatusInternalServerError && statusCode != http.StatusNotImplemented
+}
+
+// isIdempotent reports whether sending a request of the method twice has the effect of sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+const (
+	// RequestIDHeader identifies the requests of the operator in the Grafana logs
+	RequestIDHeader = "X-Request-Id"
+
+	userAgent = "grafana-operator"
+)
+
+// contextTransport binds the requests built by the Grafana API client to the context of the call
+// and logs them with the values of the reconcile logger.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(t.ctx)
+	requestID := string(uuid.NewUUID())
+	req.Header.Set(RequestIDHeader, requestID)
+	req.Header.Set("User-Agent", userAgent)
+
+	log := logf.FromContext(t.ctx).V(1).WithValues("method", req.Method, "path", req.URL.Path, "requestID", requestID)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		log.Info("Grafana API request failed", "duration", time.Since(start), "error", err.Error())
+		return nil, err
+	}
+	log.Info("Grafana API request", "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the base transport
+func (t *contextTransport) CloseIdleConnections() {
+	closeIdleConnections(t.base)
+}
+
+// closeIdleConnections closes the idle connections of the transport, if it keeps a connection pool
+func closeIdleConnections(transport http.RoundTripper) {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	if transport, ok := transport.(closeIdler); ok {
+		transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,161 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+)
+
+var _ = Describe("retryTransport", func() {
+	var options TransportOptions
+
+	BeforeEach(func() {
+		options = DefaultTransportOptions()
+		options.RetryWaitMin = time.Millisecond
+		options.RetryWaitMax = 10 * time.Millisecond
+	})
+
+	It("retries a request with its body", func() {
+		var attempts int32
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if n := atomic.AddInt32(&attempts, 1); n < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if err := r.ParseForm(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"id": 1, "uid": "folder", "title": "Folder"}`)
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		gc, err := NewClient(grafanaServer.URL, options)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gc.api(context.Background()).NewFolder("Folder")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&attempts)).To(BeEquivalentTo(3))
+	})
+
+	It("gives up after the maximum number of retries", func() {
+		var attempts int32
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		options.MaxRetries = 2
+		gc, err := NewClient(grafanaServer.URL, options)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gc.IsGrafanaHealthy(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&attempts)).To(BeEquivalentTo(3))
+	})
+
+	It("retries the server errors of the idempotent methods only", func() {
+		attempts := map[string]int{}
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts[r.Method]++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		options.MaxRetries = 2
+		gc, err := NewClient(grafanaServer.URL, options)
+		Expect(err).NotTo(HaveOccurred())
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodPost, http.MethodPatch} {
+			Expect(gc.do(context.Background(), method, "/api/teams/1", map[string]string{}, nil)).NotTo(Succeed(), method)
+		}
+
+		// A POST or a PATCH may have been applied before the proxy failed, it is sent once
+		Expect(attempts).To(Equal(map[string]int{http.MethodGet: 3, http.MethodPut: 3, http.MethodDelete: 3, http.MethodPost: 1, http.MethodPatch: 1}))
+	})
+
+	It("times out a request", func() {
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		options.Timeout = 50 * time.Millisecond
+		gc, err := NewClient(grafanaServer.URL, options)
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		_, err = gc.IsGrafanaHealthy(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+
+	It("shares the rate limiter between the clients of an instance", func() {
+		grafanaServer := httptest.NewServer(newHealthHandler(func(r *http.Request) error { return nil }))
+		DeferCleanup(grafanaServer.Close)
+
+		options.Limiter = rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			gc, err := NewClient(grafanaServer.URL, options)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = gc.IsGrafanaHealthy(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+
+	It("aborts a request when its context is cancelled", func() {
+		requestIDs := make(chan string, 1)
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestIDs <- r.Header.Get(RequestIDHeader)
+			<-r.Context().Done()
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		gc, err := NewClient(grafanaServer.URL, options)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var requestID string
+		go func() {
+			requestID = <-requestIDs
+			cancel()
+		}()
+
+		start := time.Now()
+		_, err = gc.IsGrafanaHealthy(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(requestID).NotTo(BeEmpty(), "the %s header is set", RequestIDHeader)
+	})
+})
+
+var _ = Describe("parseRetryAfter", func() {
+	It("parses a number of seconds", func() {
+		wait, ok := parseRetryAfter("2")
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(Equal(2 * time.Second))
+	})
+
+	It("parses a date", func() {
+		wait, ok := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(BeNumerically(">=", 59*time.Minute))
+	})
+
+	It("ignores an invalid value", func() {
+		_, ok := parseRetryAfter("soon")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,185 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-logr/logr"
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// GetUserID retrieves the ID of a Grafana user by its login or email.
+// As the lookup requires authentication, it also tells whether the client credentials are valid.
+func (gc *GrafanaClient) GetUserID(ctx context.Context, log logr.Logger, loginOrEmail string) (int64, error) {
+	log = log.WithValues("Resource", "User")
+
+	user, err := gc.api(ctx).UserByEmail(loginOrEmail)
+	if err != nil {
+		log.Error(err, "Failed to look up Grafana user", "login", loginOrEmail)
+		return -1, fmt.Errorf("failed to look up Grafana user '%s': %w", loginOrEmail, err)
+	}
+
+	return user.ID, nil
+}
+
+// orgUserLookup is a user found by the lookup of the organization users
+type orgUserLookup struct {
+	UserID int64  `json:"userId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+}
+
+// GetOrgUserID retrieves the ID of a user of the organization of the client by its login or email.
+// Unlike GetUserID, it does not require a Grafana server admin, an organization admin can look users up.
+func (gc *GrafanaClient) GetOrgUserID(ctx context.Context, log logr.Logger, loginOrEmail string) (int64, error) {
+	log = log.WithValues("Resource", "OrgUser")
+
+	// The lookup matches the query anywhere in the login, email or name of the users
+	var users []orgUserLookup
+	if err := gc.do(ctx, http.MethodGet, "/api/org/users/lookup?query="+url.QueryEscape(loginOrEmail), nil, &users); err != nil {
+		log.Error(err, "Failed to look up Grafana organization user", "login", loginOrEmail)
+		return -1, fmt.Errorf("failed to look up Grafana user '%s': %w", loginOrEmail, err)
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Login, loginOrEmail) || strings.EqualFold(user.Email, loginOrEmail) {
+			return user.UserID, nil
+		}
+	}
+
+	return -1, fmt.Errorf("user '%s' is not a member of the Grafana organization", loginOrEmail)
+}
+
+// UpdateUserPassword changes the password of a Grafana user through the admin API.
+func (gc *GrafanaClient) UpdateUserPassword(ctx context.Context, log logr.Logger, login string, password string) error {
+	log = log.WithValues("Resource", "User")
+
+	userID, err := gc.GetUserID(ctx, log, login)
+	if err != nil {
+		return err
+	}
+
+	if err := gc.api(ctx).UpdateUserPassword(userID, password); err != nil {
+		log.Error(err, "Failed to update Grafana user password", "login", login)
+		return fmt.Errorf("failed to update password of Grafana user '%s': %w", login, err)
+	}
+
+	log.Info("Successfully updated Grafana user password", "login", login)
+	return nil
+}
+
+// EnsureUser ensures that a user with the given login exists with the given email and name.
+// A user with a known ID is updated, otherwise it is looked up by login or created with the password.
+// It returns the ID of the user and whether it was created.
+func (gc *GrafanaClient) EnsureUser(ctx context.Context, log logr.Logger, userID int64, login string, email string, name string, password string) (int64, bool, error) {
+	log = log.WithValues("Resource", "User")
+
+	var user gapi.User
+	var err error
+	if userID != 0 {
+		user, err = gc.api(ctx).User(userID)
+	} else {
+		user, err = gc.api(ctx).UserByEmail(login)
+	}
+	if err != nil && !IsNotFound(err) {
+		log.Error(err, "Failed to get Grafana user", "login", login)
+		return -1, false, fmt.Errorf("failed to get Grafana user '%s': %w", login, err)
+	}
+
+	if err == nil {
+		if user.Login == login && user.Email == email && user.Name == name {
+			return user.ID, false, nil
+		}
+		log.Info("Updating Grafana user", "ID", user.ID, "login", login)
+		if err := gc.api(ctx).UserUpdate(gapi.User{ID: user.ID, Login: login, Email: email, Name: name}); err != nil {
+			return -1, false, fmt.Errorf("failed to update Grafana user '%s': %w", login, err)
+		}
+		return user.ID, false, nil
+	}
+
+	log.Info("Creating new Grafana user", "login", login)
+	userID, err = gc.api(ctx).CreateUser(gapi.User{Login: login, Email: email, Name: name, Password: password})
+	if err != nil {
+		return -1, false, fmt.Errorf("failed to create Grafana user '%s': %w", login, err)
+	}
+
+	return userID, true, nil
+}
+
+// SetUserPassword changes the password of a user through the admin API.
+func (gc *GrafanaClient) SetUserPassword(ctx context.Context, log logr.Logger, userID int64, password string) error {
+	if err := gc.api(ctx).UpdateUserPassword(userID, password); err != nil {
+		log.Error(err, "Failed to update Grafana user password", "ID", userID)
+		return fmt.Errorf("failed to update Grafana user password: %w", err)
+	}
+	return nil
+}
+
+// SetGrafanaAdmin grants or revokes the Grafana server admin permission of a user.
+func (gc *GrafanaClient) SetGrafanaAdmin(ctx context.Context, log logr.Logger, userID int64, isAdmin bool) error {
+	user, err := gc.api(ctx).User(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get Grafana user: %w", err)
+	}
+	if user.IsAdmin == isAdmin {
+		return nil
+	}
+
+	log.Info("Updating Grafana admin permission", "ID", userID, "grafanaAdmin", isAdmin)
+	if err := gc.api(ctx).UpdateUserPermissions(userID, isAdmin); err != nil {
+		return fmt.Errorf("failed to update Grafana admin permission: %w", err)
+	}
+	return nil
+}
+
+// SetOrganizationRole adds the user to the organization with the role, or updates its role.
+func (gc *GrafanaClient) SetOrganizationRole(ctx context.Context, log logr.Logger, orgID int64, userID int64, login string, role string) error {
+	users, err := gc.api(ctx).OrgUsers(orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list users of Grafana organization %d: %w", orgID, err)
+	}
+
+	for _, user := range users {
+		if user.UserID != userID {
+			continue
+		}
+		if user.Role == role {
+			return nil
+		}
+		log.Info("Updating Grafana organization role", "orgID", orgID, "login", login, "role", role)
+		if err := gc.api(ctx).UpdateOrgUser(orgID, userID, role); err != nil {
+			return fmt.Errorf("failed to update role in Grafana organization %d: %w", orgID, err)
+		}
+		return nil
+	}
+
+	log.Info("Adding user to Grafana organization", "orgID", orgID, "login", login, "role", role)
+	if err := gc.api(ctx).AddOrgUser(orgID, login, role); err != nil {
+		return fmt.Errorf("failed to add user to Grafana organization %d: %w", orgID, err)
+	}
+	return nil
+}
+
+// RemoveOrganizationUser removes the user from the organization, a user not member is ignored.
+func (gc *GrafanaClient) RemoveOrganizationUser(ctx context.Context, log logr.Logger, orgID int64, userID int64) error {
+	log.Info("Removing user from Grafana organization", "orgID", orgID, "userID", userID)
+	if err := gc.api(ctx).RemoveOrgUser(orgID, userID); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to remove user from Grafana organization %d: %w", orgID, err)
+	}
+	return nil
+}
+
+// DeleteUser deletes a user, a user already gone is ignored.
+func (gc *GrafanaClient) DeleteUser(ctx context.Context, log logr.Logger, userID int64) error {
+	log = log.WithValues("Resource", "User")
+
+	if err := gc.api(ctx).DeleteUser(userID); err != nil && !IsNotFound(err) {
+		log.Error(err, "Failed to delete Grafana user", "ID", userID)
+		return fmt.Errorf("failed to delete Grafana user: %w", err)
+	}
+
+	log.Info("Successfully deleted Grafana user", "ID", userID)
+	return nil
+}
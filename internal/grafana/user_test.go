@@ -0,0 +1,52 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetOrgUserID", func() {
+	var (
+		paths []string
+		gc    *GrafanaClient
+	)
+
+	BeforeEach(func() {
+		paths = nil
+		grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"userId": 1, "login": "alice", "email": "alice@example.com"},
+				{"userId": 2, "login": "alice2", "email": "alice2@example.com"},
+			})
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		var err error
+		gc, err = NewClient(grafanaServer.URL, DefaultTransportOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("finds the user matching the login or the email",
+		func(loginOrEmail string, expected int64) {
+			userID, err := gc.GetOrgUserID(context.Background(), logr.Discard(), loginOrEmail)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(userID).To(Equal(expected))
+			Expect(paths).To(HaveEach("/api/org/users/lookup"))
+		},
+		Entry("login", "alice", int64(1)),
+		Entry("email in another case", "Alice@example.com", int64(1)),
+		Entry("login prefixed by another one", "alice2", int64(2)),
+	)
+
+	It("finds nothing when the lookup only matches other users", func() {
+		_, err := gc.GetOrgUserID(context.Background(), logr.Discard(), "ali")
+		Expect(err).To(HaveOccurred())
+	})
+})
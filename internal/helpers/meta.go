@@ -28,3 +28,13 @@ func GetServiceURL(service *corev1.Service) string {
 	port := service.Spec.Ports[0].Port
 	return fmt.Sprintf("http://%s:%d", clusterIP, port)
 }
+
+// GetOperatorTokenSecretName returns the name of the Secret holding the operator service account token
+func GetOperatorTokenSecretName(crName string) string {
+	return GetPrefixedName(crName, "operator-token")
+}
+
+// GetOrganizationTokenSecretName returns the name of the Secret holding the operator service account token of an organization
+func GetOrganizationTokenSecretName(crName string) string {
+	return GetPrefixedName(crName, "org-token")
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PendingPasswordKey holds the new admin password while a rotation is in progress
+	PendingPasswordKey = "pending_password"
+
+	// ServiceAccountTokenKey holds the token of the operator service account
+	ServiceAccountTokenKey = "token"
+)
+
+// GetCredentialsFromSecret returns the username and password stored in the Secret under the given keys.
+// It returns an error if one of the keys is missing or empty.
+func GetCredentialsFromSecret(secret *corev1.Secret, usernameKey string, passwordKey string) (string, string, error) {
+	username := string(secret.Data[usernameKey])
+	if username == "" {
+		return "", "", fmt.Errorf("key '%s' not found in Secret %s", usernameKey, secret.Name)
+	}
+
+	password := string(secret.Data[passwordKey])
+	if password == "" {
+		return "", "", fmt.Errorf("key '%s' not found in Secret %s", passwordKey, secret.Name)
+	}
+
+	return username, password, nil
+}
+
+// GetCredentialsChecksum returns a SHA-256 hash of the given credentials
+func GetCredentialsChecksum(username string, password string) string {
+	hash := sha256.Sum256([]byte(username + password))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsCredentialsRotationPending reports whether a new password has been staged in the Secret
+// but not yet committed, meaning a rotation is in progress.
+func IsCredentialsRotationPending(secret *corev1.Secret) bool {
+	_, ok := secret.Data[PendingPasswordKey]
+	return ok
+}
@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+)
+
+// variableReference matches $(name), and $$(name) which escapes it
+var variableReference = regexp.MustCompile(`\$?\$\(([a-zA-Z_][a-zA-Z0-9_]*)\)`)
+
+// VariableReferences returns the names of the variables referenced as $(name) in the strings of a JSON document, sorted
+func VariableReferences(document interface{}) []string {
+	referenced := map[string]bool{}
+	walkStrings(document, func(str string) {
+		for _, match := range variableReference.FindAllStringSubmatch(str, -1) {
+			if match[0][1] != '$' {
+				referenced[match[1]] = true
+			}
+		}
+	})
+
+	names := make([]string, 0, len(referenced))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandVariables returns a copy of a JSON document where the references to the variables in its strings are
+// replaced by their values. A string only made of a reference to a number or a boolean takes the value with its type.
+// The names of the variables referenced but missing from the values are returned, sorted.
+func ExpandVariables(document interface{}, values map[string]string) (interface{}, []string) {
+	missing := map[string]bool{}
+	expanded := expandVariables(document, values, missing)
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return expanded, names
+}
+
+func expandVariables(document interface{}, values map[string]string, missing map[string]bool) interface{} {
+	switch document := document.(type) {
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(document))
+		for key, value := range document {
+			expanded[key] = expandVariables(value, values, missing)
+		}
+		return expanded
+	case []interface{}:
+		expanded := make([]interface{}, 0, len(document))
+		for _, value := range document {
+			expanded = append(expanded, expandVariables(value, values, missing))
+		}
+		return expanded
+	case string:
+		if match := variableReference.FindStringSubmatch(document); match != nil && match[0] == document && match[0][1] != '$' {
+			if value, ok := values[match[1]]; ok {
+				var typed interface{}
+				if err := json.Unmarshal([]byte(value), &typed); err == nil {
+					switch typed.(type) {
+					case float64, bool:
+						return typed
+					}
+				}
+			}
+		}
+		return variableReference.ReplaceAllStringFunc(document, func(reference string) string {
+			if reference[1] == '$' {
+				return reference[1:]
+			}
+			name := reference[2 : len(reference)-1]
+			value, ok := values[name]
+			if !ok {
+				missing[name] = true
+				return reference
+			}
+			return value
+		})
+	default:
+		return document
+	}
+}
+
+// walkStrings calls visit with every string of a JSON document
+func walkStrings(document interface{}, visit func(string)) {
+	switch document := document.(type) {
+	case map[string]interface{}:
+		for _, value := range document {
+			walkStrings(value, visit)
+		}
+	case []interface{}:
+		for _, value := range document {
+			walkStrings(value, visit)
+		}
+	case string:
+		visit(document)
+	}
+}
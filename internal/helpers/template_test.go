@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func unmarshalDocument(raw string) interface{} {
+	var document interface{}
+	Expect(json.Unmarshal([]byte(raw), &document)).To(Succeed())
+	return document
+}
+
+var _ = Describe("Dashboard variables", func() {
+	DescribeTable("VariableReferences",
+		func(document string, want []string) {
+			Expect(VariableReferences(unmarshalDocument(document))).To(Equal(want))
+		},
+		Entry("no reference", `{"title": "Overview", "refresh": 30}`, []string{}),
+		Entry("nested references sorted once", `{"title": "$(env) $(cluster)", "panels": [{"datasource": "$(cluster)"}]}`, []string{"cluster", "env"}),
+		Entry("escaped reference skipped", `{"title": "$$(env) $(cluster)"}`, []string{"cluster"}),
+		Entry("invalid name skipped", `{"title": "$(1env) $(env-name) $env"}`, []string{}),
+		Entry("keys left alone", `{"$(env)": "prod"}`, []string{}),
+	)
+
+	DescribeTable("ExpandVariables",
+		func(document string, want string, missing []string) {
+			values := map[string]string{
+				"env":       "prod",
+				"threshold": "90",
+				"ratio":     "0.5",
+				"enabled":   "true",
+				"quoted":    `"90"`,
+				"labels":    `{"team": "sre"}`,
+			}
+			original := unmarshalDocument(document)
+			expanded, gotMissing := ExpandVariables(original, values)
+			Expect(expanded).To(Equal(unmarshalDocument(want)))
+			Expect(gotMissing).To(Equal(missing))
+			Expect(original).To(Equal(unmarshalDocument(document)), "the document is left alone")
+		},
+		Entry("string substituted", `{"title": "Nodes $(env)", "panels": [{"title": "$(env)"}]}`, `{"title": "Nodes prod", "panels": [{"title": "prod"}]}`, []string{}),
+		Entry("number typed", `{"threshold": "$(threshold)", "ratio": "$(ratio)"}`, `{"threshold": 90, "ratio": 0.5}`, []string{}),
+		Entry("boolean typed", `{"enabled": "$(enabled)"}`, `{"enabled": true}`, []string{}),
+		Entry("number within a string kept as a string", `{"title": "Above $(threshold)%"}`, `{"title": "Above 90%"}`, []string{}),
+		Entry("JSON string and object kept as strings", `{"quoted": "$(quoted)", "labels": "$(labels)"}`, `{"quoted": "\"90\"", "labels": "{\"team\": \"sre\"}"}`, []string{}),
+		Entry("escaped reference unescaped", `{"title": "$$(env) is $(env)", "query": "$$(threshold)"}`, `{"title": "$(env) is prod", "query": "$(threshold)"}`, []string{}),
+		Entry("missing references kept and reported", `{"title": "$(region) $(env) $(cluster)", "limit": "$(cluster)"}`, `{"title": "$(region) prod $(cluster)", "limit": "$(cluster)"}`, []string{"cluster", "region"}),
+		Entry("other values left alone", `{"refresh": 30, "editable": false, "tags": null, "$(env)": "key"}`, `{"refresh": 30, "editable": false, "tags": null, "$(env)": "key"}`, []string{}),
+	)
+})
@@ -5,7 +5,7 @@ import (
 	"reflect"
 
 	"github.com/go-logr/logr"
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -28,7 +28,7 @@ func NewConfigMapReconciler(client client.Client) *ConfigMapReconciler {
 	}
 }
 
-func (r *ConfigMapReconciler) Reconcile(ctx context.Context, cr *grafanav1alpha1.GrafanaInstance, log logr.Logger) error {
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, cr *grafanav1beta1.GrafanaInstance, log logr.Logger) error {
 	log = log.WithValues("Resource", "ConfigMap")
 	log.Info("Reconciling ConfigMap")
 
@@ -64,6 +64,9 @@ func (r *ConfigMapReconciler) Reconcile(ctx context.Context, cr *grafanav1alpha1
 	// Check if this ConfigMap already exists
 	found := &corev1.ConfigMap{}
 	if err = r.Client.Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: cr.Namespace}, found); err != nil && errors.IsNotFound(err) {
+		if err := setOwner(r.Client, cr, configMap); err != nil {
+			return err
+		}
 		// Create the ConfigMap since it doesn't exist
 		log.Info("Creating a new ConfigMap")
 		err = r.Client.Create(ctx, configMap)
@@ -76,9 +79,12 @@ func (r *ConfigMapReconciler) Reconcile(ctx context.Context, cr *grafanav1alpha1
 		return err
 	} else {
 		// ConfigMap already exists, check if it needs to be updated
-		if !reflect.DeepEqual(configMap.Data, found.Data) {
+		if !reflect.DeepEqual(configMap.Data, found.Data) || !metav1.IsControlledBy(found, cr) {
 			// Update the found object and write the result back if there are any changes
 			found.Data = configMap.Data
+			if err := setOwner(r.Client, cr, found); err != nil {
+				return err
+			}
 			log.Info("Updating ConfigMap")
 			err = r.Client.Update(ctx, found)
 			if err != nil {
@@ -0,0 +1,169 @@
+package reconcilers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type CredentialsRotationReconciler struct {
+	Client  client.Client
+	Clients *grafana.ClientRegistry
+}
+
+func NewCredentialsRotationReconciler(client client.Client, clients *grafana.ClientRegistry) *CredentialsRotationReconciler {
+	return &CredentialsRotationReconciler{
+		Client:  client,
+		Clients: clients,
+	}
+}
+
+// Reconcile rotates the admin password when the rotation policy or the trigger annotation asks for it.
+// The new password is staged in the Secret first, then changed in Grafana and only then committed,
+// so an interrupted rotation can always be resumed.
+func (r *CredentialsRotationReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
+	log = log.WithValues("Resource", "CredentialsRotation")
+	log.Info("Reconciling credentials rotation")
+
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: cr.Spec.CredentialsSecretName, Namespace: cr.Namespace}, secret)
+	if err != nil {
+		log.Error(err, "Failed to get Secret")
+		return err
+	}
+
+	// Resume an interrupted rotation before anything else
+	pending := helpers.IsCredentialsRotationPending(secret)
+	if !pending && !IsCredentialsRotationDue(cr, time.Now()) {
+		log.Info("Skip reconcile: credentials rotation not due")
+		return nil
+	}
+
+	if !IsOperatorManagedSecret(secret) {
+		log.Info("Skip reconcile: user-managed Secret is never rotated")
+		return nil
+	}
+
+	username, password, err := helpers.GetCredentialsFromSecret(secret, cr.GetUsernameKey(), cr.GetPasswordKey())
+	if err != nil {
+		log.Error(err, "Invalid credentials Secret")
+		return err
+	}
+
+	grafanaURL, err := getGrafanaURL(ctx, r.Client, cr)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana URL")
+		return err
+	}
+
+	auths, err := grafana.GetInstanceAuthenticators(ctx, r.Client, cr)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana authenticators")
+		return err
+	}
+
+	transport := r.Clients.TransportOptions(cr)
+	grafanaClient, err := grafana.NewClient(grafanaURL, transport, append(auths, &grafana.BasicAuthenticator{Username: username, Password: password})...)
+	if err != nil {
+		return err
+	}
+	if err := checkGrafanaReady(ctx, grafanaClient); err != nil {
+		return err
+	}
+
+	// Stage the new password
+	if !pending {
+		newPassword, err := helpers.GenerateRandomString(32)
+		if err != nil {
+			log.Error(err, "Failed to generate password")
+			return err
+		}
+
+		log.Info("Staging new admin password")
+		secret.Data[helpers.PendingPasswordKey] = []byte(newPassword)
+		if err := r.Client.Update(ctx, secret); err != nil {
+			log.Error(err, "Failed to update Secret")
+			return err
+		}
+	}
+	newPassword := string(secret.Data[helpers.PendingPasswordKey])
+
+	// Change the password in Grafana, unless a previous attempt already did
+	if err := r.updateGrafanaPassword(ctx, log, grafanaClient, grafanaURL, transport, auths, username, newPassword); err != nil {
+		return err
+	}
+
+	// Commit the new password
+	log.Info("Committing new admin password")
+	secret.Data[cr.GetPasswordKey()] = []byte(newPassword)
+	delete(secret.Data, helpers.PendingPasswordKey)
+	secret.Annotations[SecretChecksumAnnotation] = helpers.GetCredentialsChecksum(username, newPassword)
+	if err := r.Client.Update(ctx, secret); err != nil {
+		log.Error(err, "Failed to update Secret")
+		return err
+	}
+
+	// Record the rotation right away, the status is otherwise only updated once every stage succeeded
+	// and a later stage failing would rotate the password again
+	now := metav1.Now()
+	cr.Status.Credentials.LastRotationTime = &now
+	cr.Status.Credentials.LastRotationTrigger = cr.Annotations[v1beta1.RotateCredentialsAnnotation]
+	if err := r.Client.Status().Update(ctx, cr); err != nil {
+		log.Error(err, "Failed to update GrafanaInstance status")
+		return err
+	}
+
+	log.Info("Successfully rotated admin credentials")
+	return nil
+}
+
+func (r *CredentialsRotationReconciler) updateGrafanaPassword(ctx context.Context, log logr.Logger, grafanaClient *grafana.GrafanaClient, grafanaURL string, transport grafana.TransportOptions, auths []grafana.Authenticator, username string, newPassword string) error {
+	err := grafanaClient.UpdateUserPassword(ctx, log, username, newPassword)
+	if err == nil {
+		return nil
+	}
+
+	// The password may have been changed by an interrupted rotation
+	pendingClient, clientErr := grafana.NewClient(grafanaURL, transport, append(auths, &grafana.BasicAuthenticator{Username: username, Password: newPassword})...)
+	if clientErr != nil {
+		return clientErr
+	}
+	if _, lookupErr := pendingClient.GetUserID(ctx, log, username); lookupErr == nil {
+		log.Info("Admin password already changed in Grafana")
+		return nil
+	}
+
+	return err
+}
+
+// IsCredentialsRotationDue reports whether the admin password should be rotated,
+// either because the trigger annotation changed or because the interval elapsed.
+func IsCredentialsRotationDue(cr *v1beta1.GrafanaInstance, now time.Time) bool {
+	trigger := cr.Annotations[v1beta1.RotateCredentialsAnnotation]
+	if trigger != "" && trigger != cr.Status.Credentials.LastRotationTrigger {
+		return true
+	}
+
+	next, ok := NextCredentialsRotation(cr)
+	return ok && !now.Before(next)
+}
+
+// NextCredentialsRotation returns the time of the next scheduled rotation, if the instance has a rotation interval.
+func NextCredentialsRotation(cr *v1beta1.GrafanaInstance) (time.Time, bool) {
+	if cr.Spec.CredentialsRotation == nil || cr.Spec.CredentialsRotation.Interval.Duration <= 0 {
+		return time.Time{}, false
+	}
+
+	last := cr.CreationTimestamp.Time
+	if cr.Status.Credentials.LastRotationTime != nil {
+		last = cr.Status.Credentials.LastRotationTime.Time
+	}
+	return last.Add(cr.Spec.CredentialsRotation.Interval.Duration), true
+}
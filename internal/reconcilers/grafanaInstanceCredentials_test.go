@@ -0,0 +1,105 @@
+package reconcilers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("CredentialsRotationReconciler", func() {
+	var (
+		ctx           context.Context
+		grafanaServer *httptest.Server
+		cr            *v1beta1.GrafanaInstance
+		secret        *corev1.Secret
+		c             client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		grafanaServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, password, _ := r.BasicAuth(); password != "old-password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch {
+			case r.URL.Path == "/api/health":
+				_, _ = w.Write([]byte(`{"database": "ok", "version": "9.5.0"}`))
+			case r.URL.Path == "/api/users/lookup":
+				_, _ = w.Write([]byte(`{"id": 1}`))
+			case r.Method == http.MethodPut && r.URL.Path == "/api/admin/users/1/password":
+				_, _ = w.Write([]byte(`{}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(grafanaServer.Close)
+
+		cr = &v1beta1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "grafana",
+				Namespace:   "default",
+				Annotations: map[string]string{v1beta1.RotateCredentialsAnnotation: "1"},
+			},
+			Spec: v1beta1.GrafanaInstanceSpec{CredentialsSecretName: "grafana-credentials"},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        cr.Spec.CredentialsSecretName,
+				Namespace:   cr.Namespace,
+				Annotations: map[string]string{SecretGeneratedByAnnotation: OperatorName},
+			},
+			Data: map[string][]byte{v1beta1.DefaultUsernameKey: []byte("admin"), v1beta1.DefaultPasswordKey: []byte("old-password")},
+		}
+
+		// The Service of the instance points to the Grafana test server
+		parsed, err := url.Parse(grafanaServer.URL)
+		Expect(err).NotTo(HaveOccurred())
+		host, port, err := net.SplitHostPort(parsed.Host)
+		Expect(err).NotTo(HaveOccurred())
+		portNumber, err := strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: helpers.GetPrefixedName(cr.Name, "service"), Namespace: cr.Namespace},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: host,
+				Ports:     []corev1.ServicePort{{Port: int32(portNumber)}},
+			},
+		}
+
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cr, secret, service).Build()
+	})
+
+	It("commits the new password and records the rotation", func() {
+		r := NewCredentialsRotationReconciler(c, grafana.NewClientRegistry(grafana.DefaultHealthTTL, grafana.DefaultTransportOptions(), 0, 0))
+		Expect(r.Reconcile(ctx, cr, logr.Discard())).To(Succeed())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		newPassword := string(secret.Data[v1beta1.DefaultPasswordKey])
+		Expect(newPassword).NotTo(Equal("old-password"))
+		Expect(helpers.IsCredentialsRotationPending(secret)).To(BeFalse())
+		Expect(secret.Annotations).To(HaveKeyWithValue(SecretChecksumAnnotation, helpers.GetCredentialsChecksum("admin", newPassword)))
+
+		// The rotation is recorded even when a later stage fails before the status is updated
+		persisted := &v1beta1.GrafanaInstance{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cr), persisted)).To(Succeed())
+		Expect(persisted.Status.Credentials.LastRotationTrigger).To(Equal("1"))
+		Expect(persisted.Status.Credentials.LastRotationTime).NotTo(BeNil())
+		Expect(IsCredentialsRotationDue(persisted, persisted.Status.Credentials.LastRotationTime.Time)).To(BeFalse())
+	})
+})
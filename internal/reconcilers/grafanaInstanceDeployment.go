@@ -2,10 +2,9 @@ package reconcilers
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/go-logr/logr"
-	"github.com/minicali/grafana-operator/api/v1alpha1"
+	"github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -25,7 +24,7 @@ func NewDeploymentReconciler(client client.Client) *DeploymentReconciler {
 	}
 }
 
-func (r *DeploymentReconciler) Reconcile(ctx context.Context, cr *v1alpha1.GrafanaInstance, log logr.Logger) error {
+func (r *DeploymentReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
 	log = log.WithValues("Resource", "Deployment")
 	log.Info("Reconciling Deployment")
 
@@ -39,19 +38,21 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, cr *v1alpha1.Grafa
 		Spec: getGrafanaDeploymentSpec(cr),
 	}
 
-	// Fetch the Secret to get the hash annotation
+	// Fetch the Secret to compute the credentials hash
 	secret := &corev1.Secret{}
 	err := r.Client.Get(ctx, client.ObjectKey{Name: cr.Spec.CredentialsSecretName, Namespace: cr.Namespace}, secret)
 	if err != nil {
-		log.Error(err, "Failed to get Secret for hash annotation")
+		log.Error(err, "Failed to get Secret for credentials hash")
 		return err
 	}
 
-	hash, ok := secret.Annotations["checksum"]
-	if !ok {
-		log.Error(fmt.Errorf("checksum annotation not found"), "Checksum annotation not found on Secret", "SecretName", cr.Spec.CredentialsSecretName)
-		return fmt.Errorf("checksum annotation not found on Secret %s", cr.Spec.CredentialsSecretName)
+	// Compute the checksum from the actual data, user-managed Secrets carry no annotation
+	username, password, err := helpers.GetCredentialsFromSecret(secret, cr.GetUsernameKey(), cr.GetPasswordKey())
+	if err != nil {
+		log.Error(err, "Invalid credentials Secret", "SecretName", cr.Spec.CredentialsSecretName)
+		return err
 	}
+	hash := helpers.GetCredentialsChecksum(username, password)
 
 	// Add or update the hash annotation on the Deployment
 	if deployment.Annotations == nil {
@@ -63,6 +64,9 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, cr *v1alpha1.Grafa
 	found := &appsv1.Deployment{}
 	err = r.Client.Get(ctx, client.ObjectKey{Name: deployment.Name, Namespace: cr.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
+		if err := setOwner(r.Client, cr, deployment); err != nil {
+			return err
+		}
 		// Create the Deployment since it doesn't exist
 		log.Info("Creating a new Deployment")
 		err = r.Client.Create(ctx, deployment)
@@ -70,17 +74,46 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, cr *v1alpha1.Grafa
 			log.Error(err, "Failed to create Deployment")
 			return err
 		}
+		return nil
 	} else if err != nil {
 		log.Error(err, "Failed to get Deployment")
 		return err
-	} else {
+	}
+
+	// Repair the Grafana container edited out of band, the other fields are defaulted by the API server
+	container := getGrafanaContainer(found)
+	if container != nil && container.Image == cr.Spec.Image && metav1.IsControlledBy(found, cr) {
 		log.Info("Skip reconcile: Deployment already exists")
+		return nil
+	}
+	if container == nil {
+		found.Spec.Template.Spec.Containers = append(found.Spec.Template.Spec.Containers, deployment.Spec.Template.Spec.Containers[0])
+	} else {
+		container.Image = cr.Spec.Image
+	}
+	if err := setOwner(r.Client, cr, found); err != nil {
+		return err
+	}
+	log.Info("Updating Deployment")
+	if err := r.Client.Update(ctx, found); err != nil {
+		log.Error(err, "Failed to update Deployment")
+		return err
 	}
 
 	return nil
 }
 
-func getGrafanaDeploymentSpec(cr *v1alpha1.GrafanaInstance) appsv1.DeploymentSpec {
+// getGrafanaContainer returns the Grafana container of the Deployment
+func getGrafanaContainer(deployment *appsv1.Deployment) *corev1.Container {
+	for i := range deployment.Spec.Template.Spec.Containers {
+		if deployment.Spec.Template.Spec.Containers[i].Name == "grafana" {
+			return &deployment.Spec.Template.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func getGrafanaDeploymentSpec(cr *v1beta1.GrafanaInstance) appsv1.DeploymentSpec {
 	pvcName := helpers.GetPrefixedName(cr.Name, "pvc")
 
 	return appsv1.DeploymentSpec{
@@ -124,7 +157,7 @@ func getGrafanaDeploymentSpec(cr *v1alpha1.GrafanaInstance) appsv1.DeploymentSpe
 										LocalObjectReference: corev1.LocalObjectReference{
 											Name: cr.Spec.CredentialsSecretName,
 										},
-										Key: "admin_username",
+										Key: cr.GetUsernameKey(),
 									},
 								},
 							},
@@ -135,7 +168,7 @@ func getGrafanaDeploymentSpec(cr *v1alpha1.GrafanaInstance) appsv1.DeploymentSpe
 										LocalObjectReference: corev1.LocalObjectReference{
 											Name: cr.Spec.CredentialsSecretName,
 										},
-										Key: "admin_password",
+										Key: cr.GetPasswordKey(),
 									},
 								},
 							},
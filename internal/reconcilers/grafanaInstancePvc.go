@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
-	"github.com/minicali/grafana-operator/api/v1alpha1"
+	"github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -23,7 +23,7 @@ func NewPVCReconciler(client client.Client) *PVCReconciler {
 	}
 }
 
-func (r *PVCReconciler) Reconcile(ctx context.Context, cr *v1alpha1.GrafanaInstance, log logr.Logger) error {
+func (r *PVCReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
 	log = log.WithValues("Resource", "PVC")
 	log.Info("Reconciling PVC")
 
@@ -41,6 +41,12 @@ func (r *PVCReconciler) Reconcile(ctx context.Context, cr *v1alpha1.GrafanaInsta
 	found := &corev1.PersistentVolumeClaim{}
 	err := r.Client.Get(ctx, client.ObjectKey{Name: pvc.Name, Namespace: cr.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
+		// A retained PVC outlives the instance
+		if cr.GetPVCRetentionPolicy() == v1beta1.DeletionPolicyDelete {
+			if err := setOwner(r.Client, cr, pvc); err != nil {
+				return err
+			}
+		}
 		// Create the PVC since it doesn't exist
 		log.Info("Creating a new PVC")
 		err = r.Client.Create(ctx, pvc)
@@ -48,12 +54,23 @@ func (r *PVCReconciler) Reconcile(ctx context.Context, cr *v1alpha1.GrafanaInsta
 			log.Error(err, "Failed to create PVC")
 			return err
 		}
+		return nil
 	} else if err != nil {
 		log.Error(err, "Failed to get PVC")
 		return err
+	}
+
+	// The retention policy may have changed since the PVC was created
+	if cr.GetPVCRetentionPolicy() == v1beta1.DeletionPolicyDelete {
+		err = ensureOwner(ctx, r.Client, cr, found)
 	} else {
-		log.Info("Skip reconcile: PVC already exists")
+		err = releaseOwner(ctx, r.Client, cr, found)
+	}
+	if err != nil {
+		log.Error(err, "Failed to update PVC owner")
+		return err
 	}
+	log.Info("Skip reconcile: PVC already exists")
 
 	return nil
 }
@@ -0,0 +1,197 @@
+package reconcilers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ReprovisionReconciler struct {
+	Client client.Client
+}
+
+func NewReprovisionReconciler(client client.Client) *ReprovisionReconciler {
+	return &ReprovisionReconciler{
+		Client: client,
+	}
+}
+
+// Reconcile re-provisions the resources of an instance that lost its data. The Grafana IDs recorded
+// in their status are cleared, which makes their controllers create them again. The resources
+// referencing an organization or a team wait for it, so they are provisioned in the order of the phases.
+func (r *ReprovisionReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
+	reprovision := cr.Status.Reprovision
+	if reprovision == nil || reprovision.Phase == v1beta1.ReprovisionPhaseCompleted {
+		return nil
+	}
+	log = log.WithValues("Resource", "Reprovision")
+
+	dependents, err := r.listDependents(ctx, cr)
+	if err != nil {
+		log.Error(err, "Failed to list the resources of the instance")
+		return err
+	}
+
+	// The IDs are cleared once, when the data loss was just detected. The phase is recorded first,
+	// a reset failing halfway must not clear the resources created again since.
+	if reprovision.Phase == "" {
+		log.Info("Grafana lost its data, re-provisioning its resources", "since", reprovision.StartedAt)
+		reprovision.Phase = dependents[0].name
+		if err := r.Client.Status().Update(ctx, cr); err != nil {
+			log.Error(err, "Failed to update GrafanaInstance status")
+			return err
+		}
+
+		var resetErr error
+		for _, phase := range dependents {
+			for _, obj := range phase.objects {
+				phase.reset(obj)
+				if err := r.Client.Status().Update(ctx, obj); err != nil {
+					log.Error(err, "Failed to reset the status", "Kind", phase.name, "Name", client.ObjectKeyFromObject(obj))
+					if resetErr == nil {
+						resetErr = err
+					}
+				}
+			}
+		}
+		if resetErr != nil {
+			return resetErr
+		}
+	}
+
+	for _, phase := range dependents {
+		pending := 0
+		for _, obj := range phase.objects {
+			if !phase.provisioned(obj) {
+				pending++
+			}
+		}
+		if pending > 0 {
+			log.Info("Re-provisioning", "Phase", phase.name, "Pending", pending)
+			reprovision.Phase = phase.name
+			reprovision.Pending = pending
+			return nil
+		}
+	}
+
+	log.Info("Re-provisioning completed")
+	now := metav1.NewTime(time.Now())
+	reprovision.Phase = v1beta1.ReprovisionPhaseCompleted
+	reprovision.Pending = 0
+	reprovision.CompletedAt = &now
+	return nil
+}
+
+// reprovisionPhase holds the resources of a kind, in the order they are re-provisioned
+type reprovisionPhase struct {
+	name        string
+	objects     []client.Object
+	reset       func(client.Object)
+	provisioned func(client.Object) bool
+}
+
+// listDependents returns the resources of the instance by phase
+func (r *ReprovisionReconciler) listDependents(ctx context.Context, cr *v1beta1.GrafanaInstance) ([]reprovisionPhase, error) {
+	instanceRef := v1beta1.GrafanaInstanceRef{Name: cr.Name, Namespace: cr.Namespace}
+
+	orgs := &v1beta1.GrafanaOrganizationList{}
+	teams := &v1beta1.GrafanaTeamList{}
+	users := &v1beta1.GrafanaUserList{}
+	libraryPanels := &v1beta1.GrafanaLibraryPanelList{}
+	dashboards := &v1beta1.GrafanaDashboardList{}
+	for _, list := range []client.ObjectList{orgs, teams, users, libraryPanels, dashboards} {
+		if err := r.Client.List(ctx, list); err != nil {
+			return nil, err
+		}
+	}
+
+	phases := []reprovisionPhase{
+		{
+			name: v1beta1.ReprovisionPhaseOrganizations,
+			reset: func(obj client.Object) {
+				obj.(*v1beta1.GrafanaOrganization).Status.OrgID = 0
+			},
+			provisioned: func(obj client.Object) bool {
+				return obj.(*v1beta1.GrafanaOrganization).Status.OrgID != 0
+			},
+		},
+		{
+			name: v1beta1.ReprovisionPhaseTeams,
+			reset: func(obj client.Object) {
+				obj.(*v1beta1.GrafanaTeam).Status = v1beta1.GrafanaTeamStatus{}
+			},
+			provisioned: func(obj client.Object) bool {
+				return obj.(*v1beta1.GrafanaTeam).Status.TeamID != 0
+			},
+		},
+		{
+			name: v1beta1.ReprovisionPhaseUsers,
+			reset: func(obj client.Object) {
+				obj.(*v1beta1.GrafanaUser).Status = v1beta1.GrafanaUserStatus{}
+			},
+			provisioned: func(obj client.Object) bool {
+				return obj.(*v1beta1.GrafanaUser).Status.UserID != 0
+			},
+		},
+		{
+			name: v1beta1.ReprovisionPhaseLibraryPanels,
+			// The UID is kept, the dashboards go on referencing the library panels by it
+			reset: func(obj client.Object) {
+				libraryPanel := obj.(*v1beta1.GrafanaLibraryPanel)
+				libraryPanel.Status.ObservedGeneration = 0
+				libraryPanel.Status.FolderUID = ""
+				libraryPanel.Status.FolderCreated = false
+				libraryPanel.Status.Version = 0
+			},
+			provisioned: func(obj client.Object) bool {
+				return obj.(*v1beta1.GrafanaLibraryPanel).Status.Version != 0
+			},
+		},
+		{
+			name: v1beta1.ReprovisionPhaseDashboards,
+			// The UID is kept, the dashboards whose UID Grafana generated keep their URLs
+			reset: func(obj client.Object) {
+				dashboard := obj.(*v1beta1.GrafanaDashboard)
+				dashboard.Status.FolderUID = ""
+				dashboard.Status.FolderCreated = false
+				dashboard.Status.Version = 0
+				dashboard.Status.Versions = nil
+			},
+			provisioned: func(obj client.Object) bool {
+				return obj.(*v1beta1.GrafanaDashboard).Status.Version != 0
+			},
+		},
+	}
+
+	for i := range orgs.Items {
+		if orgs.Items[i].Spec.GrafanaInstanceRef == instanceRef {
+			phases[0].objects = append(phases[0].objects, &orgs.Items[i])
+		}
+	}
+	for i := range teams.Items {
+		if teams.Items[i].Spec.GrafanaInstanceRef == instanceRef {
+			phases[1].objects = append(phases[1].objects, &teams.Items[i])
+		}
+	}
+	for i := range users.Items {
+		if users.Items[i].Spec.GrafanaInstanceRef == instanceRef {
+			phases[2].objects = append(phases[2].objects, &users.Items[i])
+		}
+	}
+	for i := range libraryPanels.Items {
+		libraryPanels.Items[i].SetDefaults()
+		if libraryPanels.Items[i].Spec.GrafanaInstanceRef == instanceRef {
+			phases[3].objects = append(phases[3].objects, &libraryPanels.Items[i])
+		}
+	}
+	for i := range dashboards.Items {
+		if dashboards.Items[i].Spec.GrafanaInstanceRef == instanceRef {
+			phases[4].objects = append(phases[4].objects, &dashboards.Items[i])
+		}
+	}
+	return phases, nil
+}
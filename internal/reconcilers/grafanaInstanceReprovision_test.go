@@ -0,0 +1,118 @@
+package reconcilers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ReprovisionReconciler", func() {
+	var (
+		ctx          context.Context
+		cr           *v1beta1.GrafanaInstance
+		org          *v1beta1.GrafanaOrganization
+		dashboard    *v1beta1.GrafanaDashboard
+		second       *v1beta1.GrafanaDashboard
+		other        *v1beta1.GrafanaDashboard
+		libraryPanel *v1beta1.GrafanaLibraryPanel
+		c            client.Client
+		r            *ReprovisionReconciler
+	)
+
+	get := func(obj client.Object) {
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		instanceRef := v1beta1.GrafanaInstanceRef{Name: "grafana", Namespace: "default"}
+		cr = &v1beta1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceRef.Name, Namespace: instanceRef.Namespace},
+			Status: v1beta1.GrafanaInstanceStatus{
+				Reprovision: &v1beta1.ReprovisionStatus{StartedAt: metav1.NewTime(time.Now())},
+			},
+		}
+		org = &v1beta1.GrafanaOrganization{
+			ObjectMeta: metav1.ObjectMeta{Name: "org", Namespace: "default"},
+			Spec:       v1beta1.GrafanaOrganizationSpec{GrafanaInstanceRef: instanceRef},
+			Status:     v1beta1.GrafanaOrganizationStatus{OrgID: 2},
+		}
+		dashboard = &v1beta1.GrafanaDashboard{
+			ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "default"},
+			Spec:       v1beta1.GrafanaDashboardSpec{GrafanaInstanceRef: instanceRef},
+			Status:     v1beta1.GrafanaDashboardStatus{FolderUID: "folder", FolderCreated: true, DashboardUID: "dashboard", Version: 4},
+		}
+		second = dashboard.DeepCopy()
+		second.Name = "second"
+		other = dashboard.DeepCopy()
+		other.Name = "other"
+		other.Spec.GrafanaInstanceRef.Name = "other"
+		libraryPanel = &v1beta1.GrafanaLibraryPanel{
+			ObjectMeta: metav1.ObjectMeta{Name: "panel", Namespace: "default"},
+			Spec:       v1beta1.GrafanaLibraryPanelSpec{GrafanaInstanceRef: instanceRef},
+			Status:     v1beta1.GrafanaLibraryPanelStatus{ObservedGeneration: 1, UID: "panel", Version: 1},
+		}
+
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cr, org, dashboard, second, other, libraryPanel).Build()
+		r = NewReprovisionReconciler(c)
+	})
+
+	It("re-provisions the resources of the instance phase by phase", func() {
+		By("clearing the IDs when the data loss was just detected")
+		Expect(r.Reconcile(ctx, cr, logr.Discard())).To(Succeed())
+		Expect(cr.Status.Reprovision.Phase).To(Equal(v1beta1.ReprovisionPhaseOrganizations))
+		Expect(cr.Status.Reprovision.Pending).To(Equal(1))
+
+		// The phase is persisted before the reset, so it is never repeated
+		persisted := &v1beta1.GrafanaInstance{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cr), persisted)).To(Succeed())
+		Expect(persisted.Status.Reprovision).NotTo(BeNil())
+		Expect(persisted.Status.Reprovision.Phase).NotTo(BeEmpty())
+
+		for _, obj := range []*v1beta1.GrafanaDashboard{dashboard, second} {
+			get(obj)
+			Expect(obj.Status.Version).To(BeZero())
+			Expect(obj.Status.FolderUID).To(BeEmpty())
+			Expect(obj.Status.FolderCreated).To(BeFalse())
+			// The UID Grafana generated is reused, the URL of the dashboard does not change
+			Expect(obj.Status.DashboardUID).To(Equal("dashboard"))
+		}
+		get(libraryPanel)
+		Expect(libraryPanel.Status.Version).To(BeZero())
+		Expect(libraryPanel.Status.UID).To(Equal("panel"))
+		get(other)
+		Expect(other.Status.Version).NotTo(BeZero(), "the dashboard of another instance is left alone")
+
+		By("waiting for the library panels once the organization is provisioned again")
+		get(org)
+		org.Status.OrgID = 3
+		Expect(c.Status().Update(ctx, org)).To(Succeed())
+		Expect(r.Reconcile(ctx, cr, logr.Discard())).To(Succeed())
+		Expect(cr.Status.Reprovision.Phase).To(Equal(v1beta1.ReprovisionPhaseLibraryPanels))
+		Expect(cr.Status.Reprovision.Pending).To(Equal(1))
+
+		By("waiting for the dashboards then")
+		libraryPanel.Status.Version = 1
+		Expect(c.Status().Update(ctx, libraryPanel)).To(Succeed())
+		Expect(r.Reconcile(ctx, cr, logr.Discard())).To(Succeed())
+		Expect(cr.Status.Reprovision.Phase).To(Equal(v1beta1.ReprovisionPhaseDashboards))
+		Expect(cr.Status.Reprovision.Pending).To(Equal(2))
+
+		By("completing once every dashboard is provisioned again")
+		for _, obj := range []*v1beta1.GrafanaDashboard{dashboard, second} {
+			obj.Status.Version = 1
+			Expect(c.Status().Update(ctx, obj)).To(Succeed())
+		}
+		Expect(r.Reconcile(ctx, cr, logr.Discard())).To(Succeed())
+		Expect(cr.Status.Reprovision.Phase).To(Equal(v1beta1.ReprovisionPhaseCompleted))
+		Expect(cr.Status.Reprovision.CompletedAt).NotTo(BeNil())
+	})
+})
@@ -4,15 +4,18 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
-	"github.com/minicali/grafana-operator/api/v1alpha1"
+	"github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+)
 
-	"crypto/sha256"
-	"encoding/hex"
+const (
+	SecretGeneratedByAnnotation = "generated-by"
+	SecretChecksumAnnotation    = "checksum"
+	OperatorName                = "grafana-operator"
 )
 
 type SecretReconciler struct {
@@ -25,66 +28,103 @@ func NewSecretReconciler(client client.Client) *SecretReconciler {
 	}
 }
 
-func (r *SecretReconciler) Reconcile(ctx context.Context, cr *v1alpha1.GrafanaInstance, log logr.Logger) error {
+func (r *SecretReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
 	log = log.WithValues("Resource", "Secret")
 	log.Info("Reconciling Secret")
 
-	// Generate credentials
-	username := helpers.GenerateRandomString(10)
-	password := helpers.GenerateRandomString(10)
-
-	// Define a new Secret object
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cr.Spec.CredentialsSecretName,
-			Namespace: cr.Namespace,
-			Annotations: map[string]string{
-				"generated-by": "grafana-operator", // New annotation
-			},
-		},
-		StringData: map[string]string{
-			"admin_username": username,
-			"admin_password": password,
-		},
-		Type: corev1.SecretTypeOpaque,
-	}
-
-	// Generate a hash of the secret data
-	hash := sha256.Sum256([]byte(username + password))
-	hashStr := hex.EncodeToString(hash[:])
-
 	// Check if this Secret already exists
 	found := &corev1.Secret{}
-	err := r.Client.Get(ctx, client.ObjectKey{Name: secret.Name, Namespace: cr.Namespace}, found)
+	err := r.Client.Get(ctx, client.ObjectKey{Name: cr.Spec.CredentialsSecretName, Namespace: cr.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
+		// Generate credentials
+		username, err := helpers.GenerateRandomString(10)
+		if err != nil {
+			log.Error(err, "Failed to generate username")
+			return err
+		}
+		password, err := helpers.GenerateRandomString(10)
+		if err != nil {
+			log.Error(err, "Failed to generate password")
+			return err
+		}
+
+		// Define a new Secret object
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cr.Spec.CredentialsSecretName,
+				Namespace: cr.Namespace,
+				Annotations: map[string]string{
+					SecretGeneratedByAnnotation: OperatorName,
+					SecretChecksumAnnotation:    helpers.GetCredentialsChecksum(username, password),
+				},
+			},
+			StringData: map[string]string{
+				cr.GetUsernameKey(): username,
+				cr.GetPasswordKey(): password,
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+		// The credentials are only useful with the data of the instance
+		if cr.GetPVCRetentionPolicy() == v1beta1.DeletionPolicyDelete {
+			if err := setOwner(r.Client, cr, secret); err != nil {
+				return err
+			}
+		}
+
 		// Create the Secret since it doesn't exist
 		log.Info("Creating a new Secret")
-		secret.Annotations["checksum"] = hashStr
-
 		err = r.Client.Create(ctx, secret)
 		if err != nil {
 			log.Error(err, "Failed to create Secret")
 			return err
 		}
+		return nil
 	} else if err != nil {
 		log.Error(err, "Failed to get Secret")
 		return err
+	}
+
+	// Both generated and user-managed Secrets must hold the configured keys
+	username, password, err := helpers.GetCredentialsFromSecret(found, cr.GetUsernameKey(), cr.GetPasswordKey())
+	if err != nil {
+		log.Error(err, "Invalid credentials Secret", "SecretName", found.Name)
+		return err
+	}
+
+	// A user-managed Secret is adopted as-is and never written to
+	if !IsOperatorManagedSecret(found) {
+		log.Info("Skip reconcile: Secret is user-managed")
+		return nil
+	}
+
+	// The retention policy may have changed since the Secret was generated
+	if cr.GetPVCRetentionPolicy() == v1beta1.DeletionPolicyDelete {
+		err = ensureOwner(ctx, r.Client, cr, found)
 	} else {
-		// Check if hash has changed
-		oldHash, ok := found.Annotations["checksum"]
-		if !ok || oldHash != hashStr {
-			log.Info("Secret has changed, updating Secret")
+		err = releaseOwner(ctx, r.Client, cr, found)
+	}
+	if err != nil {
+		log.Error(err, "Failed to update Secret owner")
+		return err
+	}
 
-			// Update annotation on Secret
-			found.Annotations["checksum"] = hashStr
-			if err := r.Client.Update(ctx, found); err != nil {
-				log.Error(err, "Failed to update Secret")
-				return err
-			}
-		} else {
-			log.Info("Skip reconcile: Secret already exists")
+	// Keep the checksum in line with the actual data
+	hashStr := helpers.GetCredentialsChecksum(username, password)
+	if found.Annotations[SecretChecksumAnnotation] != hashStr {
+		log.Info("Secret has changed, updating checksum")
+		found.Annotations[SecretChecksumAnnotation] = hashStr
+		if err := r.Client.Update(ctx, found); err != nil {
+			log.Error(err, "Failed to update Secret")
+			return err
 		}
+	} else {
+		log.Info("Skip reconcile: Secret already exists")
 	}
 
 	return nil
 }
+
+// IsOperatorManagedSecret reports whether the Secret was generated by the operator
+func IsOperatorManagedSecret(secret *corev1.Secret) bool {
+	return secret.Annotations[SecretGeneratedByAnnotation] == OperatorName
+}
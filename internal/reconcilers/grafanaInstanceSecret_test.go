@@ -0,0 +1,165 @@
+package reconcilers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = DescribeTable("IsOperatorManagedSecret",
+	func(annotations map[string]string, managed bool) {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+		Expect(IsOperatorManagedSecret(secret)).To(Equal(managed))
+	},
+	Entry("generated", map[string]string{SecretGeneratedByAnnotation: OperatorName}, true),
+	Entry("generated by another tool", map[string]string{SecretGeneratedByAnnotation: "helm"}, false),
+	Entry("user-managed", nil, false),
+)
+
+var _ = Describe("SecretReconciler", func() {
+	var (
+		ctx      context.Context
+		cr       *v1beta1.GrafanaInstance
+		existing *corev1.Secret
+		c        client.Client
+		key      client.ObjectKey
+		before   *corev1.Secret
+		err      error
+	)
+
+	operatorSecret := func(username string, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "grafana-credentials",
+				Namespace: "default",
+				Annotations: map[string]string{
+					SecretGeneratedByAnnotation: OperatorName,
+					SecretChecksumAnnotation:    helpers.GetCredentialsChecksum("admin", "admin"),
+				},
+			},
+			Data: map[string][]byte{v1beta1.DefaultUsernameKey: []byte(username), v1beta1.DefaultPasswordKey: []byte(password)},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cr = &v1beta1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "default", UID: "uid"},
+			Spec:       v1beta1.GrafanaInstanceSpec{CredentialsSecretName: "grafana-credentials"},
+		}
+		existing = nil
+	})
+
+	JustBeforeEach(func() {
+		builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+		if existing != nil {
+			builder = builder.WithObjects(existing)
+		}
+		c = builder.Build()
+		key = client.ObjectKey{Name: cr.Spec.CredentialsSecretName, Namespace: cr.Namespace}
+		before = &corev1.Secret{}
+		if existing != nil {
+			Expect(c.Get(ctx, key, before)).To(Succeed())
+		}
+
+		err = NewSecretReconciler(c).Reconcile(ctx, cr, logr.Discard())
+	})
+
+	getSecret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, key, secret)).To(Succeed())
+		return secret
+	}
+
+	Context("when no Secret exists", func() {
+		It("generates credentials matching their checksum and retains them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			secret := getSecret()
+			Expect(IsOperatorManagedSecret(secret)).To(BeTrue())
+			Expect(metav1.IsControlledBy(secret, cr)).To(BeFalse())
+			username, password := secret.StringData[cr.GetUsernameKey()], secret.StringData[cr.GetPasswordKey()]
+			Expect(username).NotTo(BeEmpty())
+			Expect(password).NotTo(BeEmpty())
+			Expect(secret.Annotations).To(HaveKeyWithValue(SecretChecksumAnnotation, helpers.GetCredentialsChecksum(username, password)))
+		})
+
+		Context("and the Secret is deleted with the instance", func() {
+			BeforeEach(func() {
+				cr.Spec.PVCRetentionPolicy = v1beta1.DeletionPolicyDelete
+			})
+
+			It("owns the generated Secret", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metav1.IsControlledBy(getSecret(), cr)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("when the Secret is user-managed", func() {
+		BeforeEach(func() {
+			existing = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "grafana-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"user": []byte("jane"), "pass": []byte("s3cr3t")},
+			}
+		})
+
+		It("refuses a Secret without the configured keys", func() {
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("with the configured keys", func() {
+			BeforeEach(func() {
+				cr.Spec.PVCRetentionPolicy = v1beta1.DeletionPolicyDelete
+				cr.Spec.UsernameKey, cr.Spec.PasswordKey = "user", "pass"
+			})
+
+			It("adopts the Secret as-is", func() {
+				Expect(err).NotTo(HaveOccurred())
+				secret := getSecret()
+				Expect(IsOperatorManagedSecret(secret)).To(BeFalse())
+				Expect(metav1.IsControlledBy(secret, cr)).To(BeFalse())
+				Expect(secret.ResourceVersion).To(Equal(before.ResourceVersion))
+			})
+		})
+	})
+
+	Context("when the Secret was generated by the operator", func() {
+		Context("and its credentials changed", func() {
+			BeforeEach(func() {
+				existing = operatorSecret("admin", "changed")
+			})
+
+			It("updates the stale checksum", func() {
+				Expect(err).NotTo(HaveOccurred())
+				secret := getSecret()
+				Expect(IsOperatorManagedSecret(secret)).To(BeTrue())
+				Expect(metav1.IsControlledBy(secret, cr)).To(BeFalse())
+				Expect(secret.Annotations).To(HaveKeyWithValue(SecretChecksumAnnotation, helpers.GetCredentialsChecksum("admin", "changed")))
+			})
+		})
+
+		Context("and the Secret is deleted with the instance", func() {
+			BeforeEach(func() {
+				existing = operatorSecret("admin", "admin")
+				cr.Spec.PVCRetentionPolicy = v1beta1.DeletionPolicyDelete
+			})
+
+			It("adopts the Secret", func() {
+				Expect(err).NotTo(HaveOccurred())
+				secret := getSecret()
+				Expect(IsOperatorManagedSecret(secret)).To(BeTrue())
+				Expect(metav1.IsControlledBy(secret, cr)).To(BeTrue())
+				Expect(secret.Annotations).To(HaveKeyWithValue(SecretChecksumAnnotation, helpers.GetCredentialsChecksum("admin", "admin")))
+			})
+		})
+	})
+})
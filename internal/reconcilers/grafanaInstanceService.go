@@ -2,9 +2,10 @@ package reconcilers
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/go-logr/logr"
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/internal/helpers"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -24,7 +25,7 @@ func NewServiceReconciler(client client.Client) *ServiceReconciler {
 	}
 }
 
-func (r *ServiceReconciler) Reconcile(ctx context.Context, cr *grafanav1alpha1.GrafanaInstance, log logr.Logger) error {
+func (r *ServiceReconciler) Reconcile(ctx context.Context, cr *grafanav1beta1.GrafanaInstance, log logr.Logger) error {
 	log.Info("Reconciling Service")
 
 	// Define a new Service object
@@ -51,17 +52,30 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, cr *grafanav1alpha1.G
 	found := &corev1.Service{}
 	err := r.Client.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: cr.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
+		if err := setOwner(r.Client, cr, service); err != nil {
+			return err
+		}
 		// Create the Service since it doesn't exist
 		log.Info("Creating a new Service")
 		err = r.Client.Create(ctx, service)
 		if err != nil {
 			return err
 		}
+		return nil
 	} else if err != nil {
 		return err
-	} else {
-		log.Info("Skip reconcile: Service already exists")
 	}
 
-	return nil
+	// Repair the ports and selector edited out of band, the other fields are defaulted by the API server
+	if reflect.DeepEqual(found.Spec.Ports, service.Spec.Ports) && reflect.DeepEqual(found.Spec.Selector, service.Spec.Selector) && metav1.IsControlledBy(found, cr) {
+		log.Info("Skip reconcile: Service already exists")
+		return nil
+	}
+	found.Spec.Ports = service.Spec.Ports
+	found.Spec.Selector = service.Spec.Selector
+	if err := setOwner(r.Client, cr, found); err != nil {
+		return err
+	}
+	log.Info("Updating Service")
+	return r.Client.Update(ctx, found)
 }
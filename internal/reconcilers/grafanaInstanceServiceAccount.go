@@ -0,0 +1,108 @@
+package reconcilers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	OperatorServiceAccountName = "grafana-operator"
+
+	ServiceAccountIDAnnotation    = "grafana.minicali.com/service-account-id"
+	TokenIDAnnotation             = "grafana.minicali.com/token-id"
+	TokenCreationTimeAnnotation   = "grafana.minicali.com/token-creation-time"
+	serviceAccountTokenTTLFactor  = 2
+	serviceAccountTokenNamePrefix = "grafana-operator"
+)
+
+type ServiceAccountReconciler struct {
+	Client  client.Client
+	Clients *grafana.ClientRegistry
+}
+
+func NewServiceAccountReconciler(client client.Client, clients *grafana.ClientRegistry) *ServiceAccountReconciler {
+	return &ServiceAccountReconciler{
+		Client:  client,
+		Clients: clients,
+	}
+}
+
+// Reconcile bootstraps the operator service account with the admin credentials, stores its token
+// in an operator-owned Secret and replaces the token once the rotation interval elapsed.
+// The rotation itself authenticates with the current token.
+func (r *ServiceAccountReconciler) Reconcile(ctx context.Context, cr *v1beta1.GrafanaInstance, log logr.Logger) error {
+	log = log.WithValues("Resource", "ServiceAccount")
+	log.Info("Reconciling ServiceAccount")
+
+	grafanaURL, err := getGrafanaURL(ctx, r.Client, cr)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana URL")
+		return err
+	}
+
+	auths, err := grafana.GetInstanceAuthenticators(ctx, r.Client, cr)
+	if err != nil {
+		log.Error(err, "Failed to get Grafana authenticators")
+		return err
+	}
+
+	transport := r.Clients.TransportOptions(cr)
+
+	token := &ServiceAccountToken{
+		Client:           r.Client,
+		Secret:           client.ObjectKey{Name: helpers.GetOperatorTokenSecretName(cr.Name), Namespace: cr.Namespace},
+		Labels:           helpers.GetGrafanaLabels(cr.Name, "operator-token"),
+		Owner:            cr,
+		Role:             cr.GetOperatorServiceAccountRole(),
+		RotationInterval: cr.GetTokenRotationInterval(),
+		NewTokenClient: func(secret *corev1.Secret) (*grafana.GrafanaClient, error) {
+			return grafana.CreateGrafanaClientFromTokenSecret(ctx, secret, grafanaURL, transport, auths...)
+		},
+		NewAdminClient: func() (*grafana.GrafanaClient, error) {
+			adminSecret := &corev1.Secret{}
+			err := r.Client.Get(ctx, client.ObjectKey{Name: cr.Spec.CredentialsSecretName, Namespace: cr.Namespace}, adminSecret)
+			if err != nil {
+				return nil, err
+			}
+			return grafana.CreateGrafanaClientFromSecret(ctx, cr, adminSecret, grafanaURL, transport, auths...)
+		},
+		// The service account is the first thing the operator creates, Grafana lost everything else as well
+		OnServiceAccountGone: func(int64) {
+			cr.Status.Reprovision = &v1beta1.ReprovisionStatus{StartedAt: metav1.NewTime(time.Now())}
+		},
+	}
+
+	secret, err := token.Reconcile(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	r.setStatus(cr, secret)
+	return nil
+}
+
+func (r *ServiceAccountReconciler) setStatus(cr *v1beta1.GrafanaInstance, secret *corev1.Secret) {
+	cr.Status.OperatorServiceAccount.ID, _ = strconv.ParseInt(secret.Annotations[ServiceAccountIDAnnotation], 10, 64)
+	cr.Status.OperatorServiceAccount.TokenSecretName = secret.Name
+	if created, ok := GetTokenCreationTime(secret); ok {
+		createdAt := metav1.NewTime(created)
+		cr.Status.OperatorServiceAccount.LastTokenRotationTime = &createdAt
+	}
+}
+
+// NextServiceAccountTokenRotation returns the time of the next scheduled token rotation, once a token exists.
+func NextServiceAccountTokenRotation(cr *v1beta1.GrafanaInstance) (time.Time, bool) {
+	if cr.Status.OperatorServiceAccount.LastTokenRotationTime == nil {
+		return time.Time{}, false
+	}
+	return cr.Status.OperatorServiceAccount.LastTokenRotationTime.Add(cr.GetTokenRotationInterval()), true
+}
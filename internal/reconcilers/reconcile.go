@@ -2,11 +2,71 @@ package reconcilers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/go-logr/logr"
-	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// ErrGrafanaNotReady is returned by stages talking to the Grafana API while it is not reachable yet
+var ErrGrafanaNotReady = errors.New("grafana is not ready")
+
 type StageReconciler interface {
-	Reconcile(ctx context.Context, cr *grafanav1alpha1.GrafanaInstance, log logr.Logger) error
+	Reconcile(ctx context.Context, cr *grafanav1beta1.GrafanaInstance, log logr.Logger) error
+}
+
+// getGrafanaURL returns the in-cluster URL of the Grafana service
+func getGrafanaURL(ctx context.Context, c client.Client, cr *grafanav1beta1.GrafanaInstance) (string, error) {
+	service := &corev1.Service{}
+	err := c.Get(ctx, client.ObjectKey{Name: helpers.GetPrefixedName(cr.Name, "service"), Namespace: cr.Namespace}, service)
+	if err != nil {
+		return "", err
+	}
+	return helpers.GetServiceURL(service), nil
+}
+
+// checkGrafanaReady returns ErrGrafanaNotReady if the Grafana API does not report healthy
+func checkGrafanaReady(ctx context.Context, grafanaClient *grafana.GrafanaClient) error {
+	if _, err := grafanaClient.IsGrafanaHealthy(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrGrafanaNotReady, err)
+	}
+	return nil
+}
+
+// setOwner makes the GrafanaInstance the controller of the object, so it is garbage collected with the instance
+func setOwner(c client.Client, cr *grafanav1beta1.GrafanaInstance, obj client.Object) error {
+	return controllerutil.SetControllerReference(cr, obj, c.Scheme())
+}
+
+// ensureOwner adopts an existing object created before the GrafanaInstance owned its children
+func ensureOwner(ctx context.Context, c client.Client, cr *grafanav1beta1.GrafanaInstance, obj client.Object) error {
+	if metav1.IsControlledBy(obj, cr) {
+		return nil
+	}
+	if err := setOwner(c, cr, obj); err != nil {
+		return err
+	}
+	return c.Update(ctx, obj)
+}
+
+// releaseOwner removes the owner reference of the GrafanaInstance, so the object outlives the instance
+func releaseOwner(ctx context.Context, c client.Client, cr *grafanav1beta1.GrafanaInstance, obj client.Object) error {
+	if !metav1.IsControlledBy(obj, cr) {
+		return nil
+	}
+	ownerRefs := make([]metav1.OwnerReference, 0, len(obj.GetOwnerReferences()))
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		if ownerRef.UID != cr.UID {
+			ownerRefs = append(ownerRefs, ownerRef)
+		}
+	}
+	obj.SetOwnerReferences(ownerRefs)
+	return c.Update(ctx, obj)
 }
@@ -0,0 +1,215 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ServiceAccountToken keeps the token of an operator service account in a Secret.
+// The service account is bootstrapped with admin credentials, the token of an admin is then rotated with itself.
+type ServiceAccountToken struct {
+	Client client.Client
+
+	Secret client.ObjectKey
+	Labels map[string]string
+	// Owner is set as the controller of the Secret when not nil
+	Owner client.Object
+
+	Role             string
+	RotationInterval time.Duration
+
+	// NewTokenClient creates a client authenticated with the token stored in the Secret
+	NewTokenClient func(secret *corev1.Secret) (*grafana.GrafanaClient, error)
+	// NewAdminClient creates a client allowed to create the service account
+	NewAdminClient func() (*grafana.GrafanaClient, error)
+	// OnServiceAccountGone is called when bootstrapping again because the service account of the
+	// previous token does not exist anymore, which means Grafana lost its data
+	OnServiceAccountGone func(serviceAccountID int64)
+}
+
+// Reconcile ensures the Secret holds a valid token, replacing it once the rotation interval elapsed.
+// It returns the up to date Secret.
+func (t *ServiceAccountToken) Reconcile(ctx context.Context, log logr.Logger) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := t.Client.Get(ctx, t.Secret, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get token Secret")
+		return nil, err
+	}
+	exists := err == nil
+
+	// Rotate the token with itself while it is still valid
+	if exists {
+		tokenClient, err := t.NewTokenClient(secret)
+		if err == nil {
+			if err := checkGrafanaReady(ctx, tokenClient); err != nil {
+				return nil, err
+			}
+
+			valid, err := tokenClient.IsTokenValid(ctx)
+			if err != nil {
+				log.Error(err, "Failed to check service account token")
+				return nil, err
+			}
+			if valid {
+				if !isTokenRotationDue(secret, t.RotationInterval, time.Now()) {
+					log.Info("Skip reconcile: service account token is valid")
+					return secret, t.adopt(ctx, secret)
+				}
+				log.Info("Rotating service account token")
+				serviceAccountID, _ := strconv.ParseInt(secret.Annotations[ServiceAccountIDAnnotation], 10, 64)
+				rotationClient, err := t.newRotationClient(tokenClient)
+				if err != nil {
+					log.Error(err, "Failed to create Grafana admin client")
+					return nil, err
+				}
+				return secret, t.rotateToken(ctx, log, rotationClient, secret, serviceAccountID)
+			}
+		}
+		log.Info("Service account token is not valid anymore, bootstrapping again")
+	}
+
+	// Bootstrap with the admin credentials
+	adminClient, err := t.NewAdminClient()
+	if err != nil {
+		log.Error(err, "Failed to create Grafana admin client")
+		return nil, err
+	}
+	if err := checkGrafanaReady(ctx, adminClient); err != nil {
+		return nil, err
+	}
+
+	if previousID, _ := strconv.ParseInt(secret.Annotations[ServiceAccountIDAnnotation], 10, 64); exists && previousID != 0 && t.OnServiceAccountGone != nil {
+		found, err := adminClient.ServiceAccountExists(ctx, previousID)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			log.Info("Operator service account is gone", "ID", previousID)
+			t.OnServiceAccountGone(previousID)
+		}
+	}
+
+	log.Info("Bootstrapping operator service account")
+	serviceAccountID, err := adminClient.EnsureServiceAccount(ctx, log, OperatorServiceAccountName, t.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      t.Secret.Name,
+				Namespace: t.Secret.Namespace,
+				Labels:    t.Labels,
+				Annotations: map[string]string{
+					SecretGeneratedByAnnotation: OperatorName,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	}
+
+	return secret, t.rotateToken(ctx, log, adminClient, secret, serviceAccountID)
+}
+
+// newRotationClient returns the client rotating the token. Only admins can manage service accounts,
+// the tokens of the other roles are rotated with the admin credentials.
+func (t *ServiceAccountToken) newRotationClient(tokenClient *grafana.GrafanaClient) (*grafana.GrafanaClient, error) {
+	if t.Role == grafana.AdminRole {
+		return tokenClient, nil
+	}
+	return t.NewAdminClient()
+}
+
+// adopt sets the owner of a Secret created before it was owned
+func (t *ServiceAccountToken) adopt(ctx context.Context, secret *corev1.Secret) error {
+	if t.Owner == nil || metav1.IsControlledBy(secret, t.Owner) {
+		return nil
+	}
+	if err := controllerutil.SetControllerReference(t.Owner, secret, t.Client.Scheme()); err != nil {
+		return err
+	}
+	return t.Client.Update(ctx, secret)
+}
+
+// rotateToken creates a new token, stores it in the Secret and revokes the previous one
+func (t *ServiceAccountToken) rotateToken(ctx context.Context, log logr.Logger, grafanaClient *grafana.GrafanaClient, secret *corev1.Secret, serviceAccountID int64) error {
+	now := time.Now()
+
+	// Unrotated tokens eventually expire on their own
+	ttl := t.RotationInterval * serviceAccountTokenTTLFactor
+	tokenName := fmt.Sprintf("%s-%d", serviceAccountTokenNamePrefix, now.Unix())
+	tokenID, token, err := grafanaClient.CreateServiceAccountToken(ctx, log, serviceAccountID, tokenName, ttl)
+	if err != nil {
+		return err
+	}
+
+	previousServiceAccountID, _ := strconv.ParseInt(secret.Annotations[ServiceAccountIDAnnotation], 10, 64)
+	previousTokenID, _ := strconv.ParseInt(secret.Annotations[TokenIDAnnotation], 10, 64)
+
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[ServiceAccountIDAnnotation] = strconv.FormatInt(serviceAccountID, 10)
+	secret.Annotations[TokenIDAnnotation] = strconv.FormatInt(tokenID, 10)
+	secret.Annotations[TokenCreationTimeAnnotation] = now.UTC().Format(time.RFC3339)
+	secret.Data = map[string][]byte{
+		helpers.ServiceAccountTokenKey: []byte(token),
+	}
+
+	if t.Owner != nil {
+		if err := controllerutil.SetControllerReference(t.Owner, secret, t.Client.Scheme()); err != nil {
+			_ = grafanaClient.DeleteServiceAccountToken(ctx, log, serviceAccountID, tokenID)
+			return err
+		}
+	}
+
+	if secret.ResourceVersion == "" {
+		log.Info("Creating a new token Secret")
+		err = t.Client.Create(ctx, secret)
+	} else {
+		log.Info("Updating token Secret")
+		err = t.Client.Update(ctx, secret)
+	}
+	if err != nil {
+		log.Error(err, "Failed to store service account token")
+		// Do not leave an unused token behind
+		_ = grafanaClient.DeleteServiceAccountToken(ctx, log, serviceAccountID, tokenID)
+		return err
+	}
+
+	// Revoke the previous token, it expires on its own if this fails
+	if previousTokenID != 0 && previousServiceAccountID == serviceAccountID {
+		if err := grafanaClient.DeleteServiceAccountToken(ctx, log, serviceAccountID, previousTokenID); err != nil {
+			log.Error(err, "Failed to revoke previous service account token", "tokenID", previousTokenID)
+		}
+	}
+
+	return nil
+}
+
+// GetTokenCreationTime returns the creation time of the token stored in the Secret
+func GetTokenCreationTime(secret *corev1.Secret) (time.Time, bool) {
+	created, err := time.Parse(time.RFC3339, secret.Annotations[TokenCreationTimeAnnotation])
+	return created, err == nil
+}
+
+func isTokenRotationDue(secret *corev1.Secret, interval time.Duration, now time.Time) bool {
+	created, ok := GetTokenCreationTime(secret)
+	if !ok {
+		return true
+	}
+	return !now.Before(created.Add(interval))
+}
@@ -0,0 +1,134 @@
+package reconcilers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minicali/grafana-operator/internal/grafana"
+	"github.com/minicali/grafana-operator/internal/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeServiceAccounts is a Grafana holding the tokens of a single service account
+type fakeServiceAccounts struct {
+	mu sync.Mutex
+	// role of the service account
+	role string
+	// tokens maps the valid keys to their IDs
+	tokens  map[string]int64
+	nextID  int64
+	creator string
+}
+
+func (f *fakeServiceAccounts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/api/health" {
+		_, _ = w.Write([]byte(`{"database": "ok", "version": "9.5.0"}`))
+		return
+	}
+
+	caller, role := "", ""
+	if username, password, ok := r.BasicAuth(); ok && username == "admin" && password == "admin" {
+		caller, role = "admin", grafana.AdminRole
+	} else if key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); f.tokens[key] != 0 {
+		caller, role = key, f.role
+	}
+	if caller == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "invalid API key"}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/search":
+		_, _ = w.Write([]byte(`[]`))
+	case role != grafana.AdminRole:
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "access denied"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/serviceaccounts/search":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"serviceAccounts": []map[string]interface{}{{"id": 5, "name": OperatorServiceAccountName, "role": f.role}},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/serviceaccounts/5/tokens":
+		f.nextID++
+		key := fmt.Sprintf("token-%d", f.nextID)
+		f.tokens[key] = f.nextID
+		f.creator = caller
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": f.nextID, "name": "token", "key": key})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/serviceaccounts/5/tokens/"):
+		for key, id := range f.tokens {
+			if r.URL.Path == fmt.Sprintf("/api/serviceaccounts/5/tokens/%d", id) {
+				delete(f.tokens, key)
+			}
+		}
+		_, _ = w.Write([]byte(`{"message": "API key deleted"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = DescribeTable("ServiceAccountToken.Reconcile",
+	func(role string, age time.Duration, revoked bool, creator string, newToken string) {
+		fakeGrafana := &fakeServiceAccounts{role: role, tokens: map[string]int64{"token-1": 1}, nextID: 1}
+		if revoked {
+			delete(fakeGrafana.tokens, "token-1")
+		}
+		grafanaServer := httptest.NewServer(fakeGrafana)
+		DeferCleanup(grafanaServer.Close)
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "grafana-operator-token",
+				Namespace: "default",
+				Annotations: map[string]string{
+					ServiceAccountIDAnnotation:  "5",
+					TokenIDAnnotation:           "1",
+					TokenCreationTimeAnnotation: time.Now().Add(-age).UTC().Format(time.RFC3339),
+				},
+			},
+			Data: map[string][]byte{helpers.ServiceAccountTokenKey: []byte("token-1")},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+		token := &ServiceAccountToken{
+			Client:           c,
+			Secret:           client.ObjectKeyFromObject(secret),
+			Role:             role,
+			RotationInterval: time.Hour,
+			NewTokenClient: func(secret *corev1.Secret) (*grafana.GrafanaClient, error) {
+				return grafana.CreateGrafanaClientFromTokenSecret(context.Background(), secret, grafanaServer.URL, grafana.DefaultTransportOptions())
+			},
+			NewAdminClient: func() (*grafana.GrafanaClient, error) {
+				return grafana.NewClient(grafanaServer.URL, grafana.DefaultTransportOptions(), &grafana.BasicAuthenticator{Username: "admin", Password: "admin"})
+			},
+		}
+
+		_, err := token.Reconcile(context.Background(), logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		Expect(string(secret.Data[helpers.ServiceAccountTokenKey])).To(Equal(newToken))
+		Expect(fakeGrafana.creator).To(Equal(creator))
+		if newToken != "token-1" {
+			Expect(fakeGrafana.tokens).NotTo(HaveKey("token-1"), "the previous token is revoked")
+		}
+	},
+	Entry("valid token kept", "Viewer", time.Minute, false, "", "token-1"),
+	Entry("admin token rotated with itself", grafana.AdminRole, 2*time.Hour, false, "token-1", "token-2"),
+	Entry("viewer token rotated with the admin credentials", "Viewer", 2*time.Hour, false, "admin", "token-2"),
+	Entry("revoked token bootstrapped again", "Viewer", time.Minute, true, "admin", "token-2"),
+)
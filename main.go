@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -32,7 +33,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	grafanav1alpha1 "github.com/minicali/grafana-operator/api/v1alpha1"
+	grafanav1beta1 "github.com/minicali/grafana-operator/api/v1beta1"
 	"github.com/minicali/grafana-operator/controllers"
+	"github.com/minicali/grafana-operator/internal/grafana"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +48,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(grafanav1alpha1.AddToScheme(scheme))
+	utilruntime.Must(grafanav1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +56,31 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var grafanaHealthTTL time.Duration
+	var grafanaRateLimit float64
+	var grafanaBurst int
+	var defaultDeletionPolicy string
+	var deletionTimeout time.Duration
+	grafanaTransport := grafana.DefaultTransportOptions()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&grafanaHealthTTL, "grafana-health-ttl", grafana.DefaultHealthTTL,
+		"How long a successful Grafana health check is trusted before checking again.")
+	flag.DurationVar(&grafanaTransport.Timeout, "grafana-timeout", grafanaTransport.Timeout,
+		"The timeout of every attempt of a Grafana API request.")
+	flag.IntVar(&grafanaTransport.MaxRetries, "grafana-max-retries", grafanaTransport.MaxRetries,
+		"The number of retries of a Grafana API request answered with 429, or with 5xx for the idempotent methods.")
+	flag.Float64Var(&grafanaRateLimit, "grafana-rate-limit", 20,
+		"The maximum number of requests per second sent to a Grafana instance. Zero disables rate limiting.")
+	flag.IntVar(&grafanaBurst, "grafana-burst", 40,
+		"The maximum burst of requests sent to a Grafana instance.")
+	flag.StringVar(&defaultDeletionPolicy, "default-deletion-policy", string(grafanav1beta1.DeletionPolicyDelete),
+		"What happens in Grafana to the dashboards deleted without a deletion policy, either Delete or Retain.")
+	flag.DurationVar(&deletionTimeout, "deletion-timeout", controllers.DefaultDeletionTimeout,
+		"How long the deletion of a dashboard, organization, team or user retries while Grafana is unreachable, before leaving it in Grafana.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +89,19 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// The rate limiter lets no request through with a burst of zero
+	if grafanaRateLimit > 0 && grafanaBurst < 1 {
+		setupLog.Error(nil, "invalid Grafana burst, expected at least 1", "burst", grafanaBurst)
+		os.Exit(1)
+	}
+
+	switch grafanav1beta1.DeletionPolicy(defaultDeletionPolicy) {
+	case grafanav1beta1.DeletionPolicyDelete, grafanav1beta1.DeletionPolicyRetain:
+	default:
+		setupLog.Error(nil, "invalid default deletion policy, expected Delete or Retain", "policy", defaultDeletionPolicy)
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -89,20 +126,99 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Grafana clients are shared by all controllers
+	grafanaClients := grafana.NewClientRegistry(grafanaHealthTTL, grafanaTransport, grafanaRateLimit, grafanaBurst)
+
 	if err = (&controllers.GrafanaInstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		Clients: grafanaClients,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GrafanaInstance")
 		os.Exit(1)
 	}
 	if err = (&controllers.GrafanaDashboardReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		Clients:               grafanaClients,
+		Recorder:              mgr.GetEventRecorderFor("grafanadashboard-controller"),
+		DefaultDeletionPolicy: grafanav1beta1.DeletionPolicy(defaultDeletionPolicy),
+		DeletionTimeout:       deletionTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GrafanaDashboard")
 		os.Exit(1)
 	}
+	if err = (&controllers.GrafanaOrganizationReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Clients:         grafanaClients,
+		Recorder:        mgr.GetEventRecorderFor("grafanaorganization-controller"),
+		DeletionTimeout: deletionTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GrafanaOrganization")
+		os.Exit(1)
+	}
+	if err = (&controllers.GrafanaTeamReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Clients:         grafanaClients,
+		Recorder:        mgr.GetEventRecorderFor("grafanateam-controller"),
+		DeletionTimeout: deletionTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GrafanaTeam")
+		os.Exit(1)
+	}
+	if err = (&controllers.GrafanaUserReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Clients:         grafanaClients,
+		Recorder:        mgr.GetEventRecorderFor("grafanauser-controller"),
+		DeletionTimeout: deletionTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GrafanaUser")
+		os.Exit(1)
+	}
+	if err = (&controllers.GrafanaDashboardImportReconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		Clients: grafanaClients,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GrafanaDashboardImport")
+		os.Exit(1)
+	}
+	if err = (&controllers.GrafanaLibraryPanelReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Clients:  grafanaClients,
+		Recorder: mgr.GetEventRecorderFor("grafanalibrarypanel-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GrafanaLibraryPanel")
+		os.Exit(1)
+	}
+	// The webhooks, conversion included, need a serving certificate. Disable them with ENABLE_WEBHOOKS=false
+	// to run the manager locally, v1alpha1 objects are then not served.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&grafanav1beta1.GrafanaInstance{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GrafanaInstance")
+			os.Exit(1)
+		}
+		if err = (&grafanav1beta1.GrafanaDashboard{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GrafanaDashboard")
+			os.Exit(1)
+		}
+		if err = (&grafanav1beta1.GrafanaOrganization{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GrafanaOrganization")
+			os.Exit(1)
+		}
+		if err = (&grafanav1beta1.GrafanaTeam{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GrafanaTeam")
+			os.Exit(1)
+		}
+		if err = (&grafanav1beta1.GrafanaUser{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GrafanaUser")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {